@@ -0,0 +1,275 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 备份归档（.tar.gz）依次包含：
+//   manifest.json  备份清单（格式版本、数据库结构版本、数据库文件校验值、媒体文件列表）
+//   blog.db        通过 VACUUM INTO 生成的数据库快照，服务运行期间也能得到一致的副本
+//   media/<key>    数据库引用的存储文件：媒体原文件、衍生图片、头像
+// 存储文件按key保存，与存储后端无关，本地存储的备份也可以恢复到S3
+
+// FormatVersion 备份归档格式版本
+const FormatVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	databaseName = "blog.db"
+	mediaPrefix  = "media/"
+	filePrefix   = "backup-"
+	fileSuffix   = ".tar.gz"
+)
+
+// Manifest 备份清单
+type Manifest struct {
+	FormatVersion  int              `json:"format_version"`
+	SchemaVersion  int              `json:"schema_version"`
+	CreatedAt      time.Time        `json:"created_at"`
+	DatabaseSize   int64            `json:"database_size"`
+	DatabaseSHA256 string           `json:"database_sha256"`
+	Counts         map[string]int64 `json:"counts"`         // 主要数据的记录数，便于核对
+	MediaKeys      []string         `json:"media_keys"`     // 计划备份的存储文件，备份时已不存在的文件不会出现在归档中
+	MediaIncluded  bool             `json:"media_included"` // 是否包含存储文件
+	MissingMedia   int              `json:"-"`              // 备份时已不存在的文件数
+	BackupFileSize int64            `json:"-"`              // 归档文件大小
+	BackupFileName string           `json:"-"`              // 归档文件路径
+}
+
+// Create 将数据库快照和存储文件写入w
+func Create(w io.Writer, store storage.Storage, includeMedia bool) (*Manifest, error) {
+	// VACUUM INTO 要求目标文件不存在
+	dir, err := os.MkdirTemp("", "blog-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, databaseName)
+	if err := config.DB.Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		return nil, fmt.Errorf("生成数据库快照失败: %w", err)
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		SchemaVersion: models.SchemaVersion,
+		CreatedAt:     time.Now(),
+		Counts:        map[string]int64{},
+		MediaKeys:     []string{},
+		MediaIncluded: includeMedia,
+	}
+	if manifest.DatabaseSize, manifest.DatabaseSHA256, err = fileChecksum(snapshot); err != nil {
+		return nil, err
+	}
+	for name, model := range map[string]interface{}{
+		"users": &models.User{}, "posts": &models.Post{}, "comments": &models.Comment{}, "media": &models.Media{}, "tags": &models.Tag{},
+	} {
+		var count int64
+		if err := config.DB.Model(model).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		manifest.Counts[name] = count
+	}
+	if includeMedia {
+		if manifest.MediaKeys, err = storageKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, manifestName, int64(len(data)), strings.NewReader(string(data))); err != nil {
+		return nil, err
+	}
+	db, err := os.Open(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	err = writeEntry(tw, databaseName, manifest.DatabaseSize, db)
+	db.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range manifest.MediaKeys {
+		if err := writeStorageFile(tw, store, key); err != nil {
+			if errors.Is(err, storage.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+				manifest.MissingMedia++
+				utils.Log.Warnf("[Backup] 存储文件不存在，已跳过: %s", key)
+				continue
+			}
+			return nil, fmt.Errorf("备份存储文件失败: %s: %w", key, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+// CreateFile 在目录中生成 backup-时间.tar.gz，写入完成后才出现在目录中
+func CreateFile(dir string, store storage.Storage, includeMedia bool) (*Manifest, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(dir, ".backup-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := Create(tmp, store, includeMedia)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	name := filepath.Join(dir, filePrefix+manifest.CreatedAt.Format("20060102-150405")+fileSuffix)
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if info, err := os.Stat(name); err == nil {
+		manifest.BackupFileSize = info.Size()
+	}
+	manifest.BackupFileName = name
+	return manifest, nil
+}
+
+// List 列出目录中的备份文件，按时间从新到旧排列
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), filePrefix) && strings.HasSuffix(e.Name(), fileSuffix) {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	// 文件名中的时间格式保证字典序即时间顺序
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// Prune 只保留最新的keep个备份，返回删除的文件
+func Prune(dir string, keep int) ([]string, error) {
+	files, err := List(dir)
+	if err != nil || len(files) <= keep {
+		return nil, err
+	}
+	var removed []string
+	for _, name := range files[keep:] {
+		if err := os.Remove(name); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// storageKeys 数据库引用的全部存储文件：媒体原文件及衍生图片、各尺寸头像
+func storageKeys() ([]string, error) {
+	seen := map[string]bool{}
+	var keys []string
+	add := func(key string) {
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var media []models.Media
+	if err := config.DB.Select("id", "storage_key", "variants").Find(&media).Error; err != nil {
+		return nil, err
+	}
+	for i := range media {
+		add(media[i].Key)
+		for _, v := range media[i].VariantList() {
+			add(v.Key)
+		}
+	}
+
+	var avatars []string
+	if err := config.DB.Model(&models.User{}).Where("avatar <> ''").Pluck("avatar", &avatars).Error; err != nil {
+		return nil, err
+	}
+	for _, prefix := range avatars {
+		for _, size := range models.AvatarSizes {
+			add(models.AvatarKey(prefix, size))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// writeStorageFile 读取存储文件写入归档
+// tar 条目头需要文件大小，先读入内存（单个文件不超过媒体大小上限）
+func writeStorageFile(tw *tar.Writer, store storage.Storage, key string) error {
+	rc, err := store.Open(key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	return writeEntry(tw, mediaPrefix+key, int64(len(data)), strings.NewReader(string(data)))
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: time.Now(),
+		Format:  tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// fileChecksum 计算文件大小和SHA-256
+func fileChecksum(name string) (int64, string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,283 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 可移植的数据导出，不依赖数据库类型：
+//   site.json       用户（不含密码等凭据）、标签、文章、评论、媒体的元数据
+//   posts/<slug>.md 带YAML front matter的文章原文，可直接用 import -format markdown 导入
+//   media/<key>     存储文件，文章中的图片地址改写为 /media/<key>，导入时按本地文件读取
+
+// DataFormatVersion 数据导出格式版本
+const DataFormatVersion = 1
+
+type exportData struct {
+	FormatVersion int             `json:"format_version"`
+	SchemaVersion int             `json:"schema_version"`
+	ExportedAt    time.Time       `json:"exported_at"`
+	Users         []exportUser    `json:"users"`
+	Tags          []exportTag     `json:"tags"`
+	Posts         []exportPost    `json:"posts"`
+	Comments      []exportComment `json:"comments"`
+	Media         []exportMedia   `json:"media"`
+}
+
+type exportUser struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email,omitempty"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Bio         string    `json:"bio,omitempty"`
+	Website     string    `json:"website,omitempty"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type exportTag struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type exportPost struct {
+	ID            uint      `json:"id"`
+	Slug          string    `json:"slug"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	Tags          []string  `json:"tags"`
+	ContentFormat string    `json:"content_format"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	File          string    `json:"file"`
+}
+
+type exportComment struct {
+	ID            uint      `json:"id"`
+	Post          string    `json:"post"` // 文章slug
+	Author        string    `json:"author"`
+	Content       string    `json:"content"`
+	ContentFormat string    `json:"content_format"`
+	Status        string    `json:"status"` // 审核状态：approved/pending/rejected/spam，只有 approved 的评论公开显示
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type exportMedia struct {
+	ID          uint     `json:"id"`
+	Key         string   `json:"key"`
+	Filename    string   `json:"filename"`
+	ContentType string   `json:"content_type"`
+	Size        int64    `json:"size"`
+	SHA256      string   `json:"sha256"`
+	Visibility  string   `json:"visibility"`
+	Uploader    string   `json:"uploader"`
+	Post        string   `json:"post,omitempty"` // 关联文章slug
+	Width       int      `json:"width,omitempty"`
+	Height      int      `json:"height,omitempty"`
+	Variants    []string `json:"variants,omitempty"`
+}
+
+// postFrontMatter 文章文件的front matter，字段名与导入器识别的一致
+type postFrontMatter struct {
+	Title   string   `yaml:"title"`
+	Slug    string   `yaml:"slug"`
+	Date    string   `yaml:"date"`
+	Lastmod string   `yaml:"lastmod"`
+	Author  string   `yaml:"author"`
+	Tags    []string `yaml:"tags,omitempty"`
+	Format  string   `yaml:"format"`
+}
+
+// DataStats 数据导出结果
+type DataStats struct {
+	Users, Tags, Posts, Comments, Media int
+	Files                               int // 写出的存储文件数
+	MissingFiles                        int // 已不存在的存储文件数
+}
+
+// ExportData 将站点数据导出到目录out
+func ExportData(out string, store storage.Storage) (*DataStats, error) {
+	if entries, err := os.ReadDir(out); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("目录 %s 不为空", out)
+	}
+	if err := os.MkdirAll(filepath.Join(out, "posts"), 0o755); err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := config.DB.Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var tags []models.Tag
+	if err := config.DB.Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	var posts []models.Post
+	if err := config.DB.Preload("Tags").Order("id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	var comments []models.Comment
+	if err := config.DB.Order("id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	var media []models.Media
+	if err := config.DB.Order("id").Find(&media).Error; err != nil {
+		return nil, err
+	}
+
+	data := exportData{
+		FormatVersion: DataFormatVersion,
+		SchemaVersion: models.SchemaVersion,
+		ExportedAt:    time.Now(),
+		Users:         []exportUser{},
+		Tags:          []exportTag{},
+		Posts:         []exportPost{},
+		Comments:      []exportComment{},
+		Media:         []exportMedia{},
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+		data.Users = append(data.Users, exportUser{
+			ID: u.ID, Username: u.Username, Email: u.Email, DisplayName: u.DisplayName,
+			Bio: u.Bio, Website: u.Website, Role: u.Role, CreatedAt: u.CreatedAt,
+		})
+	}
+	for _, t := range tags {
+		data.Tags = append(data.Tags, exportTag{Name: t.Name, Slug: t.Slug})
+	}
+
+	// 文章中的存储地址 → 导出目录中的相对地址，较长的地址先替换
+	var urls []string
+	localURL := map[string]string{}
+	keys := []string{}
+	for _, m := range media {
+		item := exportMedia{
+			ID: m.ID, Key: m.Key, Filename: m.Filename, ContentType: m.ContentType, Size: m.Size, SHA256: m.SHA256,
+			Visibility: m.Visibility, Uploader: usernames[m.UserID], Width: m.Width, Height: m.Height,
+		}
+		keys = append(keys, m.Key)
+		for _, v := range m.VariantList() {
+			item.Variants = append(item.Variants, v.Key)
+			keys = append(keys, v.Key)
+		}
+		data.Media = append(data.Media, item)
+	}
+	for _, key := range keys {
+		u := store.URL(key)
+		if _, ok := localURL[u]; !ok {
+			localURL[u] = "/media/" + key
+			urls = append(urls, u)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool { return len(urls[i]) > len(urls[j]) })
+
+	postSlugs := make(map[uint]string, len(posts))
+	for _, p := range posts {
+		slug := p.Slug
+		if slug == "" {
+			slug = strconv.FormatUint(uint64(p.ID), 10)
+		}
+		postSlugs[p.ID] = slug
+		var tagNames []string
+		for _, t := range p.Tags {
+			tagNames = append(tagNames, t.Name)
+		}
+		content := p.Content
+		for _, u := range urls {
+			content = strings.ReplaceAll(content, u, localURL[u])
+		}
+
+		file := "posts/" + slug + ".md"
+		head, err := yaml.Marshal(postFrontMatter{
+			Title:   p.Title,
+			Slug:    slug,
+			Date:    p.CreatedAt.Format(time.RFC3339),
+			Lastmod: p.UpdatedAt.Format(time.RFC3339),
+			Author:  usernames[p.UserID],
+			Tags:    tagNames,
+			Format:  p.ContentFormat,
+		})
+		if err != nil {
+			return nil, err
+		}
+		body := "---\n" + string(head) + "---\n\n" + content
+		if !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+		if err := os.WriteFile(filepath.Join(out, filepath.FromSlash(file)), []byte(body), 0o644); err != nil {
+			return nil, err
+		}
+		data.Posts = append(data.Posts, exportPost{
+			ID: p.ID, Slug: slug, Title: p.Title, Author: usernames[p.UserID], Tags: tagNames,
+			ContentFormat: p.ContentFormat, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, File: file,
+		})
+	}
+	for _, c := range comments {
+		data.Comments = append(data.Comments, exportComment{
+			ID: c.ID, Post: postSlugs[c.PostID], Author: usernames[c.UserID],
+			Content: c.Content, ContentFormat: c.ContentFormat, Status: c.Status, CreatedAt: c.CreatedAt,
+		})
+	}
+	for i, m := range media {
+		if m.PostID != nil {
+			data.Media[i].Post = postSlugs[*m.PostID]
+		}
+	}
+
+	stats := &DataStats{Users: len(users), Tags: len(tags), Posts: len(posts), Comments: len(comments), Media: len(media)}
+	for _, key := range keys {
+		if err := exportFile(store, key, filepath.Join(out, "media", filepath.FromSlash(key))); err != nil {
+			if errors.Is(err, storage.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+				stats.MissingFiles++
+				utils.Log.Warnf("[Export] 存储文件不存在，已跳过: %s", key)
+				continue
+			}
+			return nil, fmt.Errorf("导出存储文件失败: %s: %w", key, err)
+		}
+		stats.Files++
+	}
+
+	site, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(out, "site.json"), site, 0o644); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func exportFile(store storage.Storage, key, name string) error {
+	rc, err := store.Open(key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, rc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	Manifest      *Manifest
+	MediaRestored int
+	MediaMissing  int    // 清单中有但归档中没有的存储文件
+	PreviousDB    string // 原数据库文件的保留位置，原数据库没有数据时为空
+}
+
+// Restore 从备份归档恢复数据库和存储文件，须在服务停止时执行
+// 先校验清单、数据库文件的校验值、完整性和结构版本，全部通过后才写入存储文件并替换数据库；
+// 当前数据库已有数据时需要 force，原数据库文件重命名为 <db>.pre-restore-时间 保留
+func Restore(archive string, cfg *config.AppConfig, store storage.Storage, force bool) (*RestoreResult, error) {
+	var count int64
+	if err := config.DB.Model(&models.User{}).Unscoped().Count(&count).Error; err != nil {
+		return nil, err
+	}
+	hasData := count > 0
+	if hasData && !force {
+		return nil, errors.New("当前数据库已有数据，确认覆盖请使用 -force")
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("不是有效的备份文件: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	// 清单必须是第一个文件
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, errors.New("不是有效的备份文件：缺少 " + manifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(tr, 16<<20)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("备份清单解析失败: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("不支持的备份格式版本: %d", manifest.FormatVersion)
+	}
+	if manifest.SchemaVersion > models.SchemaVersion {
+		return nil, fmt.Errorf("备份的数据库版本（%d）高于程序支持的版本（%d），请升级程序后再恢复", manifest.SchemaVersion, models.SchemaVersion)
+	}
+
+	// 数据库快照解压到目标文件所在目录，便于之后原子替换
+	hdr, err = tr.Next()
+	if err != nil || hdr.Name != databaseName {
+		return nil, errors.New("备份文件损坏：缺少 " + databaseName)
+	}
+	dbPath, err := filepath.Abs(cfg.DBFile)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dbPath), ".restore-*.db")
+	if err != nil {
+		return nil, err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	_, err = io.Copy(tmp, tr)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解压数据库文件失败: %w", err)
+	}
+	if err := verifyDatabase(tmpName, &manifest); err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Manifest: &manifest}
+	restored := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取备份文件失败: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !strings.HasPrefix(hdr.Name, mediaPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(hdr.Name, mediaPrefix)
+		if key == "" || path.Clean(key) != key || strings.HasPrefix(key, "../") || path.IsAbs(key) {
+			return nil, fmt.Errorf("备份文件损坏：非法的文件路径 %s", hdr.Name)
+		}
+		contentType := mime.TypeByExtension(path.Ext(key))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		if err := store.Save(key, tr, contentType); err != nil {
+			return nil, fmt.Errorf("恢复存储文件失败: %s: %w", key, err)
+		}
+		restored[key] = true
+		result.MediaRestored++
+	}
+	for _, key := range manifest.MediaKeys {
+		if !restored[key] {
+			result.MediaMissing++
+		}
+	}
+
+	// 关闭当前连接后替换数据库文件，WAL等附属文件随原数据库一起保留
+	if sqlDB, err := config.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	if hasData {
+		result.PreviousDB = dbPath + ".pre-restore-" + time.Now().Format("20060102-150405")
+		for i := 2; fileExists(result.PreviousDB); i++ {
+			result.PreviousDB = fmt.Sprintf("%s.pre-restore-%s-%d", dbPath, time.Now().Format("20060102-150405"), i)
+		}
+		if err := os.Rename(dbPath, result.PreviousDB); err != nil {
+			return nil, fmt.Errorf("保留原数据库失败: %w", err)
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if _, err := os.Stat(dbPath + suffix); err == nil {
+			if result.PreviousDB != "" {
+				os.Rename(dbPath+suffix, result.PreviousDB+suffix)
+			} else {
+				os.Remove(dbPath + suffix)
+			}
+		}
+	}
+	if err := os.Rename(tmpName, dbPath); err != nil {
+		return nil, fmt.Errorf("替换数据库文件失败: %w", err)
+	}
+	utils.Log.Infof("[Backup] 已从 %s 恢复数据库和 %d 个存储文件", archive, result.MediaRestored)
+	return result, nil
+}
+
+// verifyDatabase 校验解压出的数据库：大小和SHA-256与清单一致、完整性检查通过、结构版本与清单一致
+func verifyDatabase(name string, manifest *Manifest) error {
+	size, sum, err := fileChecksum(name)
+	if err != nil {
+		return err
+	}
+	if size != manifest.DatabaseSize || sum != manifest.DatabaseSHA256 {
+		return errors.New("备份文件损坏：数据库文件校验失败")
+	}
+
+	db, err := gorm.Open(sqlite.Open(name), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return fmt.Errorf("打开备份数据库失败: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	var check string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&check).Error; err != nil || check != "ok" {
+		return fmt.Errorf("备份数据库完整性检查失败: %s %v", check, err)
+	}
+	var schema models.SchemaInfo
+	if err := db.Limit(1).Find(&schema, 1).Error; err != nil {
+		return fmt.Errorf("读取备份数据库版本失败: %w", err)
+	}
+	if schema.Version != manifest.SchemaVersion {
+		return fmt.Errorf("备份数据库版本（%d）与清单（%d）不一致", schema.Version, manifest.SchemaVersion)
+	}
+	return nil
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// runPromoteAdmin promote-admin 子命令：将指定用户设为管理员
+// 只有空数据库中首个注册的用户会自动成为管理员，升级前已有用户的站点通过该命令指定管理员
+func runPromoteAdmin(args []string) int {
+	flags := flag.NewFlagSet("promote-admin", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: go-blog-system promote-admin <用户名>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	var user models.User
+	if err := config.DB.Where("username = ?", flags.Arg(0)).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Fprintf(os.Stderr, "用户不存在: %s\n", flags.Arg(0))
+		} else {
+			fmt.Fprintf(os.Stderr, "查询用户失败: %v\n", err)
+		}
+		return 1
+	}
+	if user.Role == models.RoleAdmin {
+		fmt.Printf("%s 已是管理员\n", user.Username)
+		return 0
+	}
+	oldRole := user.Role
+	if err := config.DB.Model(&user).Update("role", models.RoleAdmin).Error; err != nil {
+		fmt.Fprintf(os.Stderr, "设置管理员失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("已将 %s 设为管理员（原角色: %s）\n", user.Username, oldRole)
+	return 0
+}
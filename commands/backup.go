@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"go-blog-system/backup"
+	"go-blog-system/config"
+	"go-blog-system/storage"
+	"os"
+	"path/filepath"
+)
+
+// runBackup backup 子命令：生成数据库和存储文件的备份，服务运行期间也可执行
+func runBackup(cfg *config.AppConfig, store storage.Storage, args []string) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := flags.String("out", "", "备份文件路径，默认在备份目录（"+cfg.BackupDir+"）中按时间命名")
+	noMedia := flags.Bool("no-media", false, "只备份数据库，不包含媒体文件和头像")
+	prune := flags.Bool("prune", false, fmt.Sprintf("备份后只保留备份目录中最新的 %d 个备份", cfg.BackupRetention))
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: go-blog-system backup [参数]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var manifest *backup.Manifest
+	var err error
+	if *out == "" {
+		manifest, err = backup.CreateFile(cfg.BackupDir, store, !*noMedia)
+	} else {
+		manifest, err = createBackupAt(*out, store, !*noMedia)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "备份失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("备份完成: %s（%d 字节），文章 %d，用户 %d，存储文件 %d",
+		manifest.BackupFileName, manifest.BackupFileSize, manifest.Counts["posts"], manifest.Counts["users"],
+		len(manifest.MediaKeys)-manifest.MissingMedia)
+	if manifest.MissingMedia > 0 {
+		fmt.Printf("（%d 个已不存在）", manifest.MissingMedia)
+	}
+	fmt.Println()
+
+	if *prune && *out == "" {
+		removed, err := backup.Prune(cfg.BackupDir, cfg.BackupRetention)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "清理过期备份失败: %v\n", err)
+			return 1
+		}
+		for _, name := range removed {
+			fmt.Println("已删除:", name)
+		}
+	}
+	return 0
+}
+
+// createBackupAt 写入指定路径，完成后才替换目标文件
+func createBackupAt(name string, store storage.Storage, includeMedia bool) (*backup.Manifest, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".backup-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := backup.Create(tmp, store, includeMedia)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if info, err := os.Stat(name); err == nil {
+		manifest.BackupFileSize = info.Size()
+	}
+	manifest.BackupFileName = name
+	return manifest, nil
+}
+
+// runRestore restore 子命令：从备份恢复数据库和存储文件，须先停止服务
+func runRestore(cfg *config.AppConfig, store storage.Storage, args []string) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	force := flags.Bool("force", false, "当前数据库已有数据时仍然恢复（原数据库文件会被保留）")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: go-blog-system restore [参数] <备份文件>\n恢复前请先停止服务")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	result, err := backup.Restore(flags.Arg(0), cfg, store, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "恢复失败: %v\n", err)
+		return 1
+	}
+	m := result.Manifest
+	fmt.Printf("恢复完成: 备份时间 %s，数据库版本 %d，文章 %d，用户 %d，存储文件 %d\n",
+		m.CreatedAt.Format("2006-01-02 15:04:05"), m.SchemaVersion, m.Counts["posts"], m.Counts["users"], result.MediaRestored)
+	if result.MediaMissing > 0 {
+		fmt.Printf("注意: %d 个存储文件在备份时已不存在\n", result.MediaMissing)
+	}
+	if !m.MediaIncluded {
+		fmt.Println("注意: 该备份不包含媒体文件和头像")
+	}
+	if result.PreviousDB != "" {
+		fmt.Println("原数据库已保留为:", result.PreviousDB)
+	}
+	return 0
+}
+
+// runExportData export-data 子命令：导出不依赖数据库类型的JSON/Markdown数据
+func runExportData(cfg *config.AppConfig, store storage.Storage, args []string) int {
+	flags := flag.NewFlagSet("export-data", flag.ContinueOnError)
+	out := flags.String("out", "data-export", "输出目录（须为空或不存在）")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: go-blog-system export-data [参数]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	stats, err := backup.ExportData(*out, store)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("导出完成: %s，用户 %d，文章 %d，评论 %d，标签 %d，媒体 %d，存储文件 %d",
+		*out, stats.Users, stats.Posts, stats.Comments, stats.Tags, stats.Media, stats.Files)
+	if stats.MissingFiles > 0 {
+		fmt.Printf("（%d 个已不存在）", stats.MissingFiles)
+	}
+	fmt.Println()
+	return 0
+}
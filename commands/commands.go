@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/storage"
+	"os"
+)
+
+// 命令行子命令：go-blog-system <命令> [参数]，不带命令时启动Web服务
+
+// usage 输出子命令说明
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法: go-blog-system [命令] [参数]
+
+不带命令时启动Web服务。可用命令：
+  export   将站点导出为静态文件（go-blog-system export -h 查看参数）
+  import   导入 WordPress WXR 文件或 Markdown/Hugo/Jekyll 目录（go-blog-system import -h 查看参数）
+  backup   备份数据库和媒体文件（go-blog-system backup -h 查看参数）
+  restore  从备份恢复，须先停止服务（go-blog-system restore -h 查看参数）
+  export-data
+           导出JSON/Markdown格式的站点数据，不依赖数据库类型（go-blog-system export-data -h 查看参数）
+  promote-admin
+           将指定用户设为管理员（go-blog-system promote-admin <用户名>）`)
+}
+
+// Run 执行子命令，返回进程退出码
+func Run(cfg *config.AppConfig, store storage.Storage, args []string) int {
+	switch args[0] {
+	case "export":
+		return runExport(cfg, store, args[1:])
+	case "import":
+		return runImport(cfg, store, args[1:])
+	case "backup":
+		return runBackup(cfg, store, args[1:])
+	case "restore":
+		return runRestore(cfg, store, args[1:])
+	case "export-data":
+		return runExportData(cfg, store, args[1:])
+	case "promote-admin":
+		return runPromoteAdmin(args[1:])
+	case "help", "-h", "--help":
+		usage()
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", args[0])
+		usage()
+		return 2
+	}
+}
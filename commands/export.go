@@ -0,0 +1,537 @@
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/controllers"
+	"go-blog-system/models"
+	"go-blog-system/render"
+	"go-blog-system/storage"
+	"go-blog-system/theme"
+	"go-blog-system/utils"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 静态导出：通过与Web服务相同的路由在进程内渲染页面，写入输出目录
+// 输出目录中的清单文件记录每篇文章的状态和每个文件的内容摘要；
+// 增量导出时只重新渲染变化文章所影响的文章页、作者页、标签页以及首页、归档、订阅源、sitemap，
+// 内容未变化的文件不会重写，已不存在的页面会被删除
+
+const (
+	exportManifestFile = ".export-manifest.json"
+	exportVersion      = 1
+	// 文件分组：全站共用页面、媒体文件，其余为 post:<id>、author:<用户名>、tag:<slug>
+	groupGlobal = "global"
+	groupMedia  = "media"
+)
+
+type exportManifest struct {
+	Version  int                        `json:"version"`
+	Settings string                     `json:"settings"`
+	Posts    map[string]exportPostState `json:"posts"`
+	Files    map[string]exportFileState `json:"files"`
+}
+
+type exportPostState struct {
+	Fingerprint string   `json:"fingerprint"`
+	Author      string   `json:"author"`
+	Tags        []string `json:"tags"`
+}
+
+type exportFileState struct {
+	Group string `json:"group"`
+	Hash  string `json:"hash"`
+}
+
+type exporter struct {
+	cfg    *config.AppConfig
+	store  storage.Storage
+	theme  *theme.Theme
+	router *gin.Engine
+	out    string
+
+	old      exportManifest
+	manifest exportManifest
+	affected map[string]bool
+
+	written, unchanged, removed int
+}
+
+// runExport export 子命令
+func runExport(cfg *config.AppConfig, store storage.Storage, args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("out", "public", "输出目录")
+	full := flags.Bool("full", false, "忽略上次导出记录，重新生成全部文件")
+	baseURL := flags.String("base-url", "", "静态站点的访问地址，默认使用 SITE_URL")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	exportCfg := *cfg
+	exportCfg.SiteStatic = true
+	exportCfg.SiteEnabled = true // 静态导出总是生成站点页面，sitemap 也需收录这些页面
+	if *baseURL != "" {
+		exportCfg.SiteURL = strings.TrimRight(*baseURL, "/")
+	}
+	siteTheme, err := controllers.LoadTheme(&exportCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "主题加载失败: %v\n", err)
+		return 1
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	controllers.RegisterFeedRoutes(router, &exportCfg, utils.NewSitemapCache(&exportCfg))
+	controllers.RegisterSiteRoutes(router, siteTheme, &exportCfg, nil)
+
+	e := &exporter{cfg: &exportCfg, store: store, theme: siteTheme, router: router, out: *out}
+	start := time.Now()
+	incremental, err := e.run(*full)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+		utils.Log.Errorf("静态导出失败: %v", err)
+		return 1
+	}
+
+	mode := "全量"
+	if incremental {
+		mode = "增量"
+	}
+	fmt.Printf("%s导出完成（%s）: 写入 %d 个文件，未变化 %d 个，删除 %d 个，耗时 %s\n",
+		mode, e.out, e.written, e.unchanged, e.removed, time.Since(start).Round(time.Millisecond))
+	utils.Log.Infof("静态导出完成: mode: %s, out: %s, written: %d, unchanged: %d, removed: %d",
+		mode, e.out, e.written, e.unchanged, e.removed)
+	return 0
+}
+
+// settingsFingerprint 影响全部页面的设置，变化时需要全量导出
+func (e *exporter) settingsFingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.Itoa(exportVersion), e.theme.Fingerprint(), e.cfg.SiteURL, e.cfg.SiteTitle, e.cfg.SiteDescription,
+		e.cfg.PostPermalink, e.cfg.HighlightStyle, strconv.Itoa(e.cfg.SitePageSize), strconv.Itoa(e.cfg.FeedItemLimit),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// run 执行导出，返回是否为增量导出
+func (e *exporter) run(full bool) (bool, error) {
+	if err := os.MkdirAll(e.out, 0o755); err != nil {
+		return false, err
+	}
+	settings := e.settingsFingerprint()
+	incremental := !full && e.loadManifest() && e.old.Settings == settings
+	if !incremental {
+		e.old.Posts = map[string]exportPostState{}
+	}
+	e.manifest = exportManifest{
+		Version:  exportVersion,
+		Settings: settings,
+		Posts:    map[string]exportPostState{},
+		Files:    map[string]exportFileState{},
+	}
+
+	var posts []models.Post
+	if err := config.DB.Preload("User").Preload("Tags").Order("created_at DESC").Find(&posts).Error; err != nil {
+		return false, err
+	}
+	states, err := postStates(posts)
+	if err != nil {
+		return false, err
+	}
+
+	// 变化（新增、修改、删除）的文章影响其自身、作者和标签（含变化前的作者和标签）
+	e.affected = map[string]bool{groupGlobal: true, groupMedia: true}
+	for id, state := range states {
+		old, exists := e.old.Posts[id]
+		if !incremental || !exists || old.Fingerprint != state.Fingerprint {
+			e.affectPost(id, state)
+			if exists {
+				e.affectPost(id, old)
+			}
+		}
+	}
+	for id, old := range e.old.Posts {
+		if _, exists := states[id]; !exists {
+			e.affectPost(id, old)
+		}
+	}
+	if !incremental {
+		// 全量导出时上次导出的全部文件都视为受影响，不再生成的会被删除
+		for _, file := range e.old.Files {
+			e.affected[file.Group] = true
+		}
+	}
+	e.manifest.Posts = states
+
+	if err := e.exportGlobal(len(posts)); err != nil {
+		return false, err
+	}
+	if err := e.exportMedia(); err != nil {
+		return false, err
+	}
+	authorPosts := map[string]int{}
+	tagPosts := map[string]int{}
+	for i := range posts {
+		post := &posts[i]
+		id := strconv.FormatUint(uint64(post.ID), 10)
+		authorPosts[post.User.Username]++
+		for _, tag := range post.Tags {
+			tagPosts[tag.Slug]++
+		}
+		if !e.affected["post:"+id] {
+			continue
+		}
+		group := "post:" + id
+		if err := e.page(utils.BuildPermalink(e.cfg.PostPermalink, post), group); err != nil {
+			return false, err
+		}
+		for file := range controllers.FeedFiles {
+			if err := e.file("/posts/"+id+"/comments/"+file, group); err != nil {
+				return false, err
+			}
+		}
+	}
+	for username, count := range authorPosts {
+		if e.affected["author:"+username] {
+			if err := e.list("/authors/"+url.PathEscape(username), count, "author:"+username); err != nil {
+				return false, err
+			}
+		}
+	}
+	for slug, count := range tagPosts {
+		if e.affected["tag:"+slug] {
+			if err := e.list("/tags/"+url.PathEscape(slug), count, "tag:"+slug); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// 未受影响分组的文件原样保留，受影响分组中本次未生成的文件删除
+	for name, file := range e.old.Files {
+		if _, produced := e.manifest.Files[name]; produced {
+			continue
+		}
+		if !e.affected[file.Group] {
+			e.manifest.Files[name] = file
+			continue
+		}
+		if err := os.Remove(filepath.Join(e.out, filepath.FromSlash(name))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		e.removed++
+		e.removeEmptyDirs(path.Dir(name))
+	}
+	return incremental, e.saveManifest()
+}
+
+// affectPost 标记文章及其作者、标签为受影响
+func (e *exporter) affectPost(id string, state exportPostState) {
+	e.affected["post:"+id] = true
+	e.affected["author:"+state.Author] = true
+	for _, tag := range state.Tags {
+		e.affected["tag:"+tag] = true
+	}
+}
+
+// postStates 计算每篇文章的状态摘要：文章、作者、标签、评论任一变化都会改变摘要
+func postStates(posts []models.Post) (map[string]exportPostState, error) {
+	var comments []struct {
+		PostID  uint
+		Count   int64
+		Updated string
+	}
+	if err := config.DB.Model(&models.Comment{}).Scopes(models.ApprovedComments).
+		Select("post_id, COUNT(*) AS count, COALESCE(MAX(updated_at), '') AS updated").
+		Group("post_id").Scan(&comments).Error; err != nil {
+		return nil, err
+	}
+	commentState := make(map[uint]string, len(comments))
+	for _, c := range comments {
+		commentState[c.PostID] = fmt.Sprintf("%d|%s", c.Count, c.Updated)
+	}
+
+	states := make(map[string]exportPostState, len(posts))
+	for i := range posts {
+		post := &posts[i]
+		tags := make([]string, 0, len(post.Tags))
+		for _, tag := range post.Tags {
+			tags = append(tags, tag.Slug)
+		}
+		sort.Strings(tags)
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s|%s|%d|%s",
+			post.ID, post.UpdatedAt.UnixNano(), post.User.Username, strings.Join(tags, ","),
+			post.User.UpdatedAt.UnixNano(), commentState[post.ID])))
+		states[strconv.FormatUint(uint64(post.ID), 10)] = exportPostState{
+			Fingerprint: hex.EncodeToString(sum[:16]),
+			Author:      post.User.Username,
+			Tags:        tags,
+		}
+	}
+	return states, nil
+}
+
+// exportGlobal 首页、归档、全站订阅源、sitemap、robots.txt、404页与主题静态文件
+func (e *exporter) exportGlobal(postCount int) error {
+	if err := e.list("/", postCount, groupGlobal); err != nil {
+		return err
+	}
+	if err := e.page("/archive", groupGlobal); err != nil {
+		return err
+	}
+	for _, name := range []string{"/robots.txt", "/sitemap.xml"} {
+		if err := e.file(name, groupGlobal); err != nil {
+			return err
+		}
+	}
+	// URL数量超过上限时sitemap为索引，逐个导出分页直到不存在
+	for i := 1; ; i++ {
+		body, status := e.get(fmt.Sprintf("/sitemaps/%d.xml", i))
+		if status != http.StatusOK {
+			break
+		}
+		if err := e.write(fmt.Sprintf("sitemaps/%d.xml", i), groupGlobal, body); err != nil {
+			return err
+		}
+	}
+	for file := range controllers.FeedFiles {
+		if err := e.file("/"+file, groupGlobal); err != nil {
+			return err
+		}
+	}
+
+	// 静态托管约定的404页面
+	body, _ := e.get("/404.html")
+	if err := e.write("404.html", groupGlobal, body); err != nil {
+		return err
+	}
+
+	css, err := render.HighlightCSS(e.cfg.HighlightStyle)
+	if err != nil {
+		return err
+	}
+	if err := e.write("assets/highlight.css", groupGlobal, []byte(css)); err != nil {
+		return err
+	}
+	return fs.WalkDir(e.theme.Static(), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(e.theme.Static(), name)
+		if err != nil {
+			return err
+		}
+		return e.write("static/"+name, groupGlobal, data)
+	})
+}
+
+// exportMedia 复制公开媒体文件及其衍生图片；使用外部地址（如S3/CDN）的文件无需复制
+// 媒体文件名由内容哈希生成，上次已导出的文件直接保留
+func (e *exporter) exportMedia() error {
+	var items []models.Media
+	if err := config.DB.Where("visibility = ?", models.MediaPublic).Find(&items).Error; err != nil {
+		return err
+	}
+	for i := range items {
+		keys := []string{items[i].Key}
+		for _, v := range items[i].VariantList() {
+			keys = append(keys, v.Key)
+		}
+		for _, key := range keys {
+			u := e.store.URL(key)
+			if !strings.HasPrefix(u, "/") || strings.HasPrefix(u, "//") {
+				continue
+			}
+			name := strings.TrimPrefix(path.Clean(u), "/")
+			if old, ok := e.old.Files[name]; ok && e.exists(name) {
+				e.manifest.Files[name] = old
+				e.unchanged++
+				continue
+			}
+			if _, done := e.manifest.Files[name]; done {
+				continue
+			}
+			rc, err := e.store.Open(key)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := e.write(name, groupMedia, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// list 导出分页列表页（首页、作者页、标签页）及对应订阅源
+func (e *exporter) list(base string, count int, group string) error {
+	pages := (count + e.cfg.SitePageSize - 1) / e.cfg.SitePageSize
+	if pages < 1 {
+		pages = 1
+	}
+	for n := 1; n <= pages; n++ {
+		p := base
+		if n > 1 {
+			p = strings.TrimSuffix(base, "/") + "/page/" + strconv.Itoa(n)
+		}
+		if err := e.page(p, group); err != nil {
+			return err
+		}
+	}
+	if base == "/" {
+		return nil
+	}
+	for file := range controllers.FeedFiles {
+		if err := e.file(base+"/"+file, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get 在进程内请求页面
+func (e *exporter) get(urlPath string) ([]byte, int) {
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	w := httptest.NewRecorder()
+	e.router.ServeHTTP(w, req)
+	return w.Body.Bytes(), w.Code
+}
+
+// page 导出HTML页面，/a/b 写入 a/b/index.html
+func (e *exporter) page(urlPath, group string) error {
+	body, status := e.get(urlPath)
+	if status != http.StatusOK {
+		return fmt.Errorf("页面渲染失败: %s, status: %d", urlPath, status)
+	}
+	name, err := outputName(urlPath)
+	if err != nil {
+		return err
+	}
+	return e.write(path.Join(name, "index.html"), group, body)
+}
+
+// file 导出非HTML文件（订阅源、sitemap等），路径不变
+func (e *exporter) file(urlPath, group string) error {
+	body, status := e.get(urlPath)
+	if status != http.StatusOK {
+		return fmt.Errorf("文件生成失败: %s, status: %d", urlPath, status)
+	}
+	name, err := outputName(urlPath)
+	if err != nil {
+		return err
+	}
+	return e.write(name, group, body)
+}
+
+// outputName URL路径转为输出目录内的相对路径
+func outputName(urlPath string) (string, error) {
+	p, err := url.PathUnescape(urlPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(path.Clean("/"+p), "/"), nil
+}
+
+// write 写入文件，内容与上次导出相同且文件仍存在时跳过
+func (e *exporter) write(name, group string, data []byte) error {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	e.manifest.Files[name] = exportFileState{Group: group, Hash: hash}
+	if old, ok := e.old.Files[name]; ok && old.Hash == hash && e.exists(name) {
+		e.unchanged++
+		return nil
+	}
+
+	target := filepath.Join(e.out, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".export-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	e.written++
+	return nil
+}
+
+func (e *exporter) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(e.out, filepath.FromSlash(name)))
+	return err == nil
+}
+
+// removeEmptyDirs 删除文件后清理变空的上级目录
+func (e *exporter) removeEmptyDirs(dir string) {
+	for dir != "." && dir != "/" && dir != "" {
+		if err := os.Remove(filepath.Join(e.out, filepath.FromSlash(dir))); err != nil {
+			return
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// loadManifest 读取上次导出的清单，不存在或无法解析时返回false
+func (e *exporter) loadManifest() bool {
+	data, err := os.ReadFile(filepath.Join(e.out, exportManifestFile))
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, &e.old); err != nil || e.old.Version != exportVersion {
+		e.old = exportManifest{}
+		return false
+	}
+	if e.old.Files == nil {
+		e.old.Files = map[string]exportFileState{}
+	}
+	return true
+}
+
+func (e *exporter) saveManifest() error {
+	data, err := json.MarshalIndent(e.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.out, exportManifestFile), data, 0o644)
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/importer"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runImport import 子命令：导入 WordPress WXR 文件、Markdown/Hugo/Jekyll 目录或其zip压缩包
+func runImport(cfg *config.AppConfig, store storage.Storage, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "来源格式：wxr/markdown/hugo/jekyll，默认自动识别")
+	dryRun := flags.Bool("dry-run", false, "只输出导入报告，不写入数据")
+	source := flags.String("source", "", "来源标识，默认取原站点地址；重复导入同一来源时更新已导入的文章")
+	author := flags.String("author", "", "条目缺少作者信息时使用的用户名，默认为第一个管理员")
+	authorMap := flags.String("author-map", "", "作者映射，如 olduser=alice,bob@example.com=bob")
+	fetchRemote := flags.Bool("fetch-remote", false, "下载原站点的远程图片（WXR导入时）")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: go-blog-system import [参数] <WXR文件|目录|zip压缩包>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	opts := importer.Options{
+		Source:       *source,
+		DryRun:       *dryRun,
+		FetchRemote:  *fetchRemote,
+		FetchTimeout: cfg.ImportFetchTimeout,
+		MediaMaxSize: cfg.MediaMaxSize,
+		Store:        store,
+		AuthorMap:    map[string]string{},
+	}
+	for _, pair := range strings.Split(*authorMap, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "作者映射格式错误: %s\n", pair)
+			return 2
+		}
+		opts.AuthorMap[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+	var defaultAuthor models.User
+	query := config.DB.Where("role = ?", models.RoleAdmin).Order("id")
+	if *author != "" {
+		query = config.DB.Where("username = ?", *author)
+	}
+	if err := query.First(&defaultAuthor).Error; err == nil {
+		opts.DefaultAuthor = &defaultAuthor
+	} else if *author != "" {
+		fmt.Fprintf(os.Stderr, "用户不存在: %s\n", *author)
+		return 1
+	} else {
+		fmt.Fprintln(os.Stderr, "注意: 站点还没有管理员，缺少作者信息的条目将导入失败；可用 -author 指定默认作者，或先执行 promote-admin 设置管理员")
+	}
+
+	site, err := loadSite(flags.Arg(0), *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取导入内容失败: %v\n", err)
+		return 1
+	}
+	report, err := importer.Run(site, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入失败: %v\n", err)
+		return 1
+	}
+
+	for _, item := range report.Items {
+		line := fmt.Sprintf("%-9s %s", item.Action, item.ExternalID)
+		if item.PostID > 0 {
+			line += fmt.Sprintf(" -> #%d %s", item.PostID, item.Slug)
+		}
+		if item.Comments > 0 || item.Images > 0 {
+			line += fmt.Sprintf("（评论 %d，图片 %d）", item.Comments, item.Images)
+		}
+		if item.Message != "" {
+			line += ": " + item.Message
+		}
+		fmt.Println(line)
+		for _, w := range item.Warnings {
+			fmt.Println("          ! " + w)
+		}
+	}
+	mode := "导入完成"
+	if report.DryRun {
+		mode = "试运行完成（未写入任何数据）"
+	}
+	fmt.Printf("%s: 来源 %s（%s），新建 %d，更新 %d，未变化 %d，跳过 %d，失败 %d；评论 %d，图片 %d，新建用户 %d\n",
+		mode, report.Source, report.Format,
+		report.Posts[importer.ActionCreated], report.Posts[importer.ActionUpdated], report.Posts[importer.ActionUnchanged],
+		report.Posts[importer.ActionSkipped], report.Posts[importer.ActionFailed],
+		report.Comments, report.Images, report.UsersCreated)
+	if report.Posts[importer.ActionFailed] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadSite 按路径类型解析导入内容：.xml 为WXR文件，.zip 为压缩包，其余为目录
+func loadSite(name, format string) (*importer.Site, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	label := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if info.IsDir() {
+		return importer.LoadDir(os.DirFS(name), format, label)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if format == importer.FormatWXR || (format == "" && strings.EqualFold(filepath.Ext(name), ".xml")) {
+		return importer.LoadWXR(f)
+	}
+	fsys, err := importer.OpenZip(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	return importer.LoadDir(fsys, format, label)
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"go-blog-system/models"
 	"log"
+	"os"
+	"strings"
 	"time"
 
 	"gorm.io/driver/sqlite"
@@ -12,9 +14,91 @@ import (
 
 // AppConfig 全局配置结构体
 type AppConfig struct {
-	JWTSecretKey string // JWT密钥
-	TokenExpire  int    // Token过期时间（小时）
-	DBFile       string // SQLite文件路径
+	JWTSecretKey             string         // JWT密钥（仅 HS256 使用），为空时使用 JWTSecretKeyFile
+	JWTSecretKeyFile         string         // 未配置 JWT_SECRET 时自动生成并保存的 HS256 密钥文件
+	JWTAlgorithm             string         // JWT签名算法：RS256/EdDSA/HS256
+	JWTKeyDir                string         // 非对称密钥目录
+	JWTSigningKeyID          string         // 当前签名密钥ID，为空时自动选择
+	JWTIssuer                string         // Token签发方（iss）
+	JWTAudience              string         // Token受众（aud）
+	TokenExpire              int            // Token过期时间（小时）
+	DBFile                   string         // SQLite文件路径
+	UploadDir                string         // 上传文件本地存储目录
+	UploadBaseURL            string         // 上传文件访问路径前缀
+	AvatarMaxSize            int64          // 头像文件大小上限（字节）
+	Storage                  StorageConfig  // 文件存储后端
+	MediaMaxSize             int64          // 媒体文件大小上限（字节）
+	MediaURLSecret           string         // 本地存储签名下载地址的密钥，为空时使用 MediaURLSecretFile
+	MediaURLSecretFile       string         // 未配置 MEDIA_URL_SECRET 时自动生成并保存的密钥文件
+	MediaSignedURLExpire     time.Duration  // 签名下载地址有效期
+	ImageVariantWidths       []int          // 响应式图片宽度
+	ImageWorkers             int            // 图片处理并发数
+	ImageQueueSize           int            // 图片处理队列长度
+	ImageMaxPixels           int            // 可处理图片的最大像素数
+	ImageCacheDir            string         // 动态缩放图片的磁盘缓存目录
+	ImageResizeMaxWidth      int            // 动态缩放允许的最大宽度
+	ImageWebPEncoder         string         // 有损WebP编码器（cwebp）路径，找不到时使用内置无损编码
+	ImageWebPQuality         int            // 有损WebP质量（0-100）
+	OIDCProviders            []OIDCProvider // 第三方登录提供方
+	AccountDeletionGraceDays int            // 注销宽限期（天）
+	AccountPurgeInterval     time.Duration  // 注销账号清理任务执行间隔
+	HighlightStyle           string         // 代码高亮配色（chroma样式名）
+	PostPermalink            string         // 文章固定链接格式，支持 :year :month :day :slug :id
+	SiteURL                  string         // 站点对外访问地址，用于订阅源等需要绝对链接的场景
+	SiteTitle                string         // 站点名称
+	SiteDescription          string         // 站点简介
+	FeedItemLimit            int            // 订阅源条目数量
+	RobotsDisallow           []string       // robots.txt 禁止抓取的路径
+	RobotsExtra              string         // 追加到 robots.txt 的自定义规则
+	SiteEnabled              bool           // 是否启用服务端渲染的站点页面
+	ThemeDir                 string         // 自定义主题目录，未提供的文件使用内置默认主题
+	SitePageSize             int            // 站点列表页每页文章数
+	SiteStatic               bool           // 静态导出模式：页面中不出现依赖服务端的功能（如搜索）
+	ImportMaxSize            int64          // 导入接口上传文件（WXR或zip压缩包）大小上限（字节）
+	ImportFetchTimeout       time.Duration  // 导入时下载远程图片的超时时间
+	BackupDir                string         // 定时备份的保存目录
+	BackupInterval           time.Duration  // 定时备份间隔，为0时不自动备份
+	BackupRetention          int            // 保留的定时备份数量
+	BackupIncludeMedia       bool           // 备份中是否包含媒体文件和头像
+	CommentModeration        string         // 站点默认的评论审核方式：open/first_time/moderated/closed，文章可单独设置
+	CommentMaxLinks          int            // 评论中的链接数量上限，超过时标记为垃圾评论（负数不限制）
+	CommentBannedWords       []string       // 评论屏蔽词（不区分大小写）
+	CommentBannedPatterns    []string       // 评论屏蔽规则（正则表达式）
+	CommentDuplicateWindow   time.Duration  // 重复内容检测的时间范围
+	CommentDuplicateLimit    int            // 时间范围内相同的带链接内容出现该次数后标记为垃圾评论
+	CommentReputationLimit   int            // 用户的垃圾评论达到该数量（且多于正常评论）后，后续评论直接标记
+	AkismetKey               string         // Akismet API密钥，为空时不调用外部分类服务
+	AkismetEndpoint          string         // Akismet 兼容服务地址
+	AkismetTimeout           time.Duration  // 调用外部分类服务的超时时间
+	ViewDedupWindow          time.Duration  // 同一访客重复阅读同一文章时，该时间内只计一次
+	ViewFlushInterval        time.Duration  // 阅读计数写入数据库的间隔
+	ViewFlushBatch           int            // 内存中累计的阅读计数达到该数量时提前写入
+	AnalyticsMaxDays         int            // 统计接口单次查询的最大天数
+	ShutdownTimeout          time.Duration  // 收到退出信号后等待进行中请求完成的最长时间
+}
+
+// siteURL 站点对外地址，未配置 SITE_URL 时使用本地地址
+func siteURL() string {
+	if u := os.Getenv("SITE_URL"); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// jwtAlgorithm JWT签名算法，可通过 JWT_ALGORITHM 选择 RS256/EdDSA/HS256，默认 RS256
+func jwtAlgorithm() string {
+	if alg := os.Getenv("JWT_ALGORITHM"); alg != "" {
+		return alg
+	}
+	return "RS256"
+}
+
+// akismetEndpoint Akismet 兼容服务地址，可通过 AKISMET_ENDPOINT 指向自建服务
+func akismetEndpoint() string {
+	if u := os.Getenv("AKISMET_ENDPOINT"); u != "" {
+		return u
+	}
+	return "https://rest.akismet.com"
 }
 
 // 全局DB实例
@@ -23,9 +107,65 @@ var DB *gorm.DB
 // NewDefaultConfig 创建默认配置
 func NewDefaultConfig() *AppConfig {
 	return &AppConfig{
-		JWTSecretKey: "blog-jwt-secret-2025",
-		TokenExpire:  72,
-		DBFile:       "blog.db",
+		JWTSecretKey:             os.Getenv("JWT_SECRET"),
+		JWTSecretKeyFile:         "keys/jwt_hs256.secret",
+		JWTAlgorithm:             jwtAlgorithm(),
+		JWTKeyDir:                "keys",
+		JWTSigningKeyID:          os.Getenv("JWT_SIGNING_KEY_ID"),
+		JWTIssuer:                "go-blog-system",
+		JWTAudience:              "go-blog-system",
+		TokenExpire:              72,
+		DBFile:                   "blog.db",
+		UploadDir:                "uploads",
+		UploadBaseURL:            "/uploads",
+		AvatarMaxSize:            2 << 20,
+		Storage:                  loadStorageConfig(),
+		MediaMaxSize:             20 << 20,
+		MediaURLSecret:           os.Getenv("MEDIA_URL_SECRET"),
+		MediaURLSecretFile:       "keys/media_url.secret",
+		MediaSignedURLExpire:     15 * time.Minute,
+		ImageVariantWidths:       []int{320, 640, 1280, 1920},
+		ImageWorkers:             2,
+		ImageQueueSize:           256,
+		ImageMaxPixels:           50_000_000,
+		ImageCacheDir:            "cache/images",
+		ImageResizeMaxWidth:      2560,
+		ImageWebPEncoder:         "cwebp",
+		ImageWebPQuality:         80,
+		OIDCProviders:            loadOIDCProviders(),
+		AccountDeletionGraceDays: 14,
+		AccountPurgeInterval:     time.Hour,
+		HighlightStyle:           "github",
+		PostPermalink:            "/posts/:slug",
+		SiteURL:                  siteURL(),
+		SiteTitle:                "Go Blog",
+		SiteDescription:          "团队技术博客",
+		FeedItemLimit:            20,
+		RobotsDisallow:           []string{"/api/", "/uploads/private/"},
+		SiteEnabled:              true,
+		ThemeDir:                 os.Getenv("THEME_DIR"),
+		SitePageSize:             10,
+		ImportMaxSize:            200 << 20,
+		ImportFetchTimeout:       30 * time.Second,
+		BackupDir:                "backups",
+		BackupInterval:           24 * time.Hour,
+		BackupRetention:          7,
+		BackupIncludeMedia:       true,
+		CommentModeration:        models.CommentModeFirstTime,
+		CommentMaxLinks:          3,
+		CommentBannedWords:       []string{},
+		CommentBannedPatterns:    []string{},
+		CommentDuplicateWindow:   24 * time.Hour,
+		CommentDuplicateLimit:    3,
+		CommentReputationLimit:   3,
+		AkismetKey:               os.Getenv("AKISMET_KEY"),
+		AkismetEndpoint:          akismetEndpoint(),
+		AkismetTimeout:           5 * time.Second,
+		ViewDedupWindow:          30 * time.Minute,
+		ViewFlushInterval:        30 * time.Second,
+		ViewFlushBatch:           500,
+		AnalyticsMaxDays:         366,
+		ShutdownTimeout:          10 * time.Second,
 	}
 }
 
@@ -48,11 +188,39 @@ func InitDB(cfg *AppConfig) {
 		panic("数据库连接失败: " + err.Error())
 	}
 
+	// 检查数据库结构版本须在任何迁移之前，由更新版本程序创建的数据库不能被旧版本程序修改
+	if DB.Migrator().HasTable(&models.SchemaInfo{}) {
+		var schema models.SchemaInfo
+		if err := DB.Limit(1).Find(&schema, 1).Error; err != nil {
+			panic("读取数据库版本失败: " + err.Error())
+		}
+		if schema.Version > models.SchemaVersion {
+			log.Fatalf("[Config] 数据库版本（%d）高于程序支持的版本（%d），请升级程序", schema.Version, models.SchemaVersion)
+		}
+	}
+
 	// 自动迁移表
-	err = DB.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{})
+	err = DB.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}, &models.RecoveryCode{}, &models.APIKey{}, &models.UserIdentity{}, &models.Session{}, &models.PostSlugRedirect{}, &models.Media{}, &models.Tag{}, &models.ImportRecord{}, &models.SchemaInfo{}, &models.Reaction{}, &models.PostViewStat{}, &models.PostReferrerStat{}, &models.Bookmark{}, &models.BookmarkCollection{}, &models.Follow{}, &models.Notification{}, &models.NotificationActor{}, &models.Mention{}, &models.ReservedUsername{})
 	if err != nil {
 		log.Printf("[Config] 表迁移失败: %v", err)
 		panic("表迁移失败: " + err.Error())
 	}
+	if err := models.BackfillPostSlugs(DB); err != nil {
+		log.Printf("[Config] 补全文章slug失败: %v", err)
+		panic("补全文章slug失败: " + err.Error())
+	}
+	if err := models.EnsurePostFeedIndex(DB); err != nil {
+		log.Printf("[Config] 创建文章索引失败: %v", err)
+		panic("创建文章索引失败: " + err.Error())
+	}
+	if err := models.EnsureNotificationGroupIndex(DB); err != nil {
+		log.Printf("[Config] 创建通知索引失败: %v", err)
+		panic("创建通知索引失败: " + err.Error())
+	}
+
+	// 记录数据库结构版本
+	if err := DB.Save(&models.SchemaInfo{ID: 1, Version: models.SchemaVersion}).Error; err != nil {
+		panic("写入数据库版本失败: " + err.Error())
+	}
 	log.Println("[Config] 数据库初始化成功")
 }
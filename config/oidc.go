@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// OIDCProvider OpenID Connect 登录提供方配置
+type OIDCProvider struct {
+	Name         string   // 提供方标识，用于路由（如 google、keycloak）
+	IssuerURL    string   // Issuer地址，用于自动发现配置
+	ClientID     string   // 客户端ID
+	ClientSecret string   // 客户端密钥（公共客户端可为空，仅使用PKCE）
+	RedirectURL  string   // 回调地址，需指向 /api/auth/oidc/:provider/callback
+	Scopes       []string // 额外申请的scope（openid 会自动添加）
+	TrustEmail   bool     // 首次登录时是否按已验证邮箱自动关联同邮箱的本地账号，仅对可信的提供方开启
+}
+
+// loadOIDCProviders 从环境变量读取OIDC提供方配置
+// OIDC_PROVIDERS=google,keycloak
+// OIDC_GOOGLE_ISSUER / OIDC_GOOGLE_CLIENT_ID / OIDC_GOOGLE_CLIENT_SECRET / OIDC_GOOGLE_REDIRECT_URL / OIDC_GOOGLE_SCOPES / OIDC_GOOGLE_TRUST_EMAIL
+func loadOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		scopes := []string{"profile", "email"}
+		if s := os.Getenv(prefix + "SCOPES"); s != "" {
+			scopes = strings.Fields(strings.ReplaceAll(s, ",", " "))
+		}
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       scopes,
+			TrustEmail:   os.Getenv(prefix+"TRUST_EMAIL") == "true",
+		})
+	}
+	return providers
+}
@@ -0,0 +1,35 @@
+package config
+
+import "os"
+
+// StorageConfig 文件存储后端配置
+type StorageConfig struct {
+	Driver          string // 存储后端：local（默认）/ s3
+	S3Endpoint      string // S3兼容服务地址（不含协议），如 127.0.0.1:9000
+	S3Region        string
+	S3Bucket        string
+	S3AccessKey     string
+	S3SecretKey     string
+	S3UseSSL        bool
+	S3PublicBaseURL string // 公开文件访问前缀（如CDN地址）
+}
+
+// loadStorageConfig 从环境变量读取存储后端配置
+// STORAGE_DRIVER=s3
+// S3_ENDPOINT / S3_REGION / S3_BUCKET / S3_ACCESS_KEY / S3_SECRET_KEY / S3_USE_SSL / S3_PUBLIC_BASE_URL
+func loadStorageConfig() StorageConfig {
+	cfg := StorageConfig{
+		Driver:          os.Getenv("STORAGE_DRIVER"),
+		S3Endpoint:      os.Getenv("S3_ENDPOINT"),
+		S3Region:        os.Getenv("S3_REGION"),
+		S3Bucket:        os.Getenv("S3_BUCKET"),
+		S3AccessKey:     os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:     os.Getenv("S3_SECRET_KEY"),
+		S3UseSSL:        os.Getenv("S3_USE_SSL") != "false",
+		S3PublicBaseURL: os.Getenv("S3_PUBLIC_BASE_URL"),
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = "local"
+	}
+	return cfg
+}
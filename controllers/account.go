@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountExport 个人数据导出内容
+type accountExport struct {
+	ExportedAt  time.Time                   `json:"exported_at"`
+	Profile     gin.H                       `json:"profile"`
+	Posts       []models.Post               `json:"posts"`
+	Comments    []models.Comment            `json:"comments"`
+	Reactions   []models.Reaction           `json:"reactions"`
+	Bookmarks   []models.Bookmark           `json:"bookmarks"`
+	Collections []models.BookmarkCollection `json:"bookmark_collections"`
+	Following   []models.Follow             `json:"following"`
+	Notices     []models.Notification       `json:"notifications"`
+	Sessions    []models.Session            `json:"sessions"`
+	APIKeys     []gin.H                     `json:"api_keys"`
+	Identities  []models.UserIdentity       `json:"identities"`
+	Media       []gin.H                     `json:"media"`
+}
+
+// collectAccountExport 汇总用户的全部个人数据
+func collectAccountExport(user *models.User, store storage.Storage) (*accountExport, error) {
+	export := &accountExport{ExportedAt: time.Now()}
+
+	export.Profile = profileData(store, user)
+	export.Profile["email"] = user.Email
+	export.Profile["role"] = user.Role
+	export.Profile["two_factor_enabled"] = user.TOTPEnabled
+
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Posts).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Comments).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Reactions).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Bookmarks).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Collections).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("follower_id = ?", user.ID).Order("created_at").Find(&export.Following).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Notices).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Sessions).Error; err != nil {
+		return nil, err
+	}
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&export.Identities).Error; err != nil {
+		return nil, err
+	}
+	var keys []models.APIKey
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	export.APIKeys = make([]gin.H, 0, len(keys))
+	for i := range keys {
+		export.APIKeys = append(export.APIKeys, apiKeyData(&keys[i]))
+	}
+	var media []models.Media
+	if err := config.DB.Where("user_id = ?", user.ID).Order("created_at").Find(&media).Error; err != nil {
+		return nil, err
+	}
+	export.Media = make([]gin.H, 0, len(media))
+	for i := range media {
+		export.Media = append(export.Media, mediaData(store, &media[i]))
+	}
+	return export, nil
+}
+
+// ExportAccountData 导出个人数据：默认ZIP（含JSON及头像），?format=json 时返回单个JSON
+func ExportAccountData(c *gin.Context, store storage.Storage) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	export, err := collectAccountExport(&user, store)
+	if err != nil {
+		utils.Log.Errorf("导出个人数据失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "导出个人数据失败: "+err.Error())
+		return
+	}
+	utils.Log.Infof("导出个人数据: user_id: %d, format: %s", user.ID, c.DefaultQuery("format", "zip"))
+
+	filename := fmt.Sprintf("%s-export-%s", user.Username, export.ExportedAt.Format("20060102"))
+	if c.Query("format") == "json" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"posts.json", export.Posts},
+		{"comments.json", export.Comments},
+		{"reactions.json", export.Reactions},
+		{"bookmarks.json", export.Bookmarks},
+		{"bookmark_collections.json", export.Collections},
+		{"following.json", export.Following},
+		{"notifications.json", export.Notices},
+		{"sessions.json", export.Sessions},
+		{"api_keys.json", export.APIKeys},
+		{"identities.json", export.Identities},
+		{"media.json", export.Media},
+	}
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err == nil {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(file.data)
+		}
+		if err != nil {
+			// 响应头已发送，只能记录日志
+			utils.Log.Errorf("写入导出文件失败: %v, file: %s, user_id: %d", err, file.name, user.ID)
+			return
+		}
+	}
+
+	// 附带原始头像文件
+	if user.Avatar != "" {
+		for _, size := range models.AvatarSizes {
+			key := models.AvatarKey(user.Avatar, size)
+			r, err := store.Open(key)
+			if err != nil {
+				continue
+			}
+			w, err := zw.Create(fmt.Sprintf("avatar/%d.jpg", size))
+			if err == nil {
+				_, err = io.Copy(w, r)
+			}
+			r.Close()
+			if err != nil {
+				utils.Log.Errorf("写入头像失败: %v, user_id: %d", err, user.ID)
+				return
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		utils.Log.Errorf("生成导出压缩包失败: %v, user_id: %d", err, user.ID)
+	}
+}
+
+// RequestAccountDeletion 申请注销账号，宽限期结束后由后台任务彻底清除
+func RequestAccountDeletion(c *gin.Context, graceDays int) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+		Content  string `json:"content" binding:"required,oneof=anonymize delete"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	// 第三方登录创建的账号没有密码，依赖当前登录态确认
+	if !user.PasswordUnset && !user.CheckPassword(req.Password) {
+		utils.Unauthorized(c, "密码错误")
+		return
+	}
+	if user.Role == models.RoleAdmin {
+		var admins int64
+		config.DB.Model(&models.User{}).Where("role = ? AND deletion_scheduled_at IS NULL", models.RoleAdmin).Count(&admins)
+		if admins <= 1 {
+			utils.BadRequest(c, "系统中至少需要保留一名管理员")
+			return
+		}
+	}
+
+	scheduledAt := time.Now().AddDate(0, 0, graceDays)
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"deletion_scheduled_at": scheduledAt,
+		"deletion_mode":         req.Content,
+	}).Error; err != nil {
+		utils.Log.Errorf("申请注销失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "申请注销失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("用户申请注销: user_id: %d, mode: %s, scheduled_at: %s", user.ID, req.Content, scheduledAt.Format(time.RFC3339))
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("已申请注销，账号将于 %d 天后彻底清除，期间可随时撤销", graceDays),
+		"data": gin.H{
+			"deletion_scheduled_at": scheduledAt,
+			"content":               req.Content,
+		},
+	})
+}
+
+// CancelAccountDeletion 撤销注销申请
+func CancelAccountDeletion(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userId).
+		Updates(map[string]interface{}{
+			"deletion_scheduled_at": nil,
+			"deletion_mode":         "",
+		})
+	if result.Error != nil {
+		utils.Log.Errorf("撤销注销失败: %v, user_id: %d", result.Error, userId)
+		utils.InternalError(c, "撤销注销失败: "+result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.BadRequest(c, "未申请注销")
+		return
+	}
+
+	utils.Log.Infof("用户撤销注销: user_id: %d", userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已撤销注销申请",
+	})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTargetUser 解析路径中的用户ID并加载用户
+func adminTargetUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "用户ID格式错误")
+		return nil, false
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, id).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return nil, false
+	}
+	return &user, true
+}
+
+// SetUserRole 管理员设置用户角色
+func SetUserRole(c *gin.Context) {
+	adminId, _ := c.Get("user_id")
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=user editor admin"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if user.ID == adminId.(uint) && req.Role != models.RoleAdmin {
+		utils.BadRequest(c, "不能取消自己的管理员角色")
+		return
+	}
+
+	updates := map[string]interface{}{"role": req.Role}
+	// 降为普通用户时取消强制两步验证
+	if req.Role == models.RoleUser {
+		updates["two_factor_required"] = false
+	}
+	if err := config.DB.Model(user).Updates(updates).Error; err != nil {
+		utils.Log.Errorf("设置用户角色失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "设置用户角色失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("用户角色已更新: user_id: %d, role: %s, admin_id: %d", user.ID, req.Role, adminId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "角色设置成功",
+		"data": gin.H{
+			"user_id": user.ID,
+			"role":    req.Role,
+		},
+	})
+}
+
+// SetTwoFactorRequired 管理员强制（或取消强制）编辑/管理员账号启用两步验证
+func SetTwoFactorRequired(c *gin.Context) {
+	adminId, _ := c.Get("user_id")
+	user, ok := adminTargetUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if *req.Required && !user.IsPrivileged() {
+		utils.BadRequest(c, "仅可对编辑或管理员账号强制启用两步验证")
+		return
+	}
+
+	if err := config.DB.Model(user).Update("two_factor_required", *req.Required).Error; err != nil {
+		utils.Log.Errorf("设置强制两步验证失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "设置失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("强制两步验证已更新: user_id: %d, required: %v, admin_id: %d", user.ID, *req.Required, adminId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "设置成功",
+		"data": gin.H{
+			"user_id":             user.ID,
+			"two_factor_required": *req.Required,
+			"two_factor_enabled":  user.TOTPEnabled,
+		},
+	})
+}
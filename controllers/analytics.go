@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/jobs"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 统计接口默认查询最近30天，排行榜返回前10项
+const (
+	analyticsDefaultDays = 30
+	analyticsTopLimit    = 10
+)
+
+// recordView 记录一次文章阅读，HEAD请求和浏览器预加载（prefetch/prerender）的请求不计数
+func recordView(c *gin.Context, views *jobs.ViewCounter, postID uint) {
+	if c.Request.Method != http.MethodGet || c.GetHeader("Sec-Purpose") != "" || c.GetHeader("Purpose") == "prefetch" || c.GetHeader("X-Moz") == "prefetch" {
+		return
+	}
+	host := ""
+	if u, err := url.Parse(c.Request.Referer()); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	views.Record(jobs.View{
+		PostID:       postID,
+		UserID:       optionalUserID(c),
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		ReferrerHost: host,
+	})
+}
+
+// dailyStat 某一天的统计
+type dailyStat struct {
+	Day      string `json:"day"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+	Comments int64  `json:"comments"`
+}
+
+// referrerStat 来源统计
+type referrerStat struct {
+	Host  string `json:"host"` // 直接访问为空
+	Views int64  `json:"views"`
+}
+
+// topPost 阅读排行中的文章
+type topPost struct {
+	PostID   uint   `json:"post_id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Views    int64  `json:"views"`
+	Visitors int64  `json:"visitors"`
+	Comments int64  `json:"comments"` // 范围内的评论数
+}
+
+// analyticsRange 解析 ?from=&to=（YYYY-MM-DD，含首尾两天），默认最近30天
+func analyticsRange(c *gin.Context, maxDays int) (from, to time.Time, ok bool) {
+	today, _ := time.ParseInLocation(models.StatsDayLayout, time.Now().Format(models.StatsDayLayout), time.Local)
+	to, from = today, today.AddDate(0, 0, 1-analyticsDefaultDays)
+	var err error
+	if s := c.Query("to"); s != "" {
+		if to, err = time.ParseInLocation(models.StatsDayLayout, s, time.Local); err != nil {
+			utils.BadRequest(c, "to 日期格式错误，应为 YYYY-MM-DD")
+			return
+		}
+		from = to.AddDate(0, 0, 1-analyticsDefaultDays)
+	}
+	if s := c.Query("from"); s != "" {
+		if from, err = time.ParseInLocation(models.StatsDayLayout, s, time.Local); err != nil {
+			utils.BadRequest(c, "from 日期格式错误，应为 YYYY-MM-DD")
+			return
+		}
+	}
+	if from.After(to) {
+		utils.BadRequest(c, "from 不能晚于 to")
+		return
+	}
+	if int(to.Sub(from).Hours()/24)+1 > maxDays {
+		utils.BadRequest(c, "查询范围不能超过 "+strconv.Itoa(maxDays)+" 天")
+		return
+	}
+	return from, to, true
+}
+
+// dailyStats 按天汇总阅读和评论，范围内没有数据的日期补0
+func dailyStats(postIDs *gorm.DB, from, to time.Time) ([]dailyStat, error) {
+	fromDay, toDay := from.Format(models.StatsDayLayout), to.Format(models.StatsDayLayout)
+	var rows []dailyStat
+	if err := config.DB.Model(&models.PostViewStat{}).
+		Select("day, SUM(views) AS views, SUM(visitors) AS visitors").
+		Where("post_id IN (?) AND day BETWEEN ? AND ?", postIDs, fromDay, toDay).
+		Group("day").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]*dailyStat, len(rows))
+	for i := range rows {
+		byDay[rows[i].Day] = &rows[i]
+	}
+
+	// 评论按本地日期分组在程序中完成，不依赖数据库的日期函数
+	var commentTimes []time.Time
+	if err := config.DB.Model(&models.Comment{}).Scopes(models.ApprovedComments).
+		Where("post_id IN (?) AND created_at >= ? AND created_at < ?", postIDs, from, to.AddDate(0, 0, 1)).
+		Pluck("created_at", &commentTimes).Error; err != nil {
+		return nil, err
+	}
+	comments := make(map[string]int64)
+	for _, t := range commentTimes {
+		comments[t.In(time.Local).Format(models.StatsDayLayout)]++
+	}
+
+	var result []dailyStat
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := d.Format(models.StatsDayLayout)
+		stat := dailyStat{Day: day, Comments: comments[day]}
+		if row := byDay[day]; row != nil {
+			stat.Views, stat.Visitors = row.Views, row.Visitors
+		}
+		result = append(result, stat)
+	}
+	return result, nil
+}
+
+// topReferrers 范围内阅读最多的来源
+func topReferrers(postIDs *gorm.DB, from, to time.Time) ([]referrerStat, error) {
+	referrers := []referrerStat{}
+	err := config.DB.Model(&models.PostReferrerStat{}).Select("host, SUM(views) AS views").
+		Where("post_id IN (?) AND day BETWEEN ? AND ?", postIDs, from.Format(models.StatsDayLayout), to.Format(models.StatsDayLayout)).
+		Group("host").Order("views DESC").Limit(analyticsTopLimit).Scan(&referrers).Error
+	return referrers, err
+}
+
+// sumDaily 汇总每日统计
+func sumDaily(daily []dailyStat) gin.H {
+	var views, visitors, comments int64
+	for _, d := range daily {
+		views += d.Views
+		visitors += d.Visitors
+		comments += d.Comments
+	}
+	return gin.H{"views": views, "visitors": visitors, "comments": comments}
+}
+
+// PostAnalytics 单篇文章的统计（文章作者或编辑/管理员）：每日阅读/访客/评论、来源排行
+// 访客数为每日独立访客之和
+func PostAnalytics(c *gin.Context, cfg *config.AppConfig) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "文章ID格式错误")
+		return
+	}
+	var post models.Post
+	if err := config.DB.Select("id", "title", "slug", "user_id", "view_count", "created_at").First(&post, id).Error; err != nil ||
+		(post.UserID != user.ID && !user.IsPrivileged()) {
+		utils.NotFound(c, "文章不存在或无查看权限")
+		return
+	}
+	from, to, ok := analyticsRange(c, cfg.AnalyticsMaxDays)
+	if !ok {
+		return
+	}
+
+	postIDs := config.DB.Model(&models.Post{}).Select("id").Where("id = ?", post.ID)
+	daily, err := dailyStats(postIDs, from, to)
+	if err != nil {
+		utils.Log.Errorf("获取文章统计失败: %v, post_id: %d", err, post.ID)
+		utils.InternalError(c, "获取文章统计失败: "+err.Error())
+		return
+	}
+	referrers, err := topReferrers(postIDs, from, to)
+	if err != nil {
+		utils.Log.Errorf("获取文章来源统计失败: %v, post_id: %d", err, post.ID)
+		utils.InternalError(c, "获取文章统计失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"post": gin.H{
+				"id":         post.ID,
+				"title":      post.Title,
+				"slug":       post.Slug,
+				"view_count": post.ViewCount,
+				"created_at": post.CreatedAt,
+			},
+			"from":      from.Format(models.StatsDayLayout),
+			"to":        to.Format(models.StatsDayLayout),
+			"totals":    sumDaily(daily),
+			"daily":     daily,
+			"referrers": referrers,
+		},
+	})
+}
+
+// AnalyticsOverview 统计概览：作者查看自己的全部文章，编辑/管理员查看全站（可用 ?author= 指定作者）
+// 返回每日汇总、阅读最多的文章（含范围内评论数）和来源排行
+func AnalyticsOverview(c *gin.Context, cfg *config.AppConfig) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	from, to, ok := analyticsRange(c, cfg.AnalyticsMaxDays)
+	if !ok {
+		return
+	}
+
+	postIDs := config.DB.Model(&models.Post{}).Select("id")
+	if !user.IsPrivileged() {
+		postIDs = postIDs.Where("user_id = ?", user.ID)
+	} else if username := c.Query("author"); username != "" {
+		var author models.User
+		if err := config.DB.Select("id").Where("username = ?", username).First(&author).Error; err != nil {
+			utils.NotFound(c, "作者不存在")
+			return
+		}
+		postIDs = postIDs.Where("user_id = ?", author.ID)
+	}
+
+	daily, err := dailyStats(postIDs, from, to)
+	if err != nil {
+		utils.Log.Errorf("获取统计概览失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取统计概览失败: "+err.Error())
+		return
+	}
+	referrers, err := topReferrers(postIDs, from, to)
+	if err != nil {
+		utils.Log.Errorf("获取来源统计失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取统计概览失败: "+err.Error())
+		return
+	}
+
+	top := []topPost{}
+	fromDay, toDay := from.Format(models.StatsDayLayout), to.Format(models.StatsDayLayout)
+	if err := config.DB.Model(&models.PostViewStat{}).
+		Select("post_view_stats.post_id, posts.title, posts.slug, SUM(post_view_stats.views) AS views, SUM(post_view_stats.visitors) AS visitors").
+		Joins("JOIN posts ON posts.id = post_view_stats.post_id AND posts.deleted_at IS NULL").
+		Where("post_view_stats.post_id IN (?) AND post_view_stats.day BETWEEN ? AND ?", postIDs, fromDay, toDay).
+		Group("post_view_stats.post_id, posts.title, posts.slug").Order("views DESC").Limit(analyticsTopLimit).
+		Scan(&top).Error; err != nil {
+		utils.Log.Errorf("获取文章排行失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取统计概览失败: "+err.Error())
+		return
+	}
+	if len(top) > 0 {
+		ids := make([]uint, len(top))
+		for i := range top {
+			ids[i] = top[i].PostID
+		}
+		var counts []struct {
+			PostID uint
+			Count  int64
+		}
+		if err := config.DB.Model(&models.Comment{}).Scopes(models.ApprovedComments).
+			Select("post_id, COUNT(*) AS count").
+			Where("post_id IN ? AND created_at >= ? AND created_at < ?", ids, from, to.AddDate(0, 0, 1)).
+			Group("post_id").Scan(&counts).Error; err != nil {
+			utils.Log.Errorf("获取文章评论数失败: %v, user_id: %d", err, user.ID)
+			utils.InternalError(c, "获取统计概览失败: "+err.Error())
+			return
+		}
+		byPost := make(map[uint]int64, len(counts))
+		for _, c := range counts {
+			byPost[c.PostID] = c.Count
+		}
+		for i := range top {
+			top[i].Comments = byPost[top[i].PostID]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"from":      fromDay,
+			"to":        toDay,
+			"totals":    sumDaily(daily),
+			"daily":     daily,
+			"top_posts": top,
+			"referrers": referrers,
+		},
+	})
+}
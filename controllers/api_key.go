@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每个用户最多可持有的有效API密钥数量
+const maxAPIKeysPerUser = 20
+
+// apiKeyData 组装API密钥响应（不含密钥明文）
+func apiKeyData(key *models.APIKey) gin.H {
+	return gin.H{
+		"id":           key.ID,
+		"name":         key.Name,
+		"prefix":       key.Prefix,
+		"scopes":       key.ScopeList(),
+		"created_at":   key.CreatedAt,
+		"expires_at":   key.ExpiresAt,
+		"last_used_at": key.LastUsedAt,
+		"last_used_ip": key.LastUsedIP,
+		"expired":      key.IsExpired(time.Now()),
+	}
+}
+
+// CreateAPIKey 创建个人API密钥，明文仅在创建时返回一次
+func CreateAPIKey(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name" binding:"required,min=1,max=50"`
+		Scopes        []string `json:"scopes" binding:"required,min=1"`
+		ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Log.Warnf("创建API密钥参数错误: %v, user_id: %d", err, userId)
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	// 校验并去重授权范围
+	seen := map[string]bool{}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !models.IsValidScope(scope) {
+			utils.BadRequest(c, "不支持的授权范围: "+scope+"，可选: "+strings.Join(models.AllScopes, ", "))
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.APIKey{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		utils.InternalError(c, "创建API密钥失败: "+err.Error())
+		return
+	}
+	if count >= maxAPIKeysPerUser {
+		utils.BadRequest(c, "API密钥数量已达上限，请先吊销不再使用的密钥")
+		return
+	}
+
+	plain, err := utils.GenerateAPIKey()
+	if err != nil {
+		utils.Log.Errorf("生成API密钥失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "生成API密钥失败")
+		return
+	}
+
+	key := models.APIKey{
+		UserID:  userId.(uint),
+		Name:    req.Name,
+		Prefix:  plain[:len(utils.APIKeyPrefix)+6],
+		KeyHash: utils.HashAPIKey(plain),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := config.DB.Create(&key).Error; err != nil {
+		utils.Log.Errorf("保存API密钥失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "创建API密钥失败: "+err.Error())
+		return
+	}
+
+	data := apiKeyData(&key)
+	data["key"] = plain
+
+	utils.Log.Infof("API密钥创建成功: key_id: %d, user_id: %d, scopes: %s", key.ID, userId, key.Scopes)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API密钥创建成功，请立即保存，密钥仅显示一次",
+		"data":    data,
+	})
+}
+
+// ListAPIKeys 获取当前用户的API密钥列表
+func ListAPIKeys(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var keys []models.APIKey
+	if err := config.DB.Where("user_id = ?", userId).Order("created_at DESC").Find(&keys).Error; err != nil {
+		utils.Log.Errorf("获取API密钥列表失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "获取API密钥列表失败: "+err.Error())
+		return
+	}
+
+	items := make([]gin.H, 0, len(keys))
+	for i := range keys {
+		items = append(items, apiKeyData(&keys[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": items,
+	})
+}
+
+// RevokeAPIKey 吊销API密钥
+func RevokeAPIKey(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "密钥ID格式错误")
+		return
+	}
+
+	var key models.APIKey
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userId).First(&key).Error; err != nil {
+		utils.NotFound(c, "API密钥不存在")
+		return
+	}
+	if err := config.DB.Delete(&key).Error; err != nil {
+		utils.Log.Errorf("吊销API密钥失败: %v, key_id: %d", err, key.ID)
+		utils.InternalError(c, "吊销API密钥失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("API密钥已吊销: key_id: %d, user_id: %d", key.ID, userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API密钥已吊销",
+	})
+}
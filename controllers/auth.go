@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Register 用户注册
@@ -23,6 +24,22 @@ func Register(c *gin.Context) {
 		utils.BadRequest(c, "参数错误: "+err.Error())
 		return
 	}
+	// 其他字符会使 @用户名 无法被正确识别
+	if !models.IsValidUsername(req.Username) {
+		utils.BadRequest(c, "用户名只能包含字母、数字和下划线")
+		return
+	}
+
+	// 匿名化账号的用户名前缀和注销账号释放的用户名不能注册
+	reserved, err := models.IsReservedUsername(config.DB, req.Username)
+	if err != nil {
+		utils.InternalError(c, "注册失败: "+err.Error())
+		return
+	}
+	if reserved {
+		utils.Forbidden(c, "该用户名不可用")
+		return
+	}
 
 	// 检查用户名是否存在
 	var user models.User
@@ -39,13 +56,23 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// 创建用户
+	// 创建用户（首个注册的用户自动成为管理员）
 	newUser := models.User{
 		Username: req.Username,
 		Password: req.Password,
 		Email:    req.Email,
+		Role:     models.RoleUser,
 	}
-	if err := config.DB.Create(&newUser).Error; err != nil {
+	// 先插入再判断是否为现存ID最小的用户：插入后事务持有写锁，并发的首次注册只有一个成为管理员
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND id = (SELECT MIN(id) FROM users WHERE deleted_at IS NULL)", newUser.ID).
+			UpdateColumn("role", models.RoleAdmin).Error
+	})
+	if err != nil {
 		utils.Log.Errorf("创建用户失败: %v, ip: %s", err, c.ClientIP())
 		utils.InternalError(c, "注册失败: "+err.Error())
 		return
@@ -63,7 +90,7 @@ func Register(c *gin.Context) {
 }
 
 // Login 用户登录（接收JWT配置参数）
-func Login(c *gin.Context, jwtSecret string, tokenExpire int) {
+func Login(c *gin.Context, jwtManager *utils.JWTManager, tokenExpire int) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
@@ -91,15 +118,62 @@ func Login(c *gin.Context, jwtSecret string, tokenExpire int) {
 		return
 	}
 
+	completeLogin(c, &user, jwtManager, tokenExpire)
+}
+
+// completeLogin 第一因素（密码/第三方登录）校验通过后，按两步验证状态签发令牌
+func completeLogin(c *gin.Context, user *models.User, jwtManager *utils.JWTManager, tokenExpire int) {
+	// 已启用两步验证：返回临时令牌，等待动态码
+	if user.TOTPEnabled {
+		challengeLogin(c, user, utils.PurposeTwoFactorLogin, jwtManager)
+		return
+	}
+
+	// 被强制要求两步验证但尚未绑定：仅允许访问绑定接口
+	if user.TwoFactorRequired {
+		challengeLogin(c, user, utils.PurposeTwoFactorSetup, jwtManager)
+		return
+	}
+
+	loginSuccess(c, user, jwtManager, tokenExpire)
+}
+
+// challengeLogin 返回两步验证临时令牌
+func challengeLogin(c *gin.Context, user *models.User, purpose string, jwtManager *utils.JWTManager) {
+	token, err := jwtManager.GenerateChallengeToken(user.ID, user.Username, purpose)
+	if err != nil {
+		utils.Log.Errorf("生成临时令牌失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "登录失败: "+err.Error())
+		return
+	}
+
+	message := "请输入两步验证动态码"
+	if purpose == utils.PurposeTwoFactorSetup {
+		message = "账号被要求启用两步验证，请先完成绑定"
+	}
+	utils.Log.Infof("登录等待两步验证: %s, id: %d, purpose: %s", user.Username, user.ID, purpose)
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data": gin.H{
+			"two_factor_required":       purpose == utils.PurposeTwoFactorLogin,
+			"two_factor_setup_required": purpose == utils.PurposeTwoFactorSetup,
+			"challenge_token":           token,
+			"expires_in":                int(utils.ChallengeTokenExpire.Seconds()),
+		},
+	})
+}
+
+// loginSuccess 签发正式Token并返回登录成功响应
+func loginSuccess(c *gin.Context, user *models.User, jwtManager *utils.JWTManager, tokenExpire int) {
 	// 生成Token
-	token, err := utils.GenerateToken(user.ID, user.Username, jwtSecret, tokenExpire)
+	token, err := issueSessionToken(c, user, jwtManager, tokenExpire)
 	if err != nil {
 		utils.Log.Errorf("生成Token失败: %v, user_id: %d", err, user.ID)
 		utils.InternalError(c, "登录失败: "+err.Error())
 		return
 	}
 
-	utils.Log.Infof("用户登录成功: %s, id: %d", req.Username, user.ID)
+	utils.Log.Infof("用户登录成功: %s, id: %d", user.Username, user.ID)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "登录成功",
 		"data": gin.H{
@@ -0,0 +1,451 @@
+package controllers
+
+import (
+	"errors"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 收藏列表每页条数；每个用户最多可创建的收藏夹数量
+const (
+	bookmarkPageSize       = 20
+	maxBookmarkCollections = 100
+)
+
+// withBookmarks 批量填充当前用户对文章的收藏状态，失败时只记录日志
+func withBookmarks(c *gin.Context, posts []models.Post) {
+	if err := models.FillPostBookmarks(config.DB, posts, optionalUserID(c)); err != nil {
+		utils.Log.Warnf("获取文章收藏状态失败: %v", err)
+	}
+}
+
+// withPostBookmark 填充当前用户对单篇文章的收藏状态
+func withPostBookmark(c *gin.Context, post *models.Post) {
+	posts := []models.Post{*post}
+	withBookmarks(c, posts)
+	post.Bookmarked = posts[0].Bookmarked
+}
+
+// findCollection 按ID查询用户自己的收藏夹，不存在时返回404
+func findCollection(c *gin.Context, userId uint, idStr string) (*models.BookmarkCollection, bool) {
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "收藏夹ID格式错误")
+		return nil, false
+	}
+	var collection models.BookmarkCollection
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userId).First(&collection).Error; err != nil {
+		utils.NotFound(c, "收藏夹不存在")
+		return nil, false
+	}
+	return &collection, true
+}
+
+// bookmarksQuery 用户的收藏查询，按 ?collection= 过滤：收藏夹ID，none 表示未归类，为空表示全部
+// 已删除的文章不在结果中
+func bookmarksQuery(c *gin.Context, userId uint) (*gorm.DB, *models.BookmarkCollection, bool) {
+	query := config.DB.Model(&models.Bookmark{}).
+		Where("user_id = ? AND post_id IN (?)", userId, config.DB.Model(&models.Post{}).Select("id"))
+	switch s := c.Query("collection"); s {
+	case "":
+	case "none":
+		query = query.Where("collection_id IS NULL")
+	default:
+		collection, ok := findCollection(c, userId, s)
+		if !ok {
+			return nil, nil, false
+		}
+		return query.Where("collection_id = ?", collection.ID), collection, true
+	}
+	return query, nil, true
+}
+
+// ListBookmarks 当前用户的收藏，按收藏时间倒序，?collection= 过滤，?page= 分页
+func ListBookmarks(c *gin.Context, permalink string) {
+	userId := c.GetUint("user_id")
+	query, _, ok := bookmarksQuery(c, userId)
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequest(c, "页码格式错误")
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalError(c, "获取收藏列表失败: "+err.Error())
+		return
+	}
+	var bookmarks []models.Bookmark
+	if err := query.Preload("Post.User").Preload("Post.Tags").Order("id DESC").
+		Offset((page - 1) * bookmarkPageSize).Limit(bookmarkPageSize).Find(&bookmarks).Error; err != nil {
+		utils.Log.Errorf("获取收藏列表失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "获取收藏列表失败: "+err.Error())
+		return
+	}
+	for i := range bookmarks {
+		if post := bookmarks[i].Post; post != nil {
+			post.Permalink = utils.BuildPermalink(permalink, post)
+			post.Bookmarked = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      bookmarks,
+		"total":     total,
+		"page":      page,
+		"page_size": bookmarkPageSize,
+	})
+}
+
+// AddBookmark 收藏文章；已收藏时更新收藏夹和备注（幂等）
+// collection_id 为0表示移出收藏夹，未传时保持不变；note 未传时保持不变
+func AddBookmark(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	var req struct {
+		PostID       uint    `json:"post_id" binding:"required"`
+		CollectionID *uint   `json:"collection_id"`
+		Note         *string `json:"note" binding:"omitempty,max=500"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Post{}).Where("id = ?", req.PostID).Count(&count).Error; err != nil {
+		utils.InternalError(c, "收藏失败: "+err.Error())
+		return
+	}
+	if count == 0 {
+		utils.NotFound(c, "文章不存在")
+		return
+	}
+	if req.CollectionID != nil && *req.CollectionID != 0 {
+		if _, ok := findCollection(c, userId, strconv.FormatUint(uint64(*req.CollectionID), 10)); !ok {
+			return
+		}
+	}
+
+	var bookmark models.Bookmark
+	err := config.DB.Where("user_id = ? AND post_id = ?", userId, req.PostID).First(&bookmark).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		bookmark = models.Bookmark{UserID: userId, PostID: req.PostID}
+		if req.CollectionID != nil && *req.CollectionID != 0 {
+			bookmark.CollectionID = req.CollectionID
+		}
+		if req.Note != nil {
+			bookmark.Note = *req.Note
+		}
+		err = config.DB.Create(&bookmark).Error
+	case err == nil:
+		updates := map[string]interface{}{}
+		if req.CollectionID != nil {
+			if *req.CollectionID == 0 {
+				updates["collection_id"] = nil
+			} else {
+				updates["collection_id"] = *req.CollectionID
+			}
+		}
+		if req.Note != nil {
+			updates["note"] = *req.Note
+		}
+		if len(updates) > 0 {
+			if err = config.DB.Model(&bookmark).Updates(updates).Error; err == nil {
+				err = config.DB.First(&bookmark, bookmark.ID).Error
+			}
+		}
+	}
+	if err != nil {
+		utils.Log.Errorf("收藏文章失败: %v, user_id: %d, post_id: %d", err, userId, req.PostID)
+		utils.InternalError(c, "收藏失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已收藏",
+		"data":    bookmark,
+	})
+}
+
+// RemoveBookmark 取消收藏，未收藏时不报错（幂等）
+func RemoveBookmark(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	postId, err := strconv.ParseUint(c.Param("post_id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "文章ID格式错误")
+		return
+	}
+	if err := config.DB.Where("user_id = ? AND post_id = ?", userId, postId).Delete(&models.Bookmark{}).Error; err != nil {
+		utils.Log.Errorf("取消收藏失败: %v, user_id: %d, post_id: %d", err, userId, postId)
+		utils.InternalError(c, "取消收藏失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已取消收藏",
+	})
+}
+
+// ListBookmarkCollections 当前用户的收藏夹及其中的文章数
+func ListBookmarkCollections(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	collections := []models.BookmarkCollection{}
+	if err := config.DB.Where("user_id = ?", userId).Order("name").Find(&collections).Error; err != nil {
+		utils.InternalError(c, "获取收藏夹失败: "+err.Error())
+		return
+	}
+
+	var counts []struct {
+		CollectionID *uint
+		Count        int64
+	}
+	if err := config.DB.Model(&models.Bookmark{}).Select("collection_id, COUNT(*) AS count").
+		Where("user_id = ? AND post_id IN (?)", userId, config.DB.Model(&models.Post{}).Select("id")).
+		Group("collection_id").Scan(&counts).Error; err != nil {
+		utils.InternalError(c, "获取收藏夹失败: "+err.Error())
+		return
+	}
+	byCollection := make(map[uint]int64, len(counts))
+	var uncategorized int64
+	for _, row := range counts {
+		if row.CollectionID == nil {
+			uncategorized = row.Count
+		} else {
+			byCollection[*row.CollectionID] = row.Count
+		}
+	}
+	for i := range collections {
+		collections[i].BookmarkCount = byCollection[collections[i].ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":          collections,
+		"uncategorized": uncategorized,
+	})
+}
+
+// collectionRequest 创建/修改收藏夹的参数
+type collectionRequest struct {
+	Name        string  `json:"name" binding:"max=50"`
+	Description *string `json:"description" binding:"omitempty,max=255"`
+}
+
+// CreateBookmarkCollection 创建收藏夹
+func CreateBookmarkCollection(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	var req collectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		utils.BadRequest(c, "收藏夹名称不能为空")
+		return
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.BookmarkCollection{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		utils.InternalError(c, "创建收藏夹失败: "+err.Error())
+		return
+	}
+	if count >= maxBookmarkCollections {
+		utils.BadRequest(c, "收藏夹数量已达上限")
+		return
+	}
+	if collectionNameTaken(userId, name, 0) {
+		utils.BadRequest(c, "收藏夹名称已存在")
+		return
+	}
+
+	collection := models.BookmarkCollection{UserID: userId, Name: name}
+	if req.Description != nil {
+		collection.Description = strings.TrimSpace(*req.Description)
+	}
+	if err := config.DB.Create(&collection).Error; err != nil {
+		utils.Log.Errorf("创建收藏夹失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "创建收藏夹失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "收藏夹创建成功",
+		"data":    collection,
+	})
+}
+
+// collectionNameTaken 用户是否已有同名收藏夹（excludeID 为正在修改的收藏夹）
+func collectionNameTaken(userId uint, name string, excludeID uint) bool {
+	var count int64
+	config.DB.Model(&models.BookmarkCollection{}).Where("user_id = ? AND name = ? AND id <> ?", userId, name, excludeID).Count(&count)
+	return count > 0
+}
+
+// UpdateBookmarkCollection 修改收藏夹名称和描述，未传的字段保持不变
+func UpdateBookmarkCollection(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	collection, ok := findCollection(c, userId, c.Param("id"))
+	if !ok {
+		return
+	}
+	var req collectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if name := strings.TrimSpace(req.Name); name != "" && name != collection.Name {
+		if collectionNameTaken(userId, name, collection.ID) {
+			utils.BadRequest(c, "收藏夹名称已存在")
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if len(updates) > 0 {
+		if err := config.DB.Model(collection).Updates(updates).Error; err != nil {
+			utils.Log.Errorf("修改收藏夹失败: %v, collection_id: %d", err, collection.ID)
+			utils.InternalError(c, "修改收藏夹失败: "+err.Error())
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "收藏夹已更新",
+		"data":    collection,
+	})
+}
+
+// DeleteBookmarkCollection 删除收藏夹，其中的收藏保留并变为未归类
+func DeleteBookmarkCollection(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	collection, ok := findCollection(c, userId, c.Param("id"))
+	if !ok {
+		return
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Bookmark{}).Where("collection_id = ?", collection.ID).
+			Update("collection_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(collection).Error
+	})
+	if err != nil {
+		utils.Log.Errorf("删除收藏夹失败: %v, collection_id: %d", err, collection.ID)
+		utils.InternalError(c, "删除收藏夹失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "收藏夹已删除",
+	})
+}
+
+// writeReadingListFeed 输出用户的阅读列表（Atom），?collection= 只输出指定收藏夹
+// 更新时间取文章更新和收藏时间中的最新者，收藏旧文章后条件请求也能拿到新内容
+func writeReadingListFeed(c *gin.Context, cfg *config.AppConfig, user *models.User) {
+	query, collection, ok := bookmarksQuery(c, user.ID)
+	if !ok {
+		return
+	}
+	var bookmarks []models.Bookmark
+	if err := query.Preload("Post.User").Order("id DESC").Limit(cfg.FeedItemLimit).Find(&bookmarks).Error; err != nil {
+		utils.Log.Errorf("获取阅读列表失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取阅读列表失败")
+		return
+	}
+	posts := make([]models.Post, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		if bookmark.Post != nil {
+			posts = append(posts, *bookmark.Post)
+		}
+	}
+
+	title := authorName(user) + " 的阅读列表"
+	description := ""
+	if collection != nil {
+		title = collection.Name + " - " + title
+		description = collection.Description
+	}
+	feed := newPostFeed(cfg, title+" - "+cfg.SiteTitle, description, "/", posts)
+	for _, bookmark := range bookmarks {
+		if bookmark.CreatedAt.After(feed.Updated) {
+			feed.Updated = bookmark.CreatedAt
+		}
+	}
+	c.Header("X-Robots-Tag", "noindex")
+	writeFeedCache(c, cfg, feed, "atom", "private, max-age=300")
+}
+
+// ExportReadingList 导出当前用户的阅读列表（Atom）
+func ExportReadingList(c *gin.Context, cfg *config.AppConfig) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	writeReadingListFeed(c, cfg, user)
+}
+
+// readingListFeedURL 私有阅读列表订阅源地址
+func readingListFeedURL(cfg *config.AppConfig, token string) string {
+	return cfg.SiteURL + "/reading-list/" + token + "/atom.xml"
+}
+
+// EnableReadingListFeed 开启（或重置）阅读列表私有订阅源，供不支持认证的阅读器订阅
+// 订阅地址中的令牌只保存哈希，仅在本次返回，重置后旧地址失效
+func EnableReadingListFeed(c *gin.Context, cfg *config.AppConfig) {
+	userId := c.GetUint("user_id")
+	token, err := utils.RandomToken()
+	if err != nil {
+		utils.Log.Errorf("生成阅读列表令牌失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "开启订阅源失败")
+		return
+	}
+	if err := config.DB.Model(&models.User{}).Where("id = ?", userId).
+		UpdateColumn("reading_list_feed_hash", utils.HashAPIKey(token)).Error; err != nil {
+		utils.Log.Errorf("保存阅读列表令牌失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "开启订阅源失败: "+err.Error())
+		return
+	}
+	utils.Log.Infof("阅读列表订阅源已开启: user_id: %d", userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "订阅源已开启，请妥善保存订阅地址，它只显示这一次",
+		"data": gin.H{
+			"feed_url": readingListFeedURL(cfg, token),
+		},
+	})
+}
+
+// DisableReadingListFeed 关闭阅读列表私有订阅源
+func DisableReadingListFeed(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	if err := config.DB.Model(&models.User{}).Where("id = ?", userId).
+		UpdateColumn("reading_list_feed_hash", "").Error; err != nil {
+		utils.InternalError(c, "关闭订阅源失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "订阅源已关闭",
+	})
+}
+
+// ReadingListFeed 私有阅读列表订阅源：/reading-list/:token/atom.xml，令牌即凭据
+func ReadingListFeed(c *gin.Context, cfg *config.AppConfig) {
+	token := c.Param("token")
+	var user models.User
+	if token == "" || config.DB.Where("reading_list_feed_hash = ?", utils.HashAPIKey(token)).First(&user).Error != nil {
+		utils.NotFound(c, "订阅源不存在")
+		return
+	}
+	writeReadingListFeed(c, cfg, &user)
+}
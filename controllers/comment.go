@@ -3,6 +3,7 @@ package controllers
 import (
 	"go-blog-system/config"
 	"go-blog-system/models"
+	"go-blog-system/spam"
 	"go-blog-system/utils"
 	"net/http"
 	"strconv"
@@ -10,8 +11,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// CreateComment 创建评论
-func CreateComment(c *gin.Context) {
+// CreateComment 创建评论，按文章（或站点默认）的审核方式决定是否直接公开；
+// 未通过反垃圾检查的评论进入 spam 状态，由审核人员复核
+func CreateComment(c *gin.Context, cfg *config.AppConfig, filter *spam.Pipeline) {
 	// 获取当前用户ID
 	userId, exists := c.Get("user_id")
 	if !exists {
@@ -40,9 +42,23 @@ func CreateComment(c *gin.Context) {
 		return
 	}
 
+	mode := post.EffectiveCommentMode(cfg.CommentModeration)
+	if mode == models.CommentModeClosed {
+		utils.Forbidden(c, "该文章已关闭评论")
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.Unauthorized(c, "用户不存在")
+		return
+	}
+
 	// 绑定评论内容
 	var req struct {
-		Content string `json:"content" binding:"required,min=1"`
+		Content       string `json:"content" binding:"required,min=1"`
+		ContentFormat string `json:"content_format" binding:"omitempty,oneof=markdown plain"` // 评论不支持HTML
+		Website       string `json:"website"`                                                 // 蜜罐字段，表单中对用户隐藏
+		ParentID      *uint  `json:"parent_id"`                                               // 回复的评论，须为同一文章下已公开的评论
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Log.Warnf("评论参数错误: %v, user_id: %d", err, userId)
@@ -50,26 +66,87 @@ func CreateComment(c *gin.Context) {
 		return
 	}
 
+	if req.ParentID != nil {
+		var count int64
+		if err := config.DB.Model(&models.Comment{}).Scopes(models.ApprovedComments).
+			Where("id = ? AND post_id = ?", *req.ParentID, post.ID).Count(&count).Error; err != nil {
+			utils.InternalError(c, "发表评论失败: "+err.Error())
+			return
+		}
+		if count == 0 {
+			utils.BadRequest(c, "回复的评论不存在")
+			return
+		}
+	}
+
 	// 创建评论
+	status, err := models.NewCommentStatus(config.DB, mode, &post, &user)
+	if err != nil {
+		utils.Log.Errorf("确定评论审核状态失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "发表评论失败: "+err.Error())
+		return
+	}
 	comment := models.Comment{
-		Content: req.Content,
-		UserID:  userId.(uint),
-		PostID:  uint(postId),
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		UserID:        userId.(uint),
+		PostID:        uint(postId),
+		ParentID:      req.ParentID,
+		Status:        status,
+	}
+	if !models.IsTrustedCommenter(&post, &user) {
+		result := filter.Check(&spam.Input{
+			Content:       req.Content,
+			ContentFormat: req.ContentFormat,
+			UserID:        user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			UserCreatedAt: user.CreatedAt,
+			PostID:        post.ID,
+			PostURL:       cfg.SiteURL + utils.BuildPermalink(cfg.PostPermalink, &post),
+			IP:            c.ClientIP(),
+			UserAgent:     c.Request.UserAgent(),
+			Referrer:      c.Request.Referer(),
+			Honeypot:      req.Website,
+		})
+		if result.Spam {
+			comment.Status = models.CommentSpam
+			comment.SpamReason = result.Checker + ": " + result.Reason
+		}
 	}
 	if err := config.DB.Create(&comment).Error; err != nil {
 		utils.Log.Errorf("创建评论失败: %v, user_id: %d", err, userId)
 		utils.InternalError(c, "发表评论失败: "+err.Error())
 		return
 	}
+	// 提及通知在评论公开时发送
+	if _, err := models.SyncMentions(config.DB, models.MentionSourceComment, comment.ID, comment.RenderedHTML); err != nil {
+		utils.Log.Warnf("保存评论提及失败: %v, comment_id: %d", err, comment.ID)
+	}
 
 	// 加载关联信息
 	if err := config.DB.Preload("User").Preload("Post").First(&comment, comment.ID).Error; err != nil {
 		utils.Log.Warnf("加载评论关联信息失败: %v, comment_id: %d", err, comment.ID)
 	}
 
-	utils.Log.Infof("评论创建成功: comment_id: %d, post_id: %d, user_id: %d", comment.ID, postId, userId)
+	message := "评论发表成功"
+	switch comment.Status {
+	case models.CommentSpam:
+		utils.Log.Warnf("评论被标记为垃圾评论: comment_id: %d, post_id: %d, user_id: %d, reason: %s", comment.ID, postId, userId, comment.SpamReason)
+		// 不向提交者透露判定结果，与待审核的评论返回相同内容
+		message = "评论已提交，审核通过后公开显示"
+		comment.Status = models.CommentPending
+		comment.SpamReason = ""
+	case models.CommentPending:
+		message = "评论已提交，审核通过后公开显示"
+		utils.Log.Infof("评论待审核: comment_id: %d, post_id: %d, user_id: %d, post_author: %d", comment.ID, postId, userId, post.UserID)
+		notifyCommentPending(&comment, &post)
+	default:
+		utils.Log.Infof("评论创建成功: comment_id: %d, post_id: %d, user_id: %d", comment.ID, postId, userId)
+		notifyCommentPublished(&comment, &post)
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"message": "评论发表成功",
+		"message": message,
 		"data":    comment,
 	})
 }
@@ -99,12 +176,16 @@ func GetComments(c *gin.Context) {
 
 	// 查询评论
 	var comments []models.Comment
-	if err := config.DB.Preload("User").Preload("Post").Where("post_id = ?", postId).Order("created_at DESC").Find(&comments).Error; err != nil {
+	if err := config.DB.Preload("User").Preload("Post").Scopes(models.ApprovedComments).Where("post_id = ?", postId).Order("created_at DESC").Find(&comments).Error; err != nil {
 		utils.Log.Errorf("获取评论列表失败: %v, post_id: %d", err, postId)
 		utils.InternalError(c, "获取评论列表失败: "+err.Error())
 		return
 	}
 
+	if err := models.FillCommentReactions(config.DB, comments, optionalUserID(c)); err != nil {
+		utils.Log.Warnf("获取评论回应统计失败: %v, post_id: %d", err, postId)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": comments,
 	})
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/render"
+	"go-blog-system/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/feeds"
+	"gorm.io/gorm"
+)
+
+// FeedFiles 订阅源文件名及对应格式
+var FeedFiles = map[string]string{
+	"feed.xml":  "rss",
+	"atom.xml":  "atom",
+	"feed.json": "json",
+}
+
+// 订阅源格式对应的Content-Type
+var feedContentTypes = map[string]string{
+	"rss":  "application/rss+xml; charset=utf-8",
+	"atom": "application/atom+xml; charset=utf-8",
+	"json": "application/feed+json; charset=utf-8",
+}
+
+// feedSummaryLength 条目摘要长度（字符数）
+const feedSummaryLength = 200
+
+// authorName 作者显示名，未设置显示名称时使用用户名
+func authorName(user *models.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Username
+}
+
+// feedEntryID 生成条目的永久ID（tag URI），不随slug或固定链接格式变化
+func feedEntryID(cfg *config.AppConfig, kind string, id uint, created time.Time) string {
+	host := cfg.SiteURL
+	if u, err := url.Parse(cfg.SiteURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("tag:%s,%s:%s-%d", host, created.UTC().Format("2006-01-02"), kind, id)
+}
+
+// newPostFeed 由文章列表生成订阅源，更新时间取最新的文章更新时间
+func newPostFeed(cfg *config.AppConfig, title, description, link string, posts []models.Post) *feeds.Feed {
+	feed := &feeds.Feed{
+		Title:       title,
+		Link:        &feeds.Link{Href: cfg.SiteURL + link},
+		Description: description,
+	}
+	for i := range posts {
+		post := &posts[i]
+		href := cfg.SiteURL + utils.BuildPermalink(cfg.PostPermalink, post)
+		feed.Add(&feeds.Item{
+			Title:       post.Title,
+			Link:        &feeds.Link{Href: href},
+			Author:      &feeds.Author{Name: authorName(&post.User)},
+			Description: render.Summary(post.RenderedHTML, feedSummaryLength),
+			Content:     render.AbsoluteURLs(post.RenderedHTML, cfg.SiteURL),
+			Id:          feedEntryID(cfg, "post", post.ID, post.CreatedAt),
+			IsPermaLink: "false",
+			Created:     post.CreatedAt,
+			Updated:     post.UpdatedAt,
+		})
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+	}
+	return feed
+}
+
+// latestPosts 按条件查询最新文章
+func latestPosts(cfg *config.AppConfig, query func(db *gorm.DB) *gorm.DB) ([]models.Post, error) {
+	var posts []models.Post
+	err := query(config.DB.Preload("User")).Order("created_at DESC").Limit(cfg.FeedItemLimit).Find(&posts).Error
+	return posts, err
+}
+
+// writeFeed 按格式输出公开订阅源
+func writeFeed(c *gin.Context, cfg *config.AppConfig, feed *feeds.Feed, format string) {
+	writeFeedCache(c, cfg, feed, format, "public, max-age=300")
+}
+
+// writeFeedCache 按格式输出订阅源并指定缓存策略，支持 If-None-Match / If-Modified-Since 条件请求
+func writeFeedCache(c *gin.Context, cfg *config.AppConfig, feed *feeds.Feed, format, cacheControl string) {
+	self := cfg.SiteURL + c.Request.URL.Path
+	var body []byte
+	var err error
+	switch format {
+	case "atom":
+		var s string
+		atom := (&feeds.Atom{Feed: feed}).AtomFeed()
+		atom.Id = self
+		s, err = feeds.ToXML(atom)
+		body = []byte(s)
+	case "json":
+		jsonFeed := (&feeds.JSON{Feed: feed}).JSONFeed()
+		jsonFeed.FeedUrl = self
+		body, err = json.MarshalIndent(jsonFeed, "", "  ")
+	default:
+		var s string
+		s, err = feeds.ToXML(&feeds.Rss{Feed: feed})
+		body = []byte(s)
+	}
+	if err != nil {
+		utils.Log.Errorf("生成订阅源失败: %v, path: %s", err, c.Request.URL.Path)
+		utils.InternalError(c, "生成订阅源失败")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+	if !feed.Updated.IsZero() {
+		c.Header("Last-Modified", feed.Updated.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c, etag, feed.Updated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, feedContentTypes[format], body)
+}
+
+// notModified 判断条件请求是否命中缓存，If-None-Match 优先于 If-Modified-Since
+func notModified(c *gin.Context, etag string, updated time.Time) bool {
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+	return err == nil && !updated.IsZero() && !updated.Truncate(time.Second).After(since)
+}
+
+// SiteFeed 全站文章订阅源：/feed.xml、/atom.xml、/feed.json
+func SiteFeed(c *gin.Context, cfg *config.AppConfig, format string) {
+	posts, err := latestPosts(cfg, func(db *gorm.DB) *gorm.DB { return db })
+	if err != nil {
+		utils.Log.Errorf("获取订阅源文章失败: %v", err)
+		utils.InternalError(c, "获取文章失败")
+		return
+	}
+	writeFeed(c, cfg, newPostFeed(cfg, cfg.SiteTitle, cfg.SiteDescription, "/", posts), format)
+}
+
+// AuthorFeed 作者文章订阅源：/authors/:username/feed.xml 等
+func AuthorFeed(c *gin.Context, cfg *config.AppConfig, format string) {
+	var user models.User
+	if err := config.DB.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	posts, err := latestPosts(cfg, func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", user.ID) })
+	if err != nil {
+		utils.Log.Errorf("获取作者订阅源文章失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取文章失败")
+		return
+	}
+	name := authorName(&user)
+	feed := newPostFeed(cfg, name+" - "+cfg.SiteTitle, user.Bio, "/authors/"+url.PathEscape(user.Username), posts)
+	feed.Author = &feeds.Author{Name: name}
+	writeFeed(c, cfg, feed, format)
+}
+
+// TagFeed 标签文章订阅源：/tags/:slug/feed.xml 等
+func TagFeed(c *gin.Context, cfg *config.AppConfig, format string) {
+	var tag models.Tag
+	if err := config.DB.Where("slug = ?", c.Param("slug")).First(&tag).Error; err != nil {
+		utils.NotFound(c, "标签不存在")
+		return
+	}
+	posts, err := latestPosts(cfg, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (?)", config.DB.Table("post_tags").Select("post_id").Where("tag_id = ?", tag.ID))
+	})
+	if err != nil {
+		utils.Log.Errorf("获取标签订阅源文章失败: %v, tag_id: %d", err, tag.ID)
+		utils.InternalError(c, "获取文章失败")
+		return
+	}
+	link := "/tags/" + url.PathEscape(tag.Slug)
+	writeFeed(c, cfg, newPostFeed(cfg, tag.Name+" - "+cfg.SiteTitle, "标签“"+tag.Name+"”下的文章", link, posts), format)
+}
+
+// PostCommentsFeed 单篇文章的评论订阅源：/posts/:id/comments/feed.xml 等
+func PostCommentsFeed(c *gin.Context, cfg *config.AppConfig, format string) {
+	var post models.Post
+	query := config.DB.Preload("User")
+	if id, err := strconv.ParseUint(c.Param("id"), 10, 32); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("slug = ?", c.Param("id"))
+	}
+	if err := query.First(&post).Error; err != nil {
+		utils.NotFound(c, "文章不存在")
+		return
+	}
+
+	var comments []models.Comment
+	if err := config.DB.Preload("User").Scopes(models.ApprovedComments).Where("post_id = ?", post.ID).
+		Order("created_at DESC").Limit(cfg.FeedItemLimit).Find(&comments).Error; err != nil {
+		utils.Log.Errorf("获取评论订阅源失败: %v, post_id: %d", err, post.ID)
+		utils.InternalError(c, "获取评论失败")
+		return
+	}
+
+	permalink := cfg.SiteURL + utils.BuildPermalink(cfg.PostPermalink, &post)
+	feed := &feeds.Feed{
+		Title:       "《" + post.Title + "》的评论",
+		Link:        &feeds.Link{Href: permalink},
+		Description: render.Summary(post.RenderedHTML, feedSummaryLength),
+		Updated:     post.UpdatedAt,
+	}
+	for i := range comments {
+		comment := &comments[i]
+		name := authorName(&comment.User)
+		feed.Add(&feeds.Item{
+			Title:       name + " 评论了《" + post.Title + "》",
+			Link:        &feeds.Link{Href: fmt.Sprintf("%s#comment-%d", permalink, comment.ID)},
+			Author:      &feeds.Author{Name: name},
+			Description: render.Summary(comment.RenderedHTML, feedSummaryLength),
+			Content:     render.AbsoluteURLs(comment.RenderedHTML, cfg.SiteURL),
+			Id:          feedEntryID(cfg, "comment", comment.ID, comment.CreatedAt),
+			IsPermaLink: "false",
+			Created:     comment.CreatedAt,
+			Updated:     comment.UpdatedAt,
+		})
+		if comment.UpdatedAt.After(feed.Updated) {
+			feed.Updated = comment.UpdatedAt
+		}
+	}
+	writeFeed(c, cfg, feed, format)
+}
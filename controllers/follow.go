@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// 粉丝/关注列表每页条数；关注动态默认和最大每页条数
+const (
+	followPageSize  = 50
+	feedPageSize    = 20
+	feedMaxPageSize = 100
+)
+
+// withFollowCounts 在资料中加入粉丝数和关注数，失败时只记录日志
+func withFollowCounts(data gin.H, userID uint) {
+	followers, following, err := models.FollowCounts(config.DB, userID)
+	if err != nil {
+		utils.Log.Warnf("统计粉丝数失败: %v, user_id: %d", err, userID)
+	}
+	data["followers_count"] = followers
+	data["following_count"] = following
+}
+
+// findFollowee 按 :username 查询被关注的作者
+func findFollowee(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := config.DB.Select("id", "username").Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return nil, false
+	}
+	return &user, true
+}
+
+// respondFollowState 返回关注操作后的关注状态和粉丝数
+func respondFollowState(c *gin.Context, followee *models.User, following bool, message string) {
+	followers, _, err := models.FollowCounts(config.DB, followee.ID)
+	if err != nil {
+		utils.InternalError(c, "获取粉丝数失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data": gin.H{
+			"following":       following,
+			"followers_count": followers,
+		},
+	})
+}
+
+// FollowUser 关注作者，重复关注不报错（幂等）
+func FollowUser(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	followee, ok := findFollowee(c)
+	if !ok {
+		return
+	}
+	if followee.ID == userId {
+		utils.BadRequest(c, "不能关注自己")
+		return
+	}
+
+	follow := models.Follow{FollowerID: userId, FolloweeID: followee.ID}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	if result.Error != nil {
+		utils.Log.Errorf("关注失败: %v, user_id: %d, followee_id: %d", result.Error, userId, followee.ID)
+		utils.InternalError(c, "关注失败: "+result.Error.Error())
+		return
+	}
+	if result.RowsAffected > 0 {
+		notify(models.NotificationEvent{Type: models.NotifyFollow, UserID: followee.ID, ActorID: userId})
+	}
+	respondFollowState(c, followee, true, "已关注")
+}
+
+// UnfollowUser 取消关注，未关注时不报错（幂等）
+func UnfollowUser(c *gin.Context) {
+	userId := c.GetUint("user_id")
+	followee, ok := findFollowee(c)
+	if !ok {
+		return
+	}
+	if err := config.DB.Where("follower_id = ? AND followee_id = ?", userId, followee.ID).Delete(&models.Follow{}).Error; err != nil {
+		utils.Log.Errorf("取消关注失败: %v, user_id: %d, followee_id: %d", err, userId, followee.ID)
+		utils.InternalError(c, "取消关注失败: "+err.Error())
+		return
+	}
+	respondFollowState(c, followee, false, "已取消关注")
+}
+
+// ListFollows 用户的粉丝（followers=true）或关注的作者列表，按关注时间倒序，?page= 分页
+func ListFollows(c *gin.Context, followers bool) {
+	user, ok := findFollowee(c)
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequest(c, "页码格式错误")
+		return
+	}
+
+	// 列出关系另一端的用户
+	column, other := "followee_id", "follower_id"
+	if !followers {
+		column, other = "follower_id", "followee_id"
+	}
+	query := config.DB.Model(&models.Follow{}).Where(column+" = ?", user.ID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalError(c, "获取列表失败: "+err.Error())
+		return
+	}
+	var rows []struct {
+		UserID      uint      `json:"id"`
+		Username    string    `json:"username"`
+		DisplayName string    `json:"display_name"`
+		FollowedAt  time.Time `json:"followed_at"`
+	}
+	if err := query.Select("users.id AS user_id, users.username, users.display_name, follows.created_at AS followed_at").
+		Joins("JOIN users ON users.id = follows." + other + " AND users.deleted_at IS NULL").
+		Order("follows.id DESC").Offset((page - 1) * followPageSize).Limit(followPageSize).
+		Scan(&rows).Error; err != nil {
+		utils.Log.Errorf("获取关注列表失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取列表失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      rows,
+		"total":     total,
+		"page":      page,
+		"page_size": followPageSize,
+	})
+}
+
+// FollowFeed 关注动态：已关注作者的文章，按发布时间倒序
+// 使用游标分页（?cursor= 为上一页返回的 next_cursor，?limit= 每页条数），翻页期间有新文章发布也不会重复或遗漏；
+// 关注列表以子查询传入（不受SQL参数个数限制），查询走 posts(user_id, deleted_at, created_at, id) 联合索引，
+// 只读取已关注作者早于游标的文章，关注数量较多时也无需扫描全表
+func FollowFeed(c *gin.Context, permalink string) {
+	userId := c.GetUint("user_id")
+	limit := feedPageSize
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > feedMaxPageSize {
+			utils.BadRequest(c, "limit 应为 1-"+strconv.Itoa(feedMaxPageSize))
+			return
+		}
+		limit = n
+	}
+
+	query := config.DB.Preload("User").Preload("Tags").
+		Where("user_id IN (?)", config.DB.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", userId))
+	if cursor := c.Query("cursor"); cursor != "" {
+		createdAt, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			utils.BadRequest(c, "分页游标无效")
+			return
+		}
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, id)
+	}
+
+	// 多取一条判断是否还有下一页
+	var posts []models.Post
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&posts).Error; err != nil {
+		utils.Log.Errorf("获取关注动态失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "获取关注动态失败: "+err.Error())
+		return
+	}
+	nextCursor := ""
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[limit-1]
+		nextCursor = utils.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	withPermalinks(permalink, posts)
+	withReactions(c, posts)
+	withBookmarks(c, posts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        posts,
+		"next_cursor": nextCursor,
+	})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/importer"
+	"go-blog-system/jobs"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportContent 管理员导入内容（multipart）：
+// file 为 WordPress 导出的 WXR 文件（.xml）或内容目录的 zip 压缩包（Markdown/Hugo/Jekyll）
+// 可选字段：format（wxr/markdown/hugo/jekyll，默认自动识别）、dry_run（仅返回报告）、source（来源标识）、
+// fetch_remote（下载原站点图片）、default_author（缺少作者时使用的用户名，默认为当前管理员）、
+// author_map（JSON对象，来源作者登录名或邮箱 → 本地用户名）
+func ImportContent(c *gin.Context, cfg *config.AppConfig, store storage.Storage, pipeline *jobs.ImagePipeline) {
+	adminId, _ := c.Get("user_id")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.ImportMaxSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, fmt.Sprintf("缺少文件（file）或文件超过 %dMB", cfg.ImportMaxSize>>20))
+		return
+	}
+	if fileHeader.Size > cfg.ImportMaxSize {
+		utils.BadRequest(c, fmt.Sprintf("文件不能超过 %dMB", cfg.ImportMaxSize>>20))
+		return
+	}
+
+	format := c.PostForm("format")
+	if format != "" && format != importer.FormatWXR && format != importer.FormatMarkdown &&
+		format != importer.FormatHugo && format != importer.FormatJekyll {
+		utils.BadRequest(c, "format 仅支持 wxr/markdown/hugo/jekyll")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+	fetchRemote, _ := strconv.ParseBool(c.DefaultPostForm("fetch_remote", "false"))
+
+	opts := importer.Options{
+		Source:       strings.TrimSpace(c.PostForm("source")),
+		DryRun:       dryRun,
+		FetchRemote:  fetchRemote,
+		FetchTimeout: cfg.ImportFetchTimeout,
+		MediaMaxSize: cfg.MediaMaxSize,
+		Store:        store,
+		OnMedia:      pipeline.Enqueue,
+	}
+	if s := c.PostForm("author_map"); s != "" {
+		if err := json.Unmarshal([]byte(s), &opts.AuthorMap); err != nil {
+			utils.BadRequest(c, "author_map 格式错误，应为JSON对象")
+			return
+		}
+	}
+	var author models.User
+	if username := c.PostForm("default_author"); username != "" {
+		if err := config.DB.Where("username = ?", username).First(&author).Error; err != nil {
+			utils.BadRequest(c, "默认作者不存在: "+username)
+			return
+		}
+	} else if err := config.DB.First(&author, adminId).Error; err != nil {
+		utils.Unauthorized(c, "用户不存在")
+		return
+	}
+	opts.DefaultAuthor = &author
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.InternalError(c, "读取文件失败")
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(path.Ext(fileHeader.Filename))
+	var site *importer.Site
+	if format == importer.FormatWXR || (format == "" && ext == ".xml") {
+		site, err = importer.LoadWXR(file)
+	} else {
+		fsys, zipErr := importer.OpenZip(file, fileHeader.Size)
+		if zipErr != nil {
+			utils.BadRequest(c, "请上传WordPress导出的 .xml 文件或内容目录的 .zip 压缩包")
+			return
+		}
+		site, err = importer.LoadDir(fsys, format, strings.TrimSuffix(path.Base(fileHeader.Filename), path.Ext(fileHeader.Filename)))
+	}
+	if err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	report, err := importer.Run(site, opts)
+	if err != nil {
+		utils.Log.Errorf("内容导入失败: %v, admin_id: %d", err, adminId)
+		utils.InternalError(c, "内容导入失败: "+err.Error())
+		return
+	}
+
+	message := "导入完成"
+	if dryRun {
+		message = "试运行完成，未写入任何数据"
+	}
+	utils.Log.Infof("管理员导入内容: admin_id: %d, format: %s, source: %s, dry_run: %v", adminId, report.Format, report.Source, dryRun)
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    report,
+	})
+}
@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"go-blog-system/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 公开JWT验证公钥（JSON Web Key Set）
+func JWKS(c *gin.Context, jwtManager *utils.JWTManager) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{
+		"keys": jwtManager.JWKS(),
+	})
+}
@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/jobs"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mediaData 组装媒体响应，私有文件不返回公开地址
+func mediaData(store storage.Storage, media *models.Media) gin.H {
+	data := gin.H{
+		"id":           media.ID,
+		"filename":     media.Filename,
+		"content_type": media.ContentType,
+		"size":         media.Size,
+		"sha256":       media.SHA256,
+		"visibility":   media.Visibility,
+		"post_id":      media.PostID,
+		"created_at":   media.CreatedAt,
+	}
+	public := media.Visibility == models.MediaPublic
+	if public {
+		data["url"] = store.URL(media.Key)
+	}
+	if media.IsImage() {
+		data["processing_status"] = media.ProcessingStatus
+		data["width"] = media.Width
+		data["height"] = media.Height
+		data["blurhash"] = media.BlurHash
+		variants := make([]gin.H, 0)
+		for _, v := range media.VariantList() {
+			item := gin.H{"width": v.Width, "height": v.Height, "format": v.Format, "size": v.Size}
+			if public {
+				item["url"] = store.URL(v.Key)
+			}
+			variants = append(variants, item)
+		}
+		data["variants"] = variants
+	}
+	return data
+}
+
+// ownPost 校验文章存在且属于当前用户
+func ownPost(postID, userID interface{}) (bool, error) {
+	var count int64
+	err := config.DB.Model(&models.Post{}).Where("id = ? AND user_id = ?", postID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// UploadMedia 上传媒体文件（multipart字段 file），可选 visibility、post_id
+// 图片在保存前去除EXIF等元数据，衍生尺寸由后台任务生成
+func UploadMedia(c *gin.Context, store storage.Storage, pipeline *jobs.ImagePipeline, maxSize int64) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	// 限制整个请求体大小，避免超大文件先被完整写入临时目录
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, fmt.Sprintf("缺少文件（file）或文件超过 %dMB", maxSize>>20))
+		return
+	}
+	if fileHeader.Size > maxSize {
+		utils.BadRequest(c, fmt.Sprintf("文件不能超过 %dMB", maxSize>>20))
+		return
+	}
+
+	visibility := c.DefaultPostForm("visibility", models.MediaPublic)
+	if visibility != models.MediaPublic && visibility != models.MediaPrivate {
+		utils.BadRequest(c, "visibility 仅支持 public/private")
+		return
+	}
+
+	var postID *uint
+	if s := c.PostForm("post_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "文章ID格式错误")
+			return
+		}
+		owned, err := ownPost(id, userId)
+		if err != nil {
+			utils.InternalError(c, "查询文章失败: "+err.Error())
+			return
+		}
+		if !owned {
+			utils.NotFound(c, "文章不存在或无权限")
+			return
+		}
+		pid := uint(id)
+		postID = &pid
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.InternalError(c, "读取文件失败")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil || int64(len(data)) > maxSize {
+		utils.BadRequest(c, "读取文件失败")
+		return
+	}
+
+	contentType, ext, err := utils.SniffMediaType(data)
+	if err != nil {
+		utils.Log.Warnf("媒体类型不支持: %v, user_id: %d, filename: %s", err, userId, fileHeader.Filename)
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	// 去除定位等隐私信息，内容哈希按清理后的文件计算
+	if data, err = utils.StripImageMetadata(data, contentType); err != nil {
+		utils.BadRequest(c, err.Error())
+		return
+	}
+	key, sum := utils.MediaKey(data, ext, visibility)
+
+	// 相同内容已存储过则直接复用
+	var existing int64
+	if err := config.DB.Model(&models.Media{}).Where("storage_key = ?", key).Count(&existing).Error; err != nil {
+		utils.Log.Errorf("查询媒体记录失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "保存文件失败: "+err.Error())
+		return
+	}
+	if existing == 0 {
+		if err := store.Save(key, bytes.NewReader(data), contentType); err != nil {
+			utils.Log.Errorf("保存媒体文件失败: %v, user_id: %d", err, userId)
+			utils.InternalError(c, "保存文件失败")
+			return
+		}
+	}
+
+	media := models.Media{
+		UserID:      userId.(uint),
+		PostID:      postID,
+		Key:         key,
+		Filename:    utils.SanitizeFilename(fileHeader.Filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      sum,
+		Visibility:  visibility,
+	}
+	if media.IsImage() {
+		media.ProcessingStatus = models.MediaProcessingPending
+	}
+	if err := config.DB.Create(&media).Error; err != nil {
+		utils.Log.Errorf("保存媒体记录失败: %v, user_id: %d", err, userId)
+		if existing == 0 {
+			utils.DeleteUnreferencedMedia(store, media)
+		}
+		utils.InternalError(c, "保存媒体记录失败: "+err.Error())
+		return
+	}
+	if media.IsImage() {
+		pipeline.Enqueue(media.ID)
+	}
+
+	utils.Log.Infof("媒体上传成功: media_id: %d, user_id: %d, type: %s, size: %d", media.ID, userId, contentType, media.Size)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "上传成功",
+		"data":    mediaData(store, &media),
+	})
+}
+
+// ListMedia 获取当前用户的媒体库，可按 ?post_id 过滤
+func ListMedia(c *gin.Context, store storage.Storage) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	query := config.DB.Where("user_id = ?", userId)
+	if s := c.Query("post_id"); s != "" {
+		postId, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "文章ID格式错误")
+			return
+		}
+		query = query.Where("post_id = ?", postId)
+	}
+
+	var items []models.Media
+	if err := query.Order("created_at DESC").Find(&items).Error; err != nil {
+		utils.Log.Errorf("获取媒体列表失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "获取媒体列表失败: "+err.Error())
+		return
+	}
+
+	data := make([]gin.H, 0, len(items))
+	for i := range items {
+		data = append(data, mediaData(store, &items[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
+
+// findOwnMedia 查询当前用户的媒体记录
+func findOwnMedia(c *gin.Context) (*models.Media, bool) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return nil, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "媒体ID格式错误")
+		return nil, false
+	}
+
+	var media models.Media
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userId).First(&media).Error; err != nil {
+		utils.NotFound(c, "媒体不存在")
+		return nil, false
+	}
+	return &media, true
+}
+
+// UpdateMedia 关联/取消关联文章（post_id 为0表示取消关联）
+func UpdateMedia(c *gin.Context, store storage.Storage) {
+	media, ok := findOwnMedia(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PostID *uint `json:"post_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if *req.PostID == 0 {
+		media.PostID = nil
+	} else {
+		owned, err := ownPost(*req.PostID, media.UserID)
+		if err != nil {
+			utils.InternalError(c, "查询文章失败: "+err.Error())
+			return
+		}
+		if !owned {
+			utils.NotFound(c, "文章不存在或无权限")
+			return
+		}
+		media.PostID = req.PostID
+	}
+
+	if err := config.DB.Model(media).Update("post_id", media.PostID).Error; err != nil {
+		utils.Log.Errorf("更新媒体失败: %v, media_id: %d", err, media.ID)
+		utils.InternalError(c, "更新媒体失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "更新成功",
+		"data":    mediaData(store, media),
+	})
+}
+
+// MediaDownloadURL 生成媒体的签名下载地址（私有文件唯一的访问方式）
+func MediaDownloadURL(c *gin.Context, store storage.Storage, ttl time.Duration) {
+	media, ok := findOwnMedia(c)
+	if !ok {
+		return
+	}
+
+	signed, err := store.SignedURL(media.Key, ttl)
+	if err != nil {
+		utils.Log.Errorf("生成签名地址失败: %v, media_id: %d", err, media.ID)
+		utils.InternalError(c, "生成下载地址失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"url":        signed,
+			"expires_at": time.Now().Add(ttl),
+		},
+	})
+}
+
+// DeleteMedia 删除媒体记录，文件不再被引用时一并删除
+func DeleteMedia(c *gin.Context, store storage.Storage) {
+	media, ok := findOwnMedia(c)
+	if !ok {
+		return
+	}
+
+	// 直接物理删除，引用计数以现存记录为准
+	if err := config.DB.Unscoped().Delete(media).Error; err != nil {
+		utils.Log.Errorf("删除媒体失败: %v, media_id: %d", err, media.ID)
+		utils.InternalError(c, "删除媒体失败: "+err.Error())
+		return
+	}
+	utils.DeleteUnreferencedMedia(store, *media)
+
+	utils.Log.Infof("媒体删除成功: media_id: %d, user_id: %d", media.ID, media.UserID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "删除成功",
+	})
+}
+
+// ResizedImage 公开图片的动态缩放：GET /api/media/:id/image?w=640&format=webp
+// 结果缓存在磁盘，同一宽度只处理一次
+func ResizedImage(c *gin.Context, pipeline *jobs.ImagePipeline) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "媒体ID格式错误")
+		return
+	}
+	var media models.Media
+	if err := config.DB.Where("id = ? AND visibility = ?", id, models.MediaPublic).First(&media).Error; err != nil || !media.IsImage() {
+		utils.NotFound(c, "图片不存在")
+		return
+	}
+	if media.ProcessingStatus != models.MediaProcessingReady {
+		utils.Error(c, http.StatusConflict, "图片尚未处理完成")
+		return
+	}
+
+	width, err := strconv.Atoi(c.Query("w"))
+	if err != nil || width <= 0 {
+		utils.BadRequest(c, "缩放宽度（w）必须为正整数")
+		return
+	}
+	format := c.DefaultQuery("format", pipeline.ResizeFormat(&media))
+	if format != "jpeg" && format != "png" && format != "webp" {
+		utils.BadRequest(c, "format 仅支持 jpeg/png/webp")
+		return
+	}
+
+	file, err := pipeline.Resize(&media, width, format)
+	if errors.Is(err, jobs.ErrImageBusy) {
+		c.Header("Retry-After", "5")
+		utils.Error(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		utils.Log.Errorf("图片缩放失败: %v, media_id: %d", err, media.ID)
+		utils.InternalError(c, "图片缩放失败")
+		return
+	}
+	// 媒体内容不可修改，缩放结果可长期缓存
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(file)
+}
+
+// ServeUpload 本地存储的文件访问：公开文件直接返回，私有文件需校验签名
+func ServeUpload(c *gin.Context, store *storage.LocalStorage) {
+	key := c.Param("filepath")
+	if storage.IsPrivateKey(key) {
+		if !store.VerifySignature(key, c.Query("expires"), c.Query("signature")) {
+			utils.Forbidden(c, "下载地址无效或已过期")
+			return
+		}
+		c.Header("Cache-Control", "private, no-store")
+	}
+
+	p, err := store.FilePath(key)
+	if err != nil {
+		utils.NotFound(c, "文件不存在")
+		return
+	}
+	if info, err := os.Stat(p); err != nil || info.IsDir() {
+		utils.NotFound(c, "文件不存在")
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(p)
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用户名补全默认和最多返回的条数
+const (
+	userSearchLimit    = 10
+	userSearchMaxLimit = 20
+)
+
+// SearchUsers 用户名补全（编辑器输入@时使用）：按用户名或显示名前缀匹配，?q= 为输入内容（可带@），?limit= 返回条数
+func SearchUsers(c *gin.Context, store storage.Storage) {
+	q := strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "@")
+	if q == "" {
+		utils.BadRequest(c, "请输入要搜索的用户名")
+		return
+	}
+	if runes := []rune(q); len(runes) > 50 {
+		q = string(runes[:50])
+	}
+	limit := userSearchLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > userSearchMaxLimit {
+			utils.BadRequest(c, "limit 应为 1-"+strconv.Itoa(userSearchMaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	pattern := likeEscaper.Replace(q) + "%"
+	var users []models.User
+	if err := config.DB.Select("id", "username", "display_name", "avatar").
+		Where(`username LIKE ? ESCAPE '\' OR display_name LIKE ? ESCAPE '\'`, pattern, pattern).
+		Order("username").Limit(limit).Find(&users).Error; err != nil {
+		utils.Log.Errorf("搜索用户失败: %v, q: %s", err, q)
+		utils.InternalError(c, "搜索用户失败: "+err.Error())
+		return
+	}
+
+	data := make([]gin.H, 0, len(users))
+	for i := range users {
+		data = append(data, gin.H{
+			"id":           users[i].ID,
+			"username":     users[i].Username,
+			"display_name": users[i].DisplayName,
+			"avatar":       avatarURLs(store, &users[i]),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+	})
+}
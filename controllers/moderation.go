@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 审核队列每页条数
+const moderationPageSize = 50
+
+// moderatableComments 当前用户可审核的评论：编辑/管理员可审核全部评论，其他用户只能审核自己文章下的评论
+func moderatableComments(user *models.User) *gorm.DB {
+	query := config.DB.Model(&models.Comment{})
+	if !user.IsPrivileged() {
+		query = query.Where("post_id IN (?)", config.DB.Model(&models.Post{}).Select("id").Where("user_id = ?", user.ID))
+	}
+	return query
+}
+
+// PendingCommentCount 当前用户待审核的评论数，个人信息中返回用于提醒作者
+func PendingCommentCount(user *models.User) int64 {
+	var count int64
+	if err := moderatableComments(user).Where("status = ?", models.CommentPending).Count(&count).Error; err != nil {
+		utils.Log.Warnf("统计待审核评论失败: %v, user_id: %d", err, user.ID)
+	}
+	return count
+}
+
+// currentUser 读取当前登录用户
+func currentUser(c *gin.Context) (*models.User, bool) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return nil, false
+	}
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.Unauthorized(c, "用户不存在")
+		return nil, false
+	}
+	return &user, true
+}
+
+// ListModerationComments 审核队列：?status=pending（默认）/approved/rejected/spam/all，可按 post_id 过滤，?page= 分页
+func ListModerationComments(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	query := moderatableComments(user)
+	switch status := c.DefaultQuery("status", models.CommentPending); status {
+	case "all":
+	case models.CommentPending, models.CommentApproved, models.CommentRejected, models.CommentSpam:
+		query = query.Where("status = ?", status)
+	default:
+		utils.BadRequest(c, "status 仅支持 pending/approved/rejected/spam/all")
+		return
+	}
+	if s := c.Query("post_id"); s != "" {
+		postId, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			utils.BadRequest(c, "文章ID格式错误")
+			return
+		}
+		query = query.Where("post_id = ?", postId)
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequest(c, "页码格式错误")
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.Log.Errorf("获取审核队列失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取审核队列失败: "+err.Error())
+		return
+	}
+	var comments []models.Comment
+	if err := query.Preload("User").Preload("Post", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "slug", "user_id", "created_at")
+	}).Order("created_at ASC").Offset((page - 1) * moderationPageSize).Limit(moderationPageSize).
+		Find(&comments).Error; err != nil {
+		utils.Log.Errorf("获取审核队列失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取审核队列失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      comments,
+		"total":     total,
+		"page":      page,
+		"page_size": moderationPageSize,
+	})
+}
+
+// ModerateComments 批量审核评论：action 为 approve（公开）/reject（拒绝）/spam（标记为垃圾评论）
+// 无权审核或不存在的评论不做处理，在 skipped 中返回
+func ModerateComments(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		IDs    []uint `json:"ids" binding:"required,min=1,max=100"`
+		Action string `json:"action" binding:"required,oneof=approve reject spam"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	status := map[string]string{
+		"approve": models.CommentApproved,
+		"reject":  models.CommentRejected,
+		"spam":    models.CommentSpam,
+	}[req.Action]
+
+	var ids []uint
+	if err := moderatableComments(user).Where("id IN ?", req.IDs).Pluck("id", &ids).Error; err != nil {
+		utils.Log.Errorf("审核评论失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "审核评论失败: "+err.Error())
+		return
+	}
+	// 由未公开变为公开的评论，审核通过后通知文章作者和被回复者
+	var published []models.Comment
+	if status == models.CommentApproved && len(ids) > 0 {
+		if err := config.DB.Select("id", "user_id", "post_id", "parent_id").
+			Where("id IN ? AND status <> ?", ids, models.CommentApproved).Find(&published).Error; err != nil {
+			utils.Log.Errorf("审核评论失败: %v, user_id: %d", err, user.ID)
+			utils.InternalError(c, "审核评论失败: "+err.Error())
+			return
+		}
+	}
+	if len(ids) > 0 {
+		now := time.Now()
+		// 只修改审核字段，不触发重新渲染
+		if err := config.DB.Model(&models.Comment{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+			"status":       status,
+			"moderated_by": user.ID,
+			"moderated_at": now,
+			"updated_at":   now,
+		}).Error; err != nil {
+			utils.Log.Errorf("审核评论失败: %v, user_id: %d", err, user.ID)
+			utils.InternalError(c, "审核评论失败: "+err.Error())
+			return
+		}
+	}
+
+	for i := range published {
+		var post models.Post
+		if err := config.DB.Select("id", "user_id").First(&post, published[i].PostID).Error; err == nil {
+			notifyCommentPublished(&published[i], &post)
+		}
+	}
+
+	found := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		found[id] = true
+	}
+	skipped := []uint{}
+	for _, id := range req.IDs {
+		if !found[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	utils.Log.Infof("评论审核: action: %s, count: %d, user_id: %d", req.Action, len(ids), user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "审核完成",
+		"data": gin.H{
+			"status":  status,
+			"updated": ids,
+			"skipped": skipped,
+		},
+	})
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 通知列表每页条数
+const notificationPageSize = 30
+
+// notify 生成通知，失败时只记录日志，不影响触发通知的操作
+func notify(e models.NotificationEvent) {
+	if err := models.Notify(config.DB, e); err != nil {
+		utils.Log.Warnf("生成通知失败: %v, type: %s, user_id: %d", err, e.Type, e.UserID)
+	}
+}
+
+// notifyPostMentions 通知文章中新提及的用户
+func notifyPostMentions(post *models.Post, userIDs []uint) {
+	for _, id := range userIDs {
+		notify(models.NotificationEvent{
+			Type:    models.NotifyMention,
+			UserID:  id,
+			ActorID: post.UserID,
+			Target:  "post:" + strconv.FormatUint(uint64(post.ID), 10),
+			PostID:  post.ID,
+		})
+	}
+}
+
+// notifyCommentPublished 评论公开后通知文章作者、被回复的评论作者和评论中提及的用户；
+// 同一用户只发送一条，优先级为回复、评论、提及
+func notifyCommentPublished(comment *models.Comment, post *models.Post) {
+	var replyTo uint
+	if comment.ParentID != nil {
+		var parent models.Comment
+		if err := config.DB.Select("id", "user_id").First(&parent, *comment.ParentID).Error; err == nil {
+			replyTo = parent.UserID
+			notify(models.NotificationEvent{
+				Type:      models.NotifyReply,
+				UserID:    parent.UserID,
+				ActorID:   comment.UserID,
+				Target:    "comment:" + strconv.FormatUint(uint64(parent.ID), 10),
+				PostID:    post.ID,
+				CommentID: comment.ID,
+			})
+		}
+	}
+	if post.UserID != replyTo {
+		notify(models.NotificationEvent{
+			Type:      models.NotifyComment,
+			UserID:    post.UserID,
+			ActorID:   comment.UserID,
+			Target:    "post:" + strconv.FormatUint(uint64(post.ID), 10),
+			PostID:    post.ID,
+			CommentID: comment.ID,
+		})
+	}
+
+	mentioned, err := models.MentionedUserIDs(config.DB, models.MentionSourceComment, comment.ID)
+	if err != nil {
+		utils.Log.Warnf("获取评论提及失败: %v, comment_id: %d", err, comment.ID)
+		return
+	}
+	for _, id := range mentioned {
+		if id == replyTo || id == post.UserID {
+			continue
+		}
+		notify(models.NotificationEvent{
+			Type:      models.NotifyMention,
+			UserID:    id,
+			ActorID:   comment.UserID,
+			Target:    "comment:" + strconv.FormatUint(uint64(comment.ID), 10),
+			PostID:    post.ID,
+			CommentID: comment.ID,
+		})
+	}
+}
+
+// notifyCommentPending 评论进入审核队列时提醒文章作者；同一文章的待审核评论在未读期间合并为一条
+func notifyCommentPending(comment *models.Comment, post *models.Post) {
+	notify(models.NotificationEvent{
+		Type:      models.NotifyPending,
+		UserID:    post.UserID,
+		ActorID:   comment.UserID,
+		Target:    "post:" + strconv.FormatUint(uint64(post.ID), 10),
+		PostID:    post.ID,
+		CommentID: comment.ID,
+	})
+}
+
+// unreadNotificationCount 用户的未读通知数
+func unreadNotificationCount(userID uint) (int64, error) {
+	var count int64
+	err := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// ListNotifications 当前用户的通知，按最近事件时间倒序，?unread=true 只返回未读，?page= 分页
+func ListNotifications(c *gin.Context, permalink string) {
+	userId := c.GetUint("user_id")
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequest(c, "页码格式错误")
+		return
+	}
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", userId)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalError(c, "获取通知失败: "+err.Error())
+		return
+	}
+	notifications := []models.Notification{}
+	if err := query.Preload("Actor", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "display_name", "role", "created_at")
+	}).Preload("Post", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "slug", "user_id", "created_at")
+	}).Order("updated_at DESC, id DESC").Offset((page - 1) * notificationPageSize).Limit(notificationPageSize).
+		Find(&notifications).Error; err != nil {
+		utils.Log.Errorf("获取通知失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "获取通知失败: "+err.Error())
+		return
+	}
+	for i := range notifications {
+		if post := notifications[i].Post; post != nil {
+			post.Permalink = utils.BuildPermalink(permalink, post)
+		}
+	}
+	unread, err := unreadNotificationCount(userId)
+	if err != nil {
+		utils.InternalError(c, "获取通知失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      notifications,
+		"total":     total,
+		"unread":    unread,
+		"page":      page,
+		"page_size": notificationPageSize,
+	})
+}
+
+// UnreadNotificationCount 未读通知数
+func UnreadNotificationCount(c *gin.Context) {
+	unread, err := unreadNotificationCount(c.GetUint("user_id"))
+	if err != nil {
+		utils.InternalError(c, "获取未读通知数失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"unread": unread},
+	})
+}
+
+// MarkNotificationsRead 将 ids 指定的通知标记为已读，all 为true时标记全部未读通知
+func MarkNotificationsRead(c *gin.Context, all bool) {
+	userId := c.GetUint("user_id")
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId)
+	if !all {
+		var req struct {
+			IDs []uint `json:"ids" binding:"required,min=1,max=100"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequest(c, "参数错误: "+err.Error())
+			return
+		}
+		query = query.Where("id IN ?", req.IDs)
+	}
+	result := query.UpdateColumn("read_at", time.Now())
+	if result.Error != nil {
+		utils.Log.Errorf("标记通知已读失败: %v, user_id: %d", result.Error, userId)
+		utils.InternalError(c, "标记已读失败: "+result.Error.Error())
+		return
+	}
+	unread, err := unreadNotificationCount(userId)
+	if err != nil {
+		utils.InternalError(c, "获取未读通知数失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已标记为已读",
+		"data": gin.H{
+			"updated": result.RowsAffected,
+			"unread":  unread,
+		},
+	})
+}
+
+// GetNotificationPreferences 各类通知的开关
+func GetNotificationPreferences(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": user.NotificationPreferences(),
+	})
+}
+
+// UpdateNotificationPreferences 修改通知开关，如 {"like": false}，未传的类型保持不变
+func UpdateNotificationPreferences(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req map[string]bool
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	prefs := user.NotificationPreferences()
+	for typ, enabled := range req {
+		if !models.IsValidNotificationType(typ) {
+			utils.BadRequest(c, "不支持的通知类型: "+typ+"，可选: "+strings.Join(models.NotificationTypes, ", "))
+			return
+		}
+		prefs[typ] = enabled
+	}
+	muted := []string{}
+	for _, typ := range models.NotificationTypes {
+		if !prefs[typ] {
+			muted = append(muted, typ)
+		}
+	}
+	user.NotificationsMuted = strings.Join(muted, ",")
+	if err := config.DB.Model(user).UpdateColumn("notifications_muted", user.NotificationsMuted).Error; err != nil {
+		utils.Log.Errorf("保存通知设置失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "保存通知设置失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "通知设置已保存",
+		"data":    user.NotificationPreferences(),
+	})
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 发起授权的浏览器绑定Cookie
+const (
+	oidcBindingCookie = "oidc_binding"
+	oidcCookiePath    = "/api/auth/oidc/"
+)
+
+// ListOIDCProviders 获取已配置的第三方登录提供方
+func ListOIDCProviders(c *gin.Context, registry *utils.OIDCRegistry) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": registry.Providers(),
+	})
+}
+
+// OIDCLogin 发起第三方登录：重定向到提供方授权页（?format=json 时返回授权地址）
+func OIDCLogin(c *gin.Context, registry *utils.OIDCRegistry) {
+	startOIDCAuth(c, registry, 0)
+}
+
+// OIDCLink 已登录用户发起第三方身份绑定，返回授权地址
+func OIDCLink(c *gin.Context, registry *utils.OIDCRegistry) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+	c.Request.URL.RawQuery = "format=json"
+	startOIDCAuth(c, registry, userId.(uint))
+}
+
+// startOIDCAuth 生成授权地址（linkUserID 非0表示绑定模式）
+func startOIDCAuth(c *gin.Context, registry *utils.OIDCRegistry, linkUserID uint) {
+	providerName := c.Param("provider")
+	client, err := registry.Client(c.Request.Context(), providerName)
+	if errors.Is(err, utils.ErrOIDCProviderNotFound) {
+		utils.NotFound(c, "未配置该登录提供方")
+		return
+	}
+	if err != nil {
+		utils.Log.Errorf("OIDC服务发现失败: %v, provider: %s", err, providerName)
+		utils.Error(c, http.StatusBadGateway, "登录提供方暂时不可用")
+		return
+	}
+
+	authURL, binding, err := registry.AuthCodeURL(client, linkUserID)
+	if err != nil {
+		utils.Log.Errorf("生成OIDC授权地址失败: %v, provider: %s", err, providerName)
+		utils.InternalError(c, "发起登录失败")
+		return
+	}
+	// 回调时校验该Cookie，确保完成授权的是发起授权的同一浏览器；
+	// SameSite=Lax 的Cookie在提供方重定向回本站（顶层GET导航）时会被发送
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, binding, int(utils.OIDCStateExpire.Seconds()), oidcCookiePath,
+		"", strings.HasPrefix(client.OAuth2.RedirectURL, "https://"), true)
+
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"auth_url": authURL,
+			},
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 第三方登录回调：校验授权结果，登录/绑定/自动创建账号
+func OIDCCallback(c *gin.Context, registry *utils.OIDCRegistry, jwtManager *utils.JWTManager, tokenExpire int) {
+	providerName := c.Param("provider")
+	if errMsg := c.Query("error"); errMsg != "" {
+		utils.Log.Warnf("OIDC授权被拒绝: %s, provider: %s, ip: %s", errMsg, providerName, c.ClientIP())
+		utils.Unauthorized(c, "第三方授权失败: "+errMsg)
+		return
+	}
+
+	binding, _ := c.Cookie(oidcBindingCookie)
+	authReq, ok := registry.TakeAuthRequest(c.Query("state"), providerName, binding)
+	if binding != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oidcBindingCookie, "", -1, oidcCookiePath, "", c.Request.TLS != nil, true)
+	}
+	if !ok {
+		utils.Log.Warnf("OIDC state无效: provider: %s, ip: %s", providerName, c.ClientIP())
+		utils.BadRequest(c, "登录请求无效或已过期，请重新发起")
+		return
+	}
+
+	client, err := registry.Client(c.Request.Context(), providerName)
+	if err != nil {
+		utils.NotFound(c, "未配置该登录提供方")
+		return
+	}
+	identity, err := client.Exchange(c.Request.Context(), c.Query("code"), authReq)
+	if err != nil {
+		utils.Log.Warnf("OIDC令牌校验失败: %v, provider: %s, ip: %s", err, providerName, c.ClientIP())
+		utils.Unauthorized(c, "第三方登录校验失败")
+		return
+	}
+
+	// 已绑定的身份
+	var existing models.UserIdentity
+	err = config.DB.Where("provider = ? AND subject = ?", providerName, identity.Subject).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.InternalError(c, "第三方登录失败: "+err.Error())
+		return
+	}
+	found := err == nil
+
+	// 绑定模式
+	if authReq.LinkUserID != 0 {
+		if found && existing.UserID != authReq.LinkUserID {
+			utils.Forbidden(c, "该第三方账号已绑定其他用户")
+			return
+		}
+		if !found {
+			if err := config.DB.Create(&models.UserIdentity{
+				UserID:   authReq.LinkUserID,
+				Provider: providerName,
+				Subject:  identity.Subject,
+				Email:    identity.Email,
+			}).Error; err != nil {
+				utils.Log.Errorf("绑定第三方身份失败: %v, user_id: %d", err, authReq.LinkUserID)
+				utils.InternalError(c, "绑定失败: "+err.Error())
+				return
+			}
+		}
+		utils.Log.Infof("第三方身份绑定成功: provider: %s, user_id: %d", providerName, authReq.LinkUserID)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "绑定成功",
+			"data": gin.H{
+				"provider": providerName,
+				"email":    identity.Email,
+			},
+		})
+		return
+	}
+
+	var user models.User
+	if found {
+		if err := config.DB.First(&user, existing.UserID).Error; err != nil {
+			utils.Unauthorized(c, "绑定的用户不存在")
+			return
+		}
+	} else {
+		err := provisionOIDCUser(providerName, identity, client.TrustEmail, &user)
+		if errors.Is(err, errOIDCEmailTaken) {
+			utils.Log.Warnf("第三方登录邮箱已被本地账号使用: provider: %s, ip: %s", providerName, c.ClientIP())
+			utils.Forbidden(c, "该邮箱已注册，请先使用原账号登录，再在账号设置中绑定第三方身份")
+			return
+		}
+		if err != nil {
+			utils.Log.Errorf("第三方登录创建用户失败: %v, provider: %s", err, providerName)
+			utils.InternalError(c, "第三方登录失败: "+err.Error())
+			return
+		}
+	}
+
+	utils.Log.Infof("第三方登录: provider: %s, user_id: %d", providerName, user.ID)
+	completeLogin(c, &user, jwtManager, tokenExpire)
+}
+
+// errOIDCEmailTaken 第三方身份的已验证邮箱属于本地账号，但提供方未被信任，不能自动关联
+var errOIDCEmailTaken = errors.New("邮箱已被本地账号使用")
+
+// provisionOIDCUser 首次第三方登录：提供方可信（trustEmail）且邮箱已验证、与现有用户一致时自动绑定，否则创建新用户；
+// 不可信的提供方不会关联已有账号（否则可绕过该账号的密码和两步验证），邮箱已被使用时返回 errOIDCEmailTaken，需登录后手动绑定
+func provisionOIDCUser(provider string, identity *utils.OIDCIdentity, trustEmail bool, user *models.User) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		linked := false
+		if identity.Email != "" && identity.EmailVerified {
+			err := tx.Where("email = ?", identity.Email).First(user).Error
+			if err == nil {
+				if !trustEmail {
+					return errOIDCEmailTaken
+				}
+				linked = true
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if !linked {
+			username, err := uniqueUsername(tx, identity)
+			if err != nil {
+				return err
+			}
+			// 未验证或已被占用的邮箱不写入用户表，避免抢占他人邮箱
+			email := identity.Email
+			if email != "" {
+				var count int64
+				if err := tx.Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+					return err
+				}
+				if !identity.EmailVerified || count > 0 {
+					email = ""
+				}
+			}
+			randomPwd := make([]byte, 24)
+			if _, err := rand.Read(randomPwd); err != nil {
+				return err
+			}
+			*user = models.User{
+				Username:      username,
+				Password:      base64.RawURLEncoding.EncodeToString(randomPwd),
+				Email:         email,
+				DisplayName:   identity.Name,
+				Role:          models.RoleUser,
+				PasswordUnset: true,
+			}
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		}).Error
+	})
+}
+
+// uniqueUsername 根据第三方资料生成不冲突的用户名
+func uniqueUsername(tx *gorm.DB, identity *utils.OIDCIdentity) (string, error) {
+	base := identity.PreferredUsername
+	if base == "" && identity.Email != "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	return models.UniqueUsername(tx, base)
+}
+
+// ListIdentities 获取当前用户绑定的第三方身份
+func ListIdentities(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var identities []models.UserIdentity
+	if err := config.DB.Where("user_id = ?", userId).Order("created_at").Find(&identities).Error; err != nil {
+		utils.InternalError(c, "获取绑定列表失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": identities,
+	})
+}
+
+// UnlinkIdentity 解绑第三方身份（未设置密码的账号至少保留一个身份）
+func UnlinkIdentity(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "身份ID格式错误")
+		return
+	}
+
+	var identity models.UserIdentity
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userId).First(&identity).Error; err != nil {
+		utils.NotFound(c, "绑定记录不存在")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	if user.PasswordUnset {
+		var count int64
+		if err := config.DB.Model(&models.UserIdentity{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+			utils.InternalError(c, "解绑失败: "+err.Error())
+			return
+		}
+		if count <= 1 {
+			utils.BadRequest(c, "账号未设置密码，不能解绑唯一的登录方式")
+			return
+		}
+	}
+
+	if err := config.DB.Unscoped().Delete(&identity).Error; err != nil {
+		utils.Log.Errorf("解绑第三方身份失败: %v, identity_id: %d", err, identity.ID)
+		utils.InternalError(c, "解绑失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("第三方身份已解绑: provider: %s, user_id: %d", identity.Provider, userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "解绑成功",
+	})
+}
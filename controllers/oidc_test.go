@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go-blog-system/config"
+	"go-blog-system/internal/oidctest"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// oidcTestEnv 回调测试环境：本地OIDC提供方、内存数据库和路由
+type oidcTestEnv struct {
+	provider *oidctest.Provider
+	registry *utils.OIDCRegistry
+	router   *gin.Engine
+}
+
+func newOIDCTestEnv(t *testing.T, trustEmail bool) *oidcTestEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	utils.Log = logrus.New()
+	utils.Log.SetOutput(io.Discard)
+
+	db, err := gorm.Open(sqlite.Open("file:"+url.PathEscape(t.Name())+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.UserIdentity{}, &models.Session{}, &models.ReservedUsername{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	config.DB = db
+
+	jwtManager, err := utils.NewJWTManager(&config.AppConfig{
+		JWTAlgorithm: utils.JWTAlgHS256,
+		JWTSecretKey: "oidc-test-secret-key",
+		JWTIssuer:    "go-blog-system",
+		JWTAudience:  "go-blog-system",
+	})
+	if err != nil {
+		t.Fatalf("创建JWT管理器失败: %v", err)
+	}
+
+	provider := oidctest.NewProvider(t, "blog")
+	registry := utils.NewOIDCRegistry([]config.OIDCProvider{{
+		Name:         "mock",
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "blog",
+		ClientSecret: "secret",
+		RedirectURL:  "http://blog.test/api/auth/oidc/mock/callback",
+		TrustEmail:   trustEmail,
+	}})
+
+	r := gin.New()
+	r.GET("/api/auth/oidc/:provider/login", func(c *gin.Context) {
+		OIDCLogin(c, registry)
+	})
+	// 测试中以请求头模拟已登录用户
+	r.POST("/api/auth/oidc/:provider/link", func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.GetHeader("X-Test-User"), 10, 32)
+		c.Set("user_id", uint(id))
+		OIDCLink(c, registry)
+	})
+	r.GET("/api/auth/oidc/:provider/callback", func(c *gin.Context) {
+		OIDCCallback(c, registry, jwtManager, 3600)
+	})
+	return &oidcTestEnv{provider: provider, registry: registry, router: r}
+}
+
+// start 发起授权，返回授权地址和写入浏览器的绑定Cookie；linkUserID 非0时为绑定模式
+func (e *oidcTestEnv) start(t *testing.T, linkUserID uint) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/login?format=json", nil)
+	if linkUserID != 0 {
+		req = httptest.NewRequest(http.MethodPost, "/api/auth/oidc/mock/link", nil)
+		req.Header.Set("X-Test-User", strconv.FormatUint(uint64(linkUserID), 10))
+	}
+	e.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("发起授权 status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data struct {
+			AuthURL string `json:"auth_url"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("响应格式错误: %v", err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcBindingCookie {
+			if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != oidcCookiePath {
+				t.Errorf("绑定Cookie属性不正确: %+v", cookie)
+			}
+			return body.Data.AuthURL, cookie
+		}
+	}
+	t.Fatal("未设置绑定Cookie")
+	return "", nil
+}
+
+// callback 以 cookie（可为空，模拟其他浏览器）请求回调
+func (e *oidcTestEnv) callback(t *testing.T, code, state string, cookie *http.Cookie) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?"+url.Values{
+		"code":  {code},
+		"state": {state},
+	}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	e.router.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("响应格式错误: %v, body: %s", err, w.Body.String())
+	}
+	return w.Code, body
+}
+
+// login 以 identity 在同一浏览器中完成一次第三方授权并请求回调，linkUserID 非0时为绑定模式
+func (e *oidcTestEnv) login(t *testing.T, identity oidctest.Identity, linkUserID uint) (int, map[string]interface{}) {
+	t.Helper()
+	authURL, cookie := e.start(t, linkUserID)
+	e.provider.SetUser(identity)
+	code, state := e.provider.Authorize(t, authURL)
+	return e.callback(t, code, state, cookie)
+}
+
+func createTestUser(t *testing.T, username, email string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Password: "secret123", Email: email, Role: models.RoleUser}
+	if err := config.DB.Create(user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+func identityOwner(t *testing.T, subject string) (uint, bool) {
+	t.Helper()
+	var identity models.UserIdentity
+	if err := config.DB.Where("provider = ? AND subject = ?", "mock", subject).First(&identity).Error; err != nil {
+		return 0, false
+	}
+	return identity.UserID, true
+}
+
+func TestOIDCCallbackProvisionsNewUser(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+
+	status, body := env.login(t, oidctest.Identity{
+		Subject:           "sub-new",
+		Email:             "newbie@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "newbie",
+		Name:              "New User",
+	}, 0)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body: %v", status, body)
+	}
+	data := body["data"].(map[string]interface{})
+	if data["token"] == "" || data["username"] != "newbie" {
+		t.Errorf("登录结果不正确: %v", data)
+	}
+
+	var user models.User
+	if err := config.DB.Where("username = ?", "newbie").First(&user).Error; err != nil {
+		t.Fatalf("未创建用户: %v", err)
+	}
+	if user.Email != "newbie@example.com" || user.DisplayName != "New User" || !user.PasswordUnset {
+		t.Errorf("新用户资料不正确: %+v", user)
+	}
+	if owner, ok := identityOwner(t, "sub-new"); !ok || owner != user.ID {
+		t.Errorf("身份未绑定到新用户: owner=%d ok=%v", owner, ok)
+	}
+
+	// 再次登录使用已绑定的账号，不再创建用户
+	status, body = env.login(t, oidctest.Identity{Subject: "sub-new", PreferredUsername: "renamed"}, 0)
+	if status != http.StatusOK {
+		t.Fatalf("再次登录 status = %d, body: %v", status, body)
+	}
+	if got := body["data"].(map[string]interface{})["username"]; got != "newbie" {
+		t.Errorf("再次登录的用户 = %v, want newbie", got)
+	}
+	var count int64
+	config.DB.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("用户数 = %d, want 1", count)
+	}
+}
+
+func TestOIDCCallbackUsernameCollision(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+	alice := createTestUser(t, "alice", "alice@local.test")
+
+	status, body := env.login(t, oidctest.Identity{
+		Subject:           "sub-alice",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+	}, 0)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body: %v", status, body)
+	}
+	username := body["data"].(map[string]interface{})["username"]
+	if username == "alice" || username == "" {
+		t.Errorf("用户名冲突时应生成新用户名，得到 %v", username)
+	}
+	owner, ok := identityOwner(t, "sub-alice")
+	if !ok || owner == alice.ID {
+		t.Errorf("身份不应绑定到同名的已有用户: owner=%d ok=%v", owner, ok)
+	}
+}
+
+func TestOIDCCallbackUnverifiedEmailNotStored(t *testing.T) {
+	env := newOIDCTestEnv(t, true)
+	createTestUser(t, "bob", "bob@example.com")
+
+	// 未验证的邮箱即使与已有用户相同也不关联，且不写入新用户
+	status, body := env.login(t, oidctest.Identity{
+		Subject:           "sub-bob",
+		Email:             "bob@example.com",
+		EmailVerified:     false,
+		PreferredUsername: "bobby",
+	}, 0)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body: %v", status, body)
+	}
+	var user models.User
+	if err := config.DB.Where("username = ?", "bobby").First(&user).Error; err != nil {
+		t.Fatalf("未创建用户: %v", err)
+	}
+	if user.Email != "" {
+		t.Errorf("未验证的邮箱不应写入: %q", user.Email)
+	}
+}
+
+func TestOIDCCallbackVerifiedEmailUntrustedProvider(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+	createTestUser(t, "carol", "carol@example.com")
+
+	status, body := env.login(t, oidctest.Identity{
+		Subject:       "sub-carol",
+		Email:         "carol@example.com",
+		EmailVerified: true,
+	}, 0)
+	if status != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403, body: %v", status, body)
+	}
+	if _, ok := identityOwner(t, "sub-carol"); ok {
+		t.Error("未信任邮箱的提供方不应自动关联已有账号")
+	}
+	var count int64
+	config.DB.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Errorf("用户数 = %d, want 1", count)
+	}
+}
+
+func TestOIDCCallbackVerifiedEmailTrustedProvider(t *testing.T) {
+	env := newOIDCTestEnv(t, true)
+	carol := createTestUser(t, "carol", "carol@example.com")
+
+	status, body := env.login(t, oidctest.Identity{
+		Subject:       "sub-carol",
+		Email:         "carol@example.com",
+		EmailVerified: true,
+	}, 0)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body: %v", status, body)
+	}
+	if owner, ok := identityOwner(t, "sub-carol"); !ok || owner != carol.ID {
+		t.Errorf("可信提供方应按邮箱关联已有账号: owner=%d ok=%v", owner, ok)
+	}
+}
+
+func TestOIDCCallbackLink(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+	dave := createTestUser(t, "dave", "dave@local.test")
+	erin := createTestUser(t, "erin", "erin@local.test")
+
+	// 已登录用户绑定：邮箱不同、提供方不可信也可绑定
+	status, body := env.login(t, oidctest.Identity{Subject: "sub-dave", Email: "dave@example.com", EmailVerified: true}, dave.ID)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body: %v", status, body)
+	}
+	if owner, ok := identityOwner(t, "sub-dave"); !ok || owner != dave.ID {
+		t.Errorf("身份未绑定到当前用户: owner=%d ok=%v", owner, ok)
+	}
+
+	// 重复绑定同一身份是幂等的
+	if status, body := env.login(t, oidctest.Identity{Subject: "sub-dave"}, dave.ID); status != http.StatusOK {
+		t.Errorf("重复绑定 status = %d, body: %v", status, body)
+	}
+
+	// 已绑定他人的身份不能再绑定
+	if status, body := env.login(t, oidctest.Identity{Subject: "sub-dave"}, erin.ID); status != http.StatusForbidden {
+		t.Errorf("绑定他人身份 status = %d, want 403, body: %v", status, body)
+	}
+	if owner, _ := identityOwner(t, "sub-dave"); owner != dave.ID {
+		t.Errorf("身份归属被修改: owner=%d", owner)
+	}
+}
+
+func TestOIDCCallbackInvalidState(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/mock/callback?code=x&state=forged", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("伪造 state status = %d, want 400", w.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsOtherBrowser(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+
+	// 登录CSRF：攻击者用自己的第三方账号完成授权，把回调地址交给受害者的浏览器
+	authURL, attackerCookie := env.start(t, 0)
+	env.provider.SetUser(oidctest.Identity{Subject: "sub-attacker", PreferredUsername: "attacker"})
+	code, state := env.provider.Authorize(t, authURL)
+	if status, body := env.callback(t, code, state, nil); status != http.StatusBadRequest {
+		t.Errorf("缺少绑定Cookie status = %d, want 400, body: %v", status, body)
+	}
+	_, victimCookie := env.start(t, 0)
+	if status, body := env.callback(t, code, state, victimCookie); status != http.StatusBadRequest {
+		t.Errorf("其他浏览器的Cookie status = %d, want 400, body: %v", status, body)
+	}
+	if status, _ := env.callback(t, code, state, attackerCookie); status != http.StatusBadRequest {
+		t.Error("校验失败后 state 应作废")
+	}
+	if _, ok := identityOwner(t, "sub-attacker"); ok {
+		t.Error("不应创建或登录攻击者的账号")
+	}
+}
+
+func TestOIDCLinkRejectsOtherBrowser(t *testing.T) {
+	env := newOIDCTestEnv(t, false)
+	attacker := createTestUser(t, "mallory", "mallory@local.test")
+
+	// 绑定CSRF：攻击者为自己的账号发起绑定，诱导受害者完成第三方授权
+	authURL, _ := env.start(t, attacker.ID)
+	env.provider.SetUser(oidctest.Identity{Subject: "sub-victim"})
+	code, state := env.provider.Authorize(t, authURL)
+	if status, body := env.callback(t, code, state, nil); status != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400, body: %v", status, body)
+	}
+	if _, ok := identityOwner(t, "sub-victim"); ok {
+		t.Error("受害者的第三方身份不应绑定到攻击者账号")
+	}
+}
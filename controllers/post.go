@@ -2,16 +2,44 @@ package controllers
 
 import (
 	"go-blog-system/config"
+	"go-blog-system/jobs"
 	"go-blog-system/models"
 	"go-blog-system/utils"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// withPermalinks 按固定链接格式填充文章访问路径
+func withPermalinks(pattern string, posts []models.Post) {
+	for i := range posts {
+		posts[i].Permalink = utils.BuildPermalink(pattern, &posts[i])
+	}
+}
+
+// withReactions 批量填充文章的回应统计，失败时只记录日志
+func withReactions(c *gin.Context, posts []models.Post) {
+	if err := models.FillPostReactions(config.DB, posts, optionalUserID(c)); err != nil {
+		utils.Log.Warnf("获取文章回应统计失败: %v", err)
+	}
+}
+
+// withPostReactions 填充单篇文章的回应统计，失败时只记录日志
+func withPostReactions(c *gin.Context, post *models.Post) {
+	stats, err := models.LoadReactionStats(config.DB, models.ReactionTargetPost, []uint{post.ID}, optionalUserID(c))
+	if err != nil {
+		utils.Log.Warnf("获取文章回应统计失败: %v, post_id: %d", err, post.ID)
+		return
+	}
+	post.ReactionStats = *stats[post.ID]
+}
+
 // CreatePost 创建文章
-func CreatePost(c *gin.Context) {
+func CreatePost(c *gin.Context, permalink string) {
 	// 获取当前用户ID
 	userId, exists := c.Get("user_id")
 	if !exists {
@@ -20,8 +48,12 @@ func CreatePost(c *gin.Context) {
 	}
 
 	var req struct {
-		Title   string `json:"title" binding:"required,min=1,max=100"`
-		Content string `json:"content" binding:"required,min=1"`
+		Title         string   `json:"title" binding:"required,min=1,max=100"`
+		Content       string   `json:"content" binding:"required,min=1"`
+		ContentFormat string   `json:"content_format" binding:"omitempty,oneof=markdown html plain"`
+		Slug          string   `json:"slug"` // 可选，为空时由标题生成
+		Tags          []string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=50"`
+		CommentMode   string   `json:"comment_mode" binding:"omitempty,oneof=open first_time moderated closed"` // 为空时使用站点设置
 	}
 
 	// 绑定参数
@@ -30,23 +62,51 @@ func CreatePost(c *gin.Context) {
 		utils.BadRequest(c, "参数错误: "+err.Error())
 		return
 	}
+	if req.Slug != "" {
+		if !models.IsValidSlug(req.Slug) {
+			utils.BadRequest(c, "slug格式错误：仅允许小写字母、数字和连字符，且不能为纯数字")
+			return
+		}
+		if slug, err := models.UniquePostSlug(config.DB, req.Slug, 0); err != nil || slug != req.Slug {
+			utils.BadRequest(c, "slug已被占用")
+			return
+		}
+	}
 
 	// 创建文章
 	post := models.Post{
-		Title:   req.Title,
-		Content: req.Content,
-		UserID:  userId.(uint),
+		Title:         req.Title,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Slug:          req.Slug,
+		UserID:        userId.(uint),
+		CommentMode:   req.CommentMode,
 	}
-	if err := config.DB.Create(&post).Error; err != nil {
+	var mentioned []uint
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := models.FindOrCreateTags(tx, req.Tags)
+		if err != nil {
+			return err
+		}
+		post.Tags = tags
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		mentioned, err = models.SyncMentions(tx, models.MentionSourcePost, post.ID, post.RenderedHTML)
+		return err
+	})
+	if err != nil {
 		utils.Log.Errorf("创建文章失败: %v, user_id: %d", err, userId)
 		utils.InternalError(c, "创建文章失败: "+err.Error())
 		return
 	}
+	notifyPostMentions(&post, mentioned)
 
 	// 加载作者信息
-	if err := config.DB.Preload("User").First(&post, post.ID).Error; err != nil {
+	if err := config.DB.Preload("User").Preload("Tags").First(&post, post.ID).Error; err != nil {
 		utils.Log.Warnf("加载文章作者信息失败: %v, post_id: %d", err, post.ID)
 	}
+	post.Permalink = utils.BuildPermalink(permalink, &post)
 
 	utils.Log.Infof("文章创建成功: post_id: %d, user_id: %d", post.ID, userId)
 	c.JSON(http.StatusOK, gin.H{
@@ -55,46 +115,124 @@ func CreatePost(c *gin.Context) {
 	})
 }
 
-// GetPosts 获取所有文章
-func GetPosts(c *gin.Context) {
+// GetPosts 获取所有文章，可按 ?tag=标签slug 过滤
+func GetPosts(c *gin.Context, permalink string) {
+	query := config.DB.Preload("User").Preload("Tags")
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("id IN (?)", config.DB.Table("post_tags").Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").Where("tags.slug = ?", tag))
+	}
+
 	var posts []models.Post
-	if err := config.DB.Preload("User").Order("created_at DESC").Find(&posts).Error; err != nil {
+	if err := query.Order("created_at DESC").Find(&posts).Error; err != nil {
 		utils.Log.Errorf("获取文章列表失败: %v", err)
 		utils.InternalError(c, "获取文章列表失败: "+err.Error())
 		return
 	}
+	withPermalinks(permalink, posts)
+	withReactions(c, posts)
+	withBookmarks(c, posts)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": posts,
 	})
 }
 
-// GetPost 获取单篇文章
-func GetPost(c *gin.Context) {
-	// 解析文章ID
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+// GetPost 获取单篇文章，:id 可为文章ID或slug；历史slug永久重定向到当前slug
+func GetPost(c *gin.Context, permalink string, views *jobs.ViewCounter) {
+	key := c.Param("id")
+
+	var post models.Post
+	var err error
+	if id, parseErr := strconv.ParseUint(key, 10, 32); parseErr == nil {
+		err = config.DB.Preload("User").Preload("Tags").Where("id = ?", id).First(&post).Error
+	} else {
+		err = config.DB.Preload("User").Preload("Tags").Where("slug = ?", key).First(&post).Error
+		if err == gorm.ErrRecordNotFound {
+			if current, ok := redirectedSlug(key); ok {
+				c.Redirect(http.StatusMovedPermanently, "/api/posts/"+url.PathEscape(current))
+				return
+			}
+		}
+	}
 	if err != nil {
-		utils.Log.Warnf("文章ID格式错误: %v, ip: %s", err, c.ClientIP())
-		utils.BadRequest(c, "文章ID格式错误")
+		utils.Log.Infof("文章不存在: key=%s, ip: %s", key, c.ClientIP())
+		utils.NotFound(c, "文章不存在")
 		return
 	}
+	post.Permalink = utils.BuildPermalink(permalink, &post)
+	withPostReactions(c, &post)
+	withPostBookmark(c, &post)
+	recordView(c, views, post.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": post,
+	})
+}
+
+// redirectedSlug 查询历史slug对应文章的当前slug
+func redirectedSlug(oldSlug string) (string, bool) {
+	var redirect models.PostSlugRedirect
+	if err := config.DB.Where("old_slug = ?", oldSlug).First(&redirect).Error; err != nil {
+		return "", false
+	}
+	var post models.Post
+	if err := config.DB.Select("id", "slug").First(&post, redirect.PostID).Error; err != nil {
+		return "", false
+	}
+	return post.Slug, true
+}
 
-	// 查询文章
+// findPostByPermalink 按固定链接中的 :id 或 :slug 查找文章，历史slug也能找到对应文章
+func findPostByPermalink(values map[string]string) (models.Post, error) {
 	var post models.Post
-	if err := config.DB.Preload("User").Where("id = ?", id).First(&post).Error; err != nil {
-		utils.Log.Infof("文章不存在: id=%d, ip: %s", id, c.ClientIP())
+	var err error
+	if id, exists := values["id"]; exists {
+		err = config.DB.Preload("User").Preload("Tags").Where("id = ?", id).First(&post).Error
+	} else {
+		err = config.DB.Preload("User").Preload("Tags").Where("slug = ?", values["slug"]).First(&post).Error
+		if err == gorm.ErrRecordNotFound {
+			if current, ok := redirectedSlug(values["slug"]); ok {
+				err = config.DB.Preload("User").Preload("Tags").Where("slug = ?", current).First(&post).Error
+			}
+		}
+	}
+	return post, err
+}
+
+// ResolvePermalink 按固定链接路径（?path=）查找文章
+// 日期与文章不符或使用了历史slug时，永久重定向到规范链接
+func ResolvePermalink(c *gin.Context, pattern string, views *jobs.ViewCounter) {
+	path := c.Query("path")
+	values, ok := utils.MatchPermalink(pattern, path)
+	if !ok {
+		utils.NotFound(c, "文章不存在")
+		return
+	}
+
+	post, err := findPostByPermalink(values)
+	if err != nil {
+		utils.Log.Infof("固定链接无对应文章: path=%s, ip: %s", path, c.ClientIP())
 		utils.NotFound(c, "文章不存在")
 		return
 	}
 
+	post.Permalink = utils.BuildPermalink(pattern, &post)
+	if post.Permalink != "/"+strings.Trim(path, "/") {
+		c.Redirect(http.StatusMovedPermanently, "/api/permalink?path="+url.QueryEscape(post.Permalink))
+		return
+	}
+	withPostReactions(c, &post)
+	withPostBookmark(c, &post)
+	recordView(c, views, post.ID)
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": post,
 	})
 }
 
-// UpdatePost 更新文章
-func UpdatePost(c *gin.Context) {
+// UpdatePost 更新文章，修改slug时记录旧slug用于永久重定向
+func UpdatePost(c *gin.Context, permalink string) {
 	// 获取当前用户ID
 	userId, exists := c.Get("user_id")
 	if !exists {
@@ -121,14 +259,22 @@ func UpdatePost(c *gin.Context) {
 
 	// 绑定更新参数
 	var req struct {
-		Title   string `json:"title" binding:"omitempty,min=1,max=100"`
-		Content string `json:"content" binding:"omitempty,min=1"`
+		Title         string    `json:"title" binding:"omitempty,min=1,max=100"`
+		Content       string    `json:"content" binding:"omitempty,min=1"`
+		ContentFormat string    `json:"content_format" binding:"omitempty,oneof=markdown html plain"`
+		Slug          string    `json:"slug"`
+		Tags          *[]string `json:"tags" binding:"omitempty,max=10,dive,min=1,max=50"`                               // 传入时整体替换标签
+		CommentMode   string    `json:"comment_mode" binding:"omitempty,oneof=default open first_time moderated closed"` // default 表示改为使用站点设置
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Log.Warnf("更新文章参数错误: %v, post_id: %d", err, id)
 		utils.BadRequest(c, "参数错误: "+err.Error())
 		return
 	}
+	if req.Slug != "" && req.Slug != post.Slug && !models.IsValidSlug(req.Slug) {
+		utils.BadRequest(c, "slug格式错误：仅允许小写字母、数字和连字符，且不能为纯数字")
+		return
+	}
 
 	// 更新文章
 	if req.Title != "" {
@@ -137,14 +283,70 @@ func UpdatePost(c *gin.Context) {
 	if req.Content != "" {
 		post.Content = req.Content
 	}
-	if err := config.DB.Save(&post).Error; err != nil {
+	if req.ContentFormat != "" {
+		post.ContentFormat = req.ContentFormat
+	}
+	if req.CommentMode == "default" {
+		post.CommentMode = ""
+	} else if req.CommentMode != "" {
+		post.CommentMode = req.CommentMode
+	}
+	oldSlug := post.Slug
+	slugTaken := false
+	var mentioned []uint
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Slug != "" && req.Slug != oldSlug {
+			slug, err := models.UniquePostSlug(tx, req.Slug, post.ID)
+			if err != nil {
+				return err
+			}
+			if slug != req.Slug {
+				slugTaken = true
+				return nil
+			}
+			// 改回曾用过的slug时移除对应的重定向记录
+			if err := tx.Unscoped().Where("old_slug = ?", req.Slug).Delete(&models.PostSlugRedirect{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&models.PostSlugRedirect{OldSlug: oldSlug, PostID: post.ID}).Error; err != nil {
+				return err
+			}
+			post.Slug = req.Slug
+		}
+		if req.Tags != nil {
+			tags, err := models.FindOrCreateTags(tx, *req.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&post).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+		// 阅读次数由后台任务累加，保存文章时不覆盖
+		if err := tx.Omit("Tags", "ViewCount").Save(&post).Error; err != nil {
+			return err
+		}
+		// 只通知本次编辑新提及的用户
+		mentioned, err = models.SyncMentions(tx, models.MentionSourcePost, post.ID, post.RenderedHTML)
+		return err
+	})
+	if slugTaken {
+		utils.BadRequest(c, "slug已被占用")
+		return
+	}
+	if err != nil {
 		utils.Log.Errorf("更新文章失败: %v, post_id: %d", err, id)
 		utils.InternalError(c, "更新文章失败: "+err.Error())
 		return
 	}
+	notifyPostMentions(&post, mentioned)
+	if post.Slug != oldSlug {
+		utils.Log.Infof("文章slug变更: post_id: %d, %s -> %s", post.ID, oldSlug, post.Slug)
+	}
 
 	// 重新加载作者信息
-	config.DB.Preload("User").First(&post, post.ID)
+	config.DB.Preload("User").Preload("Tags").First(&post, post.ID)
+	post.Permalink = utils.BuildPermalink(permalink, &post)
 
 	utils.Log.Infof("文章更新成功: post_id: %d, user_id: %d", id, userId)
 	c.JSON(http.StatusOK, gin.H{
@@ -179,8 +381,14 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 
-	// 删除文章
-	if err := config.DB.Delete(&post).Error; err != nil {
+	// 删除文章，文章的媒体保留在媒体库中并取消关联
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Media{}).Where("post_id = ?", post.ID).Update("post_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&post).Error
+	})
+	if err != nil {
 		utils.Log.Errorf("删除文章失败: %v, post_id: %d", err, id)
 		utils.InternalError(c, "删除文章失败: "+err.Error())
 		return
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 回应用户列表每页条数
+const reactionPageSize = 50
+
+// optionalUserID 可选认证接口中的当前用户ID，未登录时为0
+func optionalUserID(c *gin.Context) uint {
+	if id, exists := c.Get("user_id"); exists {
+		return id.(uint)
+	}
+	return 0
+}
+
+// findReactionTarget 解析 :id 并校验回应对象：文章须存在，评论须存在且已公开
+func findReactionTarget(c *gin.Context, targetType string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "ID格式错误")
+		return 0, false
+	}
+
+	var count int64
+	if targetType == models.ReactionTargetPost {
+		err = config.DB.Model(&models.Post{}).Where("id = ?", id).Count(&count).Error
+	} else {
+		err = config.DB.Model(&models.Comment{}).Scopes(models.ApprovedComments).Where("id = ?", id).Count(&count).Error
+	}
+	if err != nil {
+		utils.InternalError(c, "查询失败: "+err.Error())
+		return 0, false
+	}
+	if count == 0 {
+		if targetType == models.ReactionTargetPost {
+			utils.NotFound(c, "文章不存在")
+		} else {
+			utils.NotFound(c, "评论不存在")
+		}
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// reactionKind 解析并校验 :kind
+func reactionKind(c *gin.Context) (string, bool) {
+	kind := c.Param("kind")
+	if !models.IsValidReactionKind(kind) {
+		utils.BadRequest(c, "不支持的回应类型: "+kind)
+		return "", false
+	}
+	return kind, true
+}
+
+// respondReactionStats 返回对象最新的回应统计
+func respondReactionStats(c *gin.Context, targetType string, targetID, userID uint, message string) {
+	stats, err := models.LoadReactionStats(config.DB, targetType, []uint{targetID}, userID)
+	if err != nil {
+		utils.InternalError(c, "获取回应统计失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    stats[targetID],
+	})
+}
+
+// notifyLike 新的点赞通知文章/评论作者
+func notifyLike(targetType string, targetID, actorID uint) {
+	e := models.NotificationEvent{
+		Type:    models.NotifyLike,
+		ActorID: actorID,
+		Target:  targetType + ":" + strconv.FormatUint(uint64(targetID), 10),
+	}
+	if targetType == models.ReactionTargetPost {
+		var post models.Post
+		if err := config.DB.Select("id", "user_id").First(&post, targetID).Error; err != nil {
+			return
+		}
+		e.UserID, e.PostID = post.UserID, post.ID
+	} else {
+		var comment models.Comment
+		if err := config.DB.Select("id", "user_id", "post_id").First(&comment, targetID).Error; err != nil {
+			return
+		}
+		e.UserID, e.PostID, e.CommentID = comment.UserID, comment.PostID, comment.ID
+	}
+	notify(e)
+}
+
+// AddReaction 添加点赞/回应，重复添加不报错（幂等）
+func AddReaction(c *gin.Context, targetType string) {
+	userId := c.GetUint("user_id")
+	targetID, ok := findReactionTarget(c, targetType)
+	if !ok {
+		return
+	}
+	kind, ok := reactionKind(c)
+	if !ok {
+		return
+	}
+
+	reaction := models.Reaction{UserID: userId, TargetType: targetType, TargetID: targetID, Kind: kind}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&reaction)
+	if result.Error != nil {
+		utils.Log.Errorf("添加回应失败: %v, user_id: %d", result.Error, userId)
+		utils.InternalError(c, "添加回应失败: "+result.Error.Error())
+		return
+	}
+	if kind == models.ReactionLike && result.RowsAffected > 0 {
+		notifyLike(targetType, targetID, userId)
+	}
+	respondReactionStats(c, targetType, targetID, userId, "已添加")
+}
+
+// RemoveReaction 取消点赞/回应，未添加过时不报错（幂等）
+func RemoveReaction(c *gin.Context, targetType string) {
+	userId := c.GetUint("user_id")
+	targetID, ok := findReactionTarget(c, targetType)
+	if !ok {
+		return
+	}
+	kind, ok := reactionKind(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Where("user_id = ? AND target_type = ? AND target_id = ? AND kind = ?", userId, targetType, targetID, kind).
+		Delete(&models.Reaction{}).Error; err != nil {
+		utils.Log.Errorf("取消回应失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "取消回应失败: "+err.Error())
+		return
+	}
+	respondReactionStats(c, targetType, targetID, userId, "已取消")
+}
+
+// ListReactions 回应过的用户列表，按时间倒序，可按 ?kind= 过滤，?page= 分页
+func ListReactions(c *gin.Context, targetType string) {
+	targetID, ok := findReactionTarget(c, targetType)
+	if !ok {
+		return
+	}
+	query := config.DB.Model(&models.Reaction{}).Where("target_type = ? AND target_id = ?", targetType, targetID)
+	if kind := c.Query("kind"); kind != "" {
+		if !models.IsValidReactionKind(kind) {
+			utils.BadRequest(c, "不支持的回应类型: "+kind)
+			return
+		}
+		query = query.Where("kind = ?", kind)
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.BadRequest(c, "页码格式错误")
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.InternalError(c, "获取回应列表失败: "+err.Error())
+		return
+	}
+	var reactions []models.Reaction
+	if err := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "display_name", "role", "created_at")
+	}).Order("id DESC").Offset((page - 1) * reactionPageSize).Limit(reactionPageSize).Find(&reactions).Error; err != nil {
+		utils.InternalError(c, "获取回应列表失败: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      reactions,
+		"total":     total,
+		"page":      page,
+		"page_size": reactionPageSize,
+	})
+}
+
+// ReactionKinds 支持的回应类型
+func ReactionKinds(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": models.ReactionKinds,
+	})
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HighlightCSS 代码高亮样式表，rendered_html 中的代码块依赖其中的class配色
+func HighlightCSS(c *gin.Context, css string) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", []byte(css))
+}
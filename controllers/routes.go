@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/jobs"
+	"go-blog-system/theme"
+	"go-blog-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 面向读者的公开站点路由，服务端与静态导出（commands/export.go）共用同一套注册逻辑
+
+// RegisterFeedRoutes 注册订阅源、sitemap 与 robots.txt
+// 订阅源分全站、作者、标签、文章评论，各提供 RSS/Atom/JSON Feed 三种格式
+func RegisterFeedRoutes(r gin.IRoutes, cfg *config.AppConfig, sitemapCache *utils.SitemapCache) {
+	r.GET("/sitemap.xml", func(c *gin.Context) {
+		Sitemap(c, sitemapCache)
+	})
+	r.GET("/sitemaps/:page", func(c *gin.Context) {
+		SitemapPage(c, sitemapCache)
+	})
+	r.GET("/robots.txt", func(c *gin.Context) {
+		RobotsTxt(c, cfg)
+	})
+
+	for file, format := range FeedFiles {
+		r.GET("/"+file, func(c *gin.Context) {
+			SiteFeed(c, cfg, format)
+		})
+		r.GET("/authors/:username/"+file, func(c *gin.Context) {
+			AuthorFeed(c, cfg, format)
+		})
+		r.GET("/tags/:slug/"+file, func(c *gin.Context) {
+			TagFeed(c, cfg, format)
+		})
+		r.GET("/posts/:id/comments/"+file, func(c *gin.Context) {
+			PostCommentsFeed(c, cfg, format)
+		})
+	}
+}
+
+// RegisterSiteRoutes 注册服务端渲染的站点页面，文章页按固定链接格式在未匹配路由中处理
+// views 为nil时不记录文章阅读（静态导出）
+func RegisterSiteRoutes(r *gin.Engine, t *theme.Theme, cfg *config.AppConfig, views *jobs.ViewCounter) {
+	r.GET("/static/*filepath", func(c *gin.Context) {
+		ThemeStatic(c, t)
+	})
+	for _, path := range []string{"/", "/page/:page"} {
+		r.GET(path, func(c *gin.Context) {
+			SiteHome(c, t, cfg)
+		})
+	}
+	for _, path := range []string{"/authors/:username", "/authors/:username/page/:page"} {
+		r.GET(path, func(c *gin.Context) {
+			SiteAuthor(c, t, cfg)
+		})
+	}
+	for _, path := range []string{"/tags/:slug", "/tags/:slug/page/:page"} {
+		r.GET(path, func(c *gin.Context) {
+			SiteTag(c, t, cfg)
+		})
+	}
+	r.GET("/archive", func(c *gin.Context) {
+		SiteArchive(c, t, cfg)
+	})
+	r.GET("/search", func(c *gin.Context) {
+		SiteSearch(c, t, cfg)
+	})
+	r.NoRoute(func(c *gin.Context) {
+		SitePost(c, t, cfg, views)
+	})
+}
+
+// LoadTheme 加载站点主题
+func LoadTheme(cfg *config.AppConfig) (*theme.Theme, error) {
+	return theme.Load(cfg.ThemeDir, TemplateFuncs(cfg))
+}
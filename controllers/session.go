@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// issueSessionToken 签发正式Token并登记会话
+func issueSessionToken(c *gin.Context, user *models.User, jwtManager *utils.JWTManager, tokenExpire int) (string, error) {
+	token, claims, err := jwtManager.GenerateToken(user.ID, user.Username, tokenExpire)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	userAgent := utils.TruncateUTF8(c.Request.UserAgent(), 255)
+	session := models.Session{
+		UserID:     user.ID,
+		TokenID:    claims.ID,
+		Device:     utils.DescribeUserAgent(userAgent),
+		UserAgent:  userAgent,
+		IP:         c.ClientIP(),
+		LastSeenAt: now,
+		ExpiresAt:  claims.ExpiresAt.Time,
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		return "", err
+	}
+
+	// 顺带清理该用户已过期的会话
+	if err := config.DB.Unscoped().Where("user_id = ? AND expires_at < ?", user.ID, now).Delete(&models.Session{}).Error; err != nil {
+		utils.Log.Warnf("清理过期会话失败: %v, user_id: %d", err, user.ID)
+	}
+	return token, nil
+}
+
+// ListSessions 获取当前用户的登录会话（设备）列表
+func ListSessions(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var sessions []models.Session
+	if err := config.DB.Where("user_id = ? AND expires_at > ?", userId, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error; err != nil {
+		utils.Log.Errorf("获取会话列表失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "获取会话列表失败: "+err.Error())
+		return
+	}
+
+	currentID := c.GetUint("session_id")
+	items := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, gin.H{
+			"id":           s.ID,
+			"device":       s.Device,
+			"user_agent":   s.UserAgent,
+			"ip":           s.IP,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"expires_at":   s.ExpiresAt,
+			"current":      s.ID == currentID,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": items,
+	})
+}
+
+// RevokeSession 注销指定会话，对应设备上的Token立即失效
+func RevokeSession(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequest(c, "会话ID格式错误")
+		return
+	}
+
+	result := config.DB.Where("id = ? AND user_id = ?", id, userId).Delete(&models.Session{})
+	if result.Error != nil {
+		utils.Log.Errorf("注销会话失败: %v, session_id: %d", result.Error, id)
+		utils.InternalError(c, "注销会话失败: "+result.Error.Error())
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.NotFound(c, "会话不存在")
+		return
+	}
+
+	utils.Log.Infof("会话已注销: session_id: %d, user_id: %d", id, userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "会话已注销",
+	})
+}
+
+// Logout 退出登录（注销当前会话）
+func Logout(c *gin.Context) {
+	sessionID := c.GetUint("session_id")
+	if sessionID == 0 {
+		utils.Unauthorized(c, "未获取到会话信息")
+		return
+	}
+	if err := config.DB.Delete(&models.Session{}, sessionID).Error; err != nil {
+		utils.Log.Errorf("退出登录失败: %v, session_id: %d", err, sessionID)
+		utils.InternalError(c, "退出登录失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("用户退出登录: session_id: %d, user_id: %d", sessionID, c.GetUint("user_id"))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已退出登录",
+	})
+}
@@ -0,0 +1,390 @@
+package controllers
+
+import (
+	"bytes"
+	"go-blog-system/config"
+	"go-blog-system/jobs"
+	"go-blog-system/models"
+	"go-blog-system/render"
+	"go-blog-system/theme"
+	"go-blog-system/utils"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 服务端渲染的站点页面（首页、文章、作者、标签、归档、搜索），模板由主题提供
+
+// pageMeta 页面的SEO与社交分享信息
+type pageMeta struct {
+	Title         string
+	Description   string
+	URL           string // 规范地址（绝对地址）
+	Type          string // OpenGraph类型：website/article/profile
+	Image         string
+	Author        string
+	PublishedTime time.Time
+	ModifiedTime  time.Time
+	Tags          []string
+	Feeds         []feedLink
+	NoIndex       bool // 搜索结果、错误页不需要被收录
+}
+
+// feedLink 页面对应的订阅源
+type feedLink struct {
+	Title string
+	Type  string
+	Href  string
+}
+
+// pagination 分页导航，上一页/下一页为空表示没有
+type pagination struct {
+	Page    int
+	PrevURL string
+	NextURL string
+}
+
+// archiveGroup 归档页按月分组
+type archiveGroup struct {
+	Label string
+	Posts []models.Post
+}
+
+// siteSummaryLength 页面描述与列表摘要长度
+const siteSummaryLength = 160
+
+// TemplateFuncs 主题模板可用的函数
+func TemplateFuncs(cfg *config.AppConfig) template.FuncMap {
+	return template.FuncMap{
+		"permalink": func(post models.Post) string {
+			return utils.BuildPermalink(cfg.PostPermalink, &post)
+		},
+		"authorName": func(user models.User) string {
+			return authorName(&user)
+		},
+		"summary": func(renderedHTML string) string {
+			return render.Summary(renderedHTML, siteSummaryLength)
+		},
+		// 文章与评论的HTML在保存时已经过滤
+		"safeHTML": func(s string) template.HTML {
+			return template.HTML(s)
+		},
+		"date": func(t time.Time) string {
+			return t.Local().Format("2006-01-02")
+		},
+		"datetime": func(t time.Time) string {
+			return t.Local().Format("2006-01-02 15:04")
+		},
+		"rfc3339": func(t time.Time) string {
+			return t.UTC().Format(time.RFC3339)
+		},
+	}
+}
+
+// feedLinks 生成某个订阅源前缀下三种格式的链接
+func feedLinks(cfg *config.AppConfig, prefix, title string) []feedLink {
+	return []feedLink{
+		{Title: title + "（RSS）", Type: "application/rss+xml", Href: cfg.SiteURL + prefix + "/feed.xml"},
+		{Title: title + "（Atom）", Type: "application/atom+xml", Href: cfg.SiteURL + prefix + "/atom.xml"},
+		{Title: title + "（JSON Feed）", Type: "application/feed+json", Href: cfg.SiteURL + prefix + "/feed.json"},
+	}
+}
+
+// absoluteURL 站内相对地址补全为绝对地址
+func absoluteURL(cfg *config.AppConfig, s string) string {
+	if strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") {
+		return cfg.SiteURL + s
+	}
+	return s
+}
+
+// renderPage 渲染主题页面
+func renderPage(c *gin.Context, t *theme.Theme, cfg *config.AppConfig, status int, page string, meta pageMeta, data gin.H) {
+	if meta.Type == "" {
+		meta.Type = "website"
+	}
+	if meta.URL == "" {
+		meta.URL = cfg.SiteURL + c.Request.URL.Path
+	}
+	data["Site"] = gin.H{"Title": cfg.SiteTitle, "Description": cfg.SiteDescription, "URL": cfg.SiteURL, "Static": cfg.SiteStatic}
+	data["Meta"] = meta
+	if _, ok := data["Query"]; !ok {
+		data["Query"] = ""
+	}
+
+	var buf bytes.Buffer
+	if err := t.Render(&buf, page, data); err != nil {
+		utils.Log.Errorf("渲染页面失败: %v, page: %s, path: %s", err, page, c.Request.URL.Path)
+		c.Data(http.StatusInternalServerError, "text/plain; charset=utf-8", []byte("页面渲染失败"))
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// renderError 渲染错误页
+func renderError(c *gin.Context, t *theme.Theme, cfg *config.AppConfig, status int, message string) {
+	renderPage(c, t, cfg, status, "error", pageMeta{Title: message, NoIndex: true}, gin.H{
+		"Status":  status,
+		"Message": message,
+	})
+}
+
+// pageNumber 当前页码：列表页为路径 /page/:page，搜索页为 ?page=，非法值按第一页处理
+func pageNumber(c *gin.Context) int {
+	s := c.Param("page")
+	if s == "" {
+		s = c.Query("page")
+	}
+	page, err := strconv.Atoi(s)
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// listPageURL 列表第n页的路径，第一页为列表本身（静态导出时同样适用）
+func listPageURL(base string, n int) string {
+	if n == 1 {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/page/" + strconv.Itoa(n)
+}
+
+// paginatePosts 分页查询文章（多取一条用于判断是否有下一页），并生成分页导航
+// base 为列表路径，分页地址为 base/page/n；为空时使用查询参数 ?page=n
+func paginatePosts(c *gin.Context, cfg *config.AppConfig, query *gorm.DB, base string) ([]models.Post, pagination, error) {
+	page := pageNumber(c)
+	size := cfg.SitePageSize
+
+	var posts []models.Post
+	err := query.Preload("User").Preload("Tags").Order("created_at DESC").
+		Offset((page - 1) * size).Limit(size + 1).Find(&posts).Error
+	if err != nil {
+		return nil, pagination{}, err
+	}
+
+	pageURL := func(n int) string {
+		if base != "" {
+			return listPageURL(base, n)
+		}
+		values := c.Request.URL.Query()
+		if n == 1 {
+			values.Del("page")
+		} else {
+			values.Set("page", strconv.Itoa(n))
+		}
+		return c.Request.URL.Path + "?" + values.Encode()
+	}
+	nav := pagination{Page: page}
+	if page > 1 {
+		nav.PrevURL = pageURL(page - 1)
+	}
+	if len(posts) > size {
+		posts = posts[:size]
+		nav.NextURL = pageURL(page + 1)
+	}
+	return posts, nav, nil
+}
+
+// SiteHome 首页：最新文章列表
+func SiteHome(c *gin.Context, t *theme.Theme, cfg *config.AppConfig) {
+	posts, nav, err := paginatePosts(c, cfg, config.DB.Model(&models.Post{}), "/")
+	if err != nil {
+		utils.Log.Errorf("获取首页文章失败: %v", err)
+		renderError(c, t, cfg, http.StatusInternalServerError, "获取文章失败")
+		return
+	}
+	renderPage(c, t, cfg, http.StatusOK, "home", pageMeta{
+		Description: cfg.SiteDescription,
+		URL:         cfg.SiteURL + listPageURL("/", pageNumber(c)),
+		Feeds:       feedLinks(cfg, "", cfg.SiteTitle),
+	}, gin.H{
+		"Posts":      posts,
+		"Pagination": nav,
+	})
+}
+
+// SitePost 文章页，作为未匹配路由的处理函数：按固定链接格式查找文章
+// 接口路径返回JSON格式的404；历史slug或日期不符时永久重定向到规范链接
+func SitePost(c *gin.Context, t *theme.Theme, cfg *config.AppConfig, views *jobs.ViewCounter) {
+	path := c.Request.URL.Path
+	if strings.HasPrefix(path, "/api/") {
+		utils.NotFound(c, "接口不存在")
+		return
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		utils.NotFound(c, "页面不存在")
+		return
+	}
+
+	values, ok := utils.MatchPermalink(cfg.PostPermalink, path)
+	if !ok {
+		renderError(c, t, cfg, http.StatusNotFound, "页面不存在")
+		return
+	}
+	post, err := findPostByPermalink(values)
+	if err != nil {
+		renderError(c, t, cfg, http.StatusNotFound, "文章不存在")
+		return
+	}
+	permalink := utils.BuildPermalink(cfg.PostPermalink, &post)
+	if permalink != "/"+strings.Trim(path, "/") {
+		c.Redirect(http.StatusMovedPermanently, permalink)
+		return
+	}
+	recordView(c, views, post.ID)
+
+	var comments []models.Comment
+	if err := config.DB.Preload("User").Scopes(models.ApprovedComments).Where("post_id = ?", post.ID).Order("created_at ASC").Find(&comments).Error; err != nil {
+		utils.Log.Errorf("获取文章评论失败: %v, post_id: %d", err, post.ID)
+	}
+
+	tags := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags = append(tags, tag.Name)
+	}
+	commentsFeed := "/posts/" + strconv.FormatUint(uint64(post.ID), 10) + "/comments"
+	renderPage(c, t, cfg, http.StatusOK, "post", pageMeta{
+		Title:         post.Title,
+		Description:   render.Summary(post.RenderedHTML, siteSummaryLength),
+		URL:           cfg.SiteURL + permalink,
+		Type:          "article",
+		Image:         absoluteURL(cfg, render.FirstImage(post.RenderedHTML)),
+		Author:        authorName(&post.User),
+		PublishedTime: post.CreatedAt,
+		ModifiedTime:  post.UpdatedAt,
+		Tags:          tags,
+		Feeds:         feedLinks(cfg, commentsFeed, "《"+post.Title+"》的评论"),
+	}, gin.H{
+		"Post":         post,
+		"Comments":     comments,
+		"CommentsFeed": commentsFeed + "/feed.xml",
+	})
+}
+
+// SiteAuthor 作者页
+func SiteAuthor(c *gin.Context, t *theme.Theme, cfg *config.AppConfig) {
+	var user models.User
+	if err := config.DB.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+		renderError(c, t, cfg, http.StatusNotFound, "用户不存在")
+		return
+	}
+	base := "/authors/" + url.PathEscape(user.Username)
+	posts, nav, err := paginatePosts(c, cfg, config.DB.Model(&models.Post{}).Where("user_id = ?", user.ID), base)
+	if err != nil {
+		utils.Log.Errorf("获取作者文章失败: %v, user_id: %d", err, user.ID)
+		renderError(c, t, cfg, http.StatusInternalServerError, "获取文章失败")
+		return
+	}
+	name := authorName(&user)
+	renderPage(c, t, cfg, http.StatusOK, "author", pageMeta{
+		Title:       name,
+		Description: user.Bio,
+		URL:         cfg.SiteURL + listPageURL(base, pageNumber(c)),
+		Type:        "profile",
+		Feeds:       feedLinks(cfg, base, name),
+	}, gin.H{
+		"Author":     user,
+		"Posts":      posts,
+		"Pagination": nav,
+	})
+}
+
+// SiteTag 标签页
+func SiteTag(c *gin.Context, t *theme.Theme, cfg *config.AppConfig) {
+	var tag models.Tag
+	if err := config.DB.Where("slug = ?", c.Param("slug")).First(&tag).Error; err != nil {
+		renderError(c, t, cfg, http.StatusNotFound, "标签不存在")
+		return
+	}
+	query := config.DB.Model(&models.Post{}).
+		Where("id IN (?)", config.DB.Table("post_tags").Select("post_id").Where("tag_id = ?", tag.ID))
+	base := "/tags/" + url.PathEscape(tag.Slug)
+	posts, nav, err := paginatePosts(c, cfg, query, base)
+	if err != nil {
+		utils.Log.Errorf("获取标签文章失败: %v, tag_id: %d", err, tag.ID)
+		renderError(c, t, cfg, http.StatusInternalServerError, "获取文章失败")
+		return
+	}
+	renderPage(c, t, cfg, http.StatusOK, "tag", pageMeta{
+		Title:       "#" + tag.Name,
+		Description: "标签“" + tag.Name + "”下的文章",
+		URL:         cfg.SiteURL + listPageURL(base, pageNumber(c)),
+		Feeds:       feedLinks(cfg, base, tag.Name),
+	}, gin.H{
+		"Tag":        tag,
+		"Posts":      posts,
+		"Pagination": nav,
+	})
+}
+
+// SiteArchive 归档页：全部文章按月分组
+func SiteArchive(c *gin.Context, t *theme.Theme, cfg *config.AppConfig) {
+	var posts []models.Post
+	if err := config.DB.Select("id", "title", "slug", "created_at").Order("created_at DESC").Find(&posts).Error; err != nil {
+		utils.Log.Errorf("获取归档失败: %v", err)
+		renderError(c, t, cfg, http.StatusInternalServerError, "获取文章失败")
+		return
+	}
+
+	var groups []archiveGroup
+	for _, post := range posts {
+		label := post.CreatedAt.Local().Format("2006年01月")
+		if len(groups) == 0 || groups[len(groups)-1].Label != label {
+			groups = append(groups, archiveGroup{Label: label})
+		}
+		groups[len(groups)-1].Posts = append(groups[len(groups)-1].Posts, post)
+	}
+	renderPage(c, t, cfg, http.StatusOK, "archive", pageMeta{
+		Title:       "归档",
+		Description: cfg.SiteTitle + "的全部文章",
+	}, gin.H{
+		"Groups": groups,
+	})
+}
+
+// likeEscaper 转义LIKE中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SiteSearch 搜索页：按标题和正文模糊匹配
+func SiteSearch(c *gin.Context, t *theme.Theme, cfg *config.AppConfig) {
+	q := strings.TrimSpace(c.Query("q"))
+	if runes := []rune(q); len(runes) > 100 {
+		q = string(runes[:100])
+	}
+
+	data := gin.H{"Query": q}
+	if q != "" {
+		pattern := "%" + likeEscaper.Replace(q) + "%"
+		query := config.DB.Model(&models.Post{}).
+			Where(`title LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\'`, pattern, pattern)
+		posts, nav, err := paginatePosts(c, cfg, query, "")
+		if err != nil {
+			utils.Log.Errorf("搜索文章失败: %v, q: %s", err, q)
+			renderError(c, t, cfg, http.StatusInternalServerError, "搜索失败")
+			return
+		}
+		data["Posts"] = posts
+		data["Pagination"] = nav
+	}
+	renderPage(c, t, cfg, http.StatusOK, "search", pageMeta{Title: "搜索", NoIndex: true}, data)
+}
+
+// ThemeStatic 主题静态文件：/static/*filepath
+func ThemeStatic(c *gin.Context, t *theme.Theme) {
+	name := strings.TrimPrefix(c.Param("filepath"), "/")
+	info, err := fs.Stat(t.Static(), name)
+	if err != nil || info.IsDir() {
+		utils.NotFound(c, "文件不存在")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.FileFromFS(name, http.FS(t.Static()))
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go-blog-system/config"
+	"go-blog-system/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeSitemap 输出sitemap文件，支持条件请求
+func writeSitemap(c *gin.Context, sitemap *utils.Sitemap, data []byte) {
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=3600")
+	if !sitemap.Updated.IsZero() {
+		c.Header("Last-Modified", sitemap.Updated.UTC().Format(http.TimeFormat))
+	}
+	if notModified(c, etag, sitemap.Updated) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+}
+
+// Sitemap /sitemap.xml：URL数量超过上限时为sitemap索引
+func Sitemap(c *gin.Context, cache *utils.SitemapCache) {
+	sitemap, err := cache.Get()
+	if err != nil {
+		utils.Log.Errorf("生成sitemap失败: %v", err)
+		utils.InternalError(c, "生成sitemap失败")
+		return
+	}
+	writeSitemap(c, sitemap, sitemap.Index)
+}
+
+// SitemapPage 拆分后的sitemap分页：/sitemaps/1.xml
+func SitemapPage(c *gin.Context, cache *utils.SitemapCache) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || !strings.HasSuffix(c.Param("page"), ".xml") {
+		utils.NotFound(c, "sitemap不存在")
+		return
+	}
+	sitemap, err := cache.Get()
+	if err != nil {
+		utils.Log.Errorf("生成sitemap失败: %v", err)
+		utils.InternalError(c, "生成sitemap失败")
+		return
+	}
+	if page < 1 || page > len(sitemap.Pages) {
+		utils.NotFound(c, "sitemap不存在")
+		return
+	}
+	writeSitemap(c, sitemap, sitemap.Pages[page-1])
+}
+
+// RobotsTxt /robots.txt：按配置生成抓取规则并指向sitemap
+func RobotsTxt(c *gin.Context, cfg *config.AppConfig) {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	for _, path := range cfg.RobotsDisallow {
+		b.WriteString("Disallow: " + path + "\n")
+	}
+	if cfg.RobotsExtra != "" {
+		b.WriteString("\n" + strings.TrimSpace(cfg.RobotsExtra) + "\n")
+	}
+	b.WriteString("\nSitemap: " + cfg.SiteURL + "/sitemap.xml\n")
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,390 @@
+package controllers
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// TOTP 签发方名称（显示在验证器App中）
+const totpIssuer = "go-blog-system"
+
+// 两步验证失败限制：窗口期内连续失败达到上限后暂时锁定
+const (
+	secondFactorMaxFailures = 5
+	secondFactorLockWindow  = 15 * time.Minute
+)
+
+// secondFactorFailures 记录各用户两步验证失败次数（内存级，重启清零）
+var secondFactorFailures = struct {
+	sync.Mutex
+	m map[uint]*failureRecord
+}{m: make(map[uint]*failureRecord)}
+
+type failureRecord struct {
+	count int
+	first time.Time
+}
+
+// SetupTwoFactor 生成TOTP密钥，返回密钥、otpauth链接和二维码，需调用确认接口后生效
+func SetupTwoFactor(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	if user.TOTPEnabled {
+		utils.BadRequest(c, "已启用两步验证，如需更换请先关闭")
+		return
+	}
+
+	key, err := utils.GenerateTOTPKey(totpIssuer, user.Username)
+	if err != nil {
+		utils.Log.Errorf("生成TOTP密钥失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "生成两步验证密钥失败")
+		return
+	}
+
+	// 暂存密钥，确认前不生效
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    key.Secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		utils.Log.Errorf("保存TOTP密钥失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "生成两步验证密钥失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("生成TOTP密钥: user_id: %d", user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "请使用验证器App扫描二维码，并提交动态码完成绑定",
+		"data": gin.H{
+			"secret":      key.Secret,
+			"otpauth_uri": key.URI,
+			"qr_code":     key.QRCode,
+		},
+	})
+}
+
+// ConfirmTwoFactor 校验动态码并启用两步验证，返回一次性恢复码
+// 使用强制绑定临时令牌调用时，绑定成功后同时签发正式Token
+func ConfirmTwoFactor(c *gin.Context, jwtManager *utils.JWTManager, tokenExpire int) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required,len=6"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	if user.TOTPEnabled {
+		utils.BadRequest(c, "已启用两步验证")
+		return
+	}
+	if user.TOTPSecret == "" {
+		utils.BadRequest(c, "请先获取两步验证密钥")
+		return
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, user.TOTPLastStep, time.Now())
+	if !ok {
+		utils.Log.Warnf("两步验证绑定动态码错误: user_id: %d, ip: %s", user.ID, c.ClientIP())
+		utils.BadRequest(c, "动态码错误")
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		utils.Log.Errorf("启用两步验证失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "启用两步验证失败: "+err.Error())
+		return
+	}
+
+	data := gin.H{"recovery_codes": codes}
+	if c.GetString("token_purpose") == utils.PurposeTwoFactorSetup {
+		token, err := issueSessionToken(c, &user, jwtManager, tokenExpire)
+		if err != nil {
+			utils.Log.Errorf("生成Token失败: %v, user_id: %d", err, user.ID)
+			utils.InternalError(c, "登录失败: "+err.Error())
+			return
+		}
+		data["token"] = token
+	}
+
+	utils.Log.Infof("两步验证已启用: user_id: %d", user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "两步验证已启用，请妥善保存恢复码，恢复码仅显示一次",
+		"data":    data,
+	})
+}
+
+// DisableTwoFactor 关闭两步验证（需密码和动态码/恢复码；未设置密码的第三方登录账号只需动态码/恢复码）
+func DisableTwoFactor(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	if !user.TOTPEnabled {
+		utils.BadRequest(c, "未启用两步验证")
+		return
+	}
+	if user.TwoFactorRequired {
+		utils.Forbidden(c, "管理员要求该账号必须启用两步验证")
+		return
+	}
+	if !user.PasswordUnset && !user.CheckPassword(req.Password) {
+		utils.Unauthorized(c, "密码错误")
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, req.RecoveryCode) {
+		utils.Log.Warnf("关闭两步验证校验失败: user_id: %d, ip: %s", user.ID, c.ClientIP())
+		utils.Unauthorized(c, "动态码或恢复码错误")
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   false,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		utils.Log.Errorf("关闭两步验证失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "关闭两步验证失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("两步验证已关闭: user_id: %d", user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "两步验证已关闭",
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（旧恢复码全部作废）
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required,len=6"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+	if !user.TOTPEnabled {
+		utils.BadRequest(c, "未启用两步验证")
+		return
+	}
+	if !verifySecondFactor(&user, req.Code, "") {
+		utils.Unauthorized(c, "动态码错误")
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		utils.Log.Errorf("生成恢复码失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "生成恢复码失败: "+err.Error())
+		return
+	}
+
+	utils.Log.Infof("恢复码已重新生成: user_id: %d", user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "恢复码已重新生成，旧恢复码已失效",
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// LoginTwoFactor 两步登录第二步：提交临时令牌与动态码/恢复码，换取正式Token
+func LoginTwoFactor(c *gin.Context, jwtManager *utils.JWTManager, tokenExpire int) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Log.Warnf("两步验证参数错误: %v, ip: %s", err, c.ClientIP())
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		utils.BadRequest(c, "请输入动态码或恢复码")
+		return
+	}
+
+	claims, err := jwtManager.ParseToken(req.ChallengeToken)
+	if err != nil || claims.Purpose != utils.PurposeTwoFactorLogin {
+		utils.Unauthorized(c, "临时令牌无效或已过期，请重新登录")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, claims.UserID).Error; err != nil || !user.TOTPEnabled {
+		utils.Unauthorized(c, "临时令牌无效或已过期，请重新登录")
+		return
+	}
+
+	if !verifySecondFactor(&user, req.Code, req.RecoveryCode) {
+		utils.Log.Warnf("两步验证失败: %s, ip: %s", user.Username, c.ClientIP())
+		utils.Unauthorized(c, "动态码或恢复码错误")
+		return
+	}
+
+	loginSuccess(c, &user, jwtManager, tokenExpire)
+}
+
+// verifySecondFactor 校验动态码或恢复码，校验成功会消耗对应的时间步/恢复码
+func verifySecondFactor(user *models.User, code, recoveryCode string) bool {
+	secondFactorFailures.Lock()
+	record := secondFactorFailures.m[user.ID]
+	if record != nil && time.Since(record.first) > secondFactorLockWindow {
+		delete(secondFactorFailures.m, user.ID)
+		record = nil
+	}
+	locked := record != nil && record.count >= secondFactorMaxFailures
+	secondFactorFailures.Unlock()
+	if locked {
+		utils.Log.Warnf("两步验证失败次数过多，暂时锁定: user_id: %d", user.ID)
+		return false
+	}
+
+	ok := checkSecondFactor(user, code, recoveryCode)
+
+	secondFactorFailures.Lock()
+	if ok {
+		delete(secondFactorFailures.m, user.ID)
+	} else if record := secondFactorFailures.m[user.ID]; record != nil {
+		record.count++
+	} else {
+		secondFactorFailures.m[user.ID] = &failureRecord{count: 1, first: time.Now()}
+	}
+	secondFactorFailures.Unlock()
+	return ok
+}
+
+// checkSecondFactor 实际校验动态码或恢复码
+func checkSecondFactor(user *models.User, code, recoveryCode string) bool {
+	if code != "" {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, code, user.TOTPLastStep, time.Now())
+		if !ok {
+			return false
+		}
+		// 条件更新防止并发请求重复使用同一动态码
+		result := config.DB.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return false
+		}
+		user.TOTPLastStep = step
+		return true
+	}
+
+	if recoveryCode != "" {
+		now := time.Now()
+		result := config.DB.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashRecoveryCode(recoveryCode)).
+			Update("used_at", &now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return false
+		}
+		utils.Log.Infof("使用恢复码登录: user_id: %d", user.ID)
+		return true
+	}
+
+	return false
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的一组，返回明文（仅此一次）
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	records := make([]models.RecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: utils.HashRecoveryCode(code)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 头像解码允许的最大像素数（约 4000x4000）
+const avatarMaxPixels = 16000000
+
+// avatarURLs 返回用户各尺寸头像地址，未上传头像时返回nil
+func avatarURLs(store storage.Storage, user *models.User) gin.H {
+	if user.Avatar == "" {
+		return nil
+	}
+	urls := gin.H{}
+	for _, size := range models.AvatarSizes {
+		urls[strconv.Itoa(size)] = store.URL(models.AvatarKey(user.Avatar, size))
+	}
+	return urls
+}
+
+// profileData 组装个人资料响应
+func profileData(store storage.Storage, user *models.User) gin.H {
+	return gin.H{
+		"user_id":      user.ID,
+		"username":     user.Username,
+		"display_name": user.DisplayName,
+		"bio":          user.Bio,
+		"website":      user.Website,
+		"avatar":       avatarURLs(store, user),
+		"created_at":   user.CreatedAt,
+	}
+}
+
+// GetProfile 获取当前用户个人信息
+func GetProfile(c *gin.Context, store storage.Storage) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.Log.Warnf("用户不存在: user_id: %d", userId)
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	data := profileData(store, &user)
+	data["email"] = user.Email
+	data["deletion_scheduled_at"] = user.DeletionScheduledAt
+	data["pending_comments"] = PendingCommentCount(&user)
+	if unread, err := unreadNotificationCount(user.ID); err == nil {
+		data["unread_notifications"] = unread
+	}
+	withFollowCounts(data, user.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "获取个人信息成功",
+		"data":    data,
+	})
+}
+
+// UpdateProfile 更新个人资料（未传的字段保持不变，传空字符串表示清空）
+func UpdateProfile(c *gin.Context, store storage.Storage) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	var req struct {
+		DisplayName *string `json:"display_name" binding:"omitempty,max=50"`
+		Bio         *string `json:"bio" binding:"omitempty,max=500"`
+		Website     *string `json:"website" binding:"omitempty,max=200"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Log.Warnf("更新资料参数错误: %v, user_id: %d", err, userId)
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	if req.Website != nil && *req.Website != "" && !utils.IsHTTPURL(*req.Website) {
+		utils.BadRequest(c, "个人网站必须是 http(s) 链接")
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.DisplayName != nil {
+		user.DisplayName = *req.DisplayName
+		updates["display_name"] = user.DisplayName
+	}
+	if req.Bio != nil {
+		user.Bio = *req.Bio
+		updates["bio"] = user.Bio
+	}
+	if req.Website != nil {
+		user.Website = *req.Website
+		updates["website"] = user.Website
+	}
+	if len(updates) > 0 {
+		if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+			utils.Log.Errorf("更新资料失败: %v, user_id: %d", err, userId)
+			utils.InternalError(c, "更新资料失败: "+err.Error())
+			return
+		}
+	}
+
+	utils.Log.Infof("个人资料更新成功: user_id: %d", userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "资料更新成功",
+		"data":    profileData(store, &user),
+	})
+}
+
+// UploadAvatar 上传头像（multipart字段名 avatar），生成多尺寸缩略图
+func UploadAvatar(c *gin.Context, store storage.Storage, maxSize int64) {
+	userId, exists := c.Get("user_id")
+	if !exists {
+		utils.Unauthorized(c, "未获取到用户信息")
+		return
+	}
+
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		utils.BadRequest(c, "缺少头像文件（avatar）")
+		return
+	}
+	if fileHeader.Size > maxSize {
+		utils.BadRequest(c, fmt.Sprintf("头像文件不能超过 %dKB", maxSize>>10))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.InternalError(c, "读取头像文件失败")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil || int64(len(data)) > maxSize {
+		utils.BadRequest(c, "读取头像文件失败")
+		return
+	}
+
+	img, _, err := utils.DecodeImage(data, avatarMaxPixels)
+	if err != nil {
+		utils.Log.Warnf("头像图片无效: %v, user_id: %d", err, userId)
+		utils.BadRequest(c, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	// 每次上传使用新的随机前缀，避免CDN/浏览器缓存旧头像
+	random := make([]byte, 8)
+	_, _ = rand.Read(random)
+	prefix := fmt.Sprintf("avatars/%d/%s", user.ID, hex.EncodeToString(random))
+
+	for _, size := range models.AvatarSizes {
+		thumb, err := utils.EncodeJPEG(utils.SquareThumbnail(img, size), 85)
+		if err != nil {
+			utils.Log.Errorf("生成头像缩略图失败: %v, user_id: %d", err, userId)
+			utils.InternalError(c, "头像处理失败")
+			return
+		}
+		if err := store.Save(models.AvatarKey(prefix, size), bytes.NewReader(thumb), "image/jpeg"); err != nil {
+			utils.Log.Errorf("保存头像失败: %v, user_id: %d", err, userId)
+			utils.InternalError(c, "保存头像失败")
+			return
+		}
+	}
+
+	oldPrefix := user.Avatar
+	if err := config.DB.Model(&user).Update("avatar", prefix).Error; err != nil {
+		utils.Log.Errorf("更新头像失败: %v, user_id: %d", err, userId)
+		utils.InternalError(c, "更新头像失败: "+err.Error())
+		return
+	}
+	user.Avatar = prefix
+
+	// 清理旧头像
+	if oldPrefix != "" {
+		for _, size := range models.AvatarSizes {
+			if err := store.Delete(models.AvatarKey(oldPrefix, size)); err != nil {
+				utils.Log.Warnf("删除旧头像失败: %v, key: %s", err, models.AvatarKey(oldPrefix, size))
+			}
+		}
+	}
+
+	utils.Log.Infof("头像上传成功: user_id: %d", userId)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "头像上传成功",
+		"data": gin.H{
+			"avatar": avatarURLs(store, &user),
+		},
+	})
+}
+
+// GetUserPage 公开作者主页：返回用户资料及其发布的文章
+func GetUserPage(c *gin.Context, store storage.Storage) {
+	username := c.Param("username")
+
+	var user models.User
+	if err := config.DB.Where("username = ?", username).First(&user).Error; err != nil {
+		utils.Log.Infof("用户不存在: %s, ip: %s", username, c.ClientIP())
+		utils.NotFound(c, "用户不存在")
+		return
+	}
+
+	var posts []models.Post
+	if err := config.DB.Select("id", "created_at", "updated_at", "title", "slug", "user_id").
+		Where("user_id = ?", user.ID).Order("created_at DESC").Find(&posts).Error; err != nil {
+		utils.Log.Errorf("获取作者文章失败: %v, user_id: %d", err, user.ID)
+		utils.InternalError(c, "获取作者文章失败: "+err.Error())
+		return
+	}
+
+	items := make([]gin.H, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, gin.H{
+			"id":         post.ID,
+			"title":      post.Title,
+			"slug":       post.Slug,
+			"created_at": post.CreatedAt,
+			"updated_at": post.UpdatedAt,
+		})
+	}
+
+	profile := profileData(store, &user)
+	withFollowCounts(profile, user.ID)
+	if viewer := optionalUserID(c); viewer != 0 && viewer != user.ID {
+		following, err := models.IsFollowing(config.DB, viewer, user.ID)
+		if err != nil {
+			utils.Log.Warnf("查询关注状态失败: %v, user_id: %d", err, viewer)
+		}
+		profile["followed_by_me"] = following
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"profile": profile,
+			"posts":   items,
+		},
+	})
+}
@@ -0,0 +1,283 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 文章中引用的图片：站内图片（本地文件或原站点地址）导入媒体库，并将引用改为新地址
+
+var (
+	markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^\s)>]+)>?`)
+	htmlRefPattern       = regexp.MustCompile(`(?i)<(img|a)\b[^>]*?\s(?:src|href)\s*=\s*["']([^"']+)["']`)
+	imageExtPattern      = regexp.MustCompile(`(?i)\.(jpe?g|png|gif|webp)$`)
+)
+
+// image 一张已读取的图片
+type image struct {
+	media models.Media // 待创建的媒体记录（不含用户和文章）
+	url   string       // 导入后的访问地址
+	fresh bool         // 本次导入新写入存储
+}
+
+// rewriteImages 导入文章引用的图片并替换地址，返回新内容和引用的图片
+func (r *run) rewriteImages(src *Post, item *Item, stats *itemStats) (string, []*image) {
+	var images []*image
+	seen := map[string]bool{}
+	var remoteSkipped int
+	replace := func(ref string, htmlRef bool) string {
+		img, err := r.loadImage(src, ref)
+		if errors.Is(err, errRemoteSkipped) {
+			remoteSkipped++
+			return ref
+		}
+		if err != nil {
+			item.Warnings = append(item.Warnings, fmt.Sprintf("图片未导入: %s（%v）", ref, err))
+			return ref
+		}
+		if img == nil {
+			return ref
+		}
+		if !seen[img.media.Key] {
+			seen[img.media.Key] = true
+			images = append(images, img)
+			if img.fresh {
+				stats.newKeys = append(stats.newKeys, img.media)
+			}
+		}
+		if htmlRef {
+			return html.EscapeString(img.url)
+		}
+		return img.url
+	}
+
+	content := replaceGroup(src.Content, markdownImagePattern, 1, func(ref string, _ []string) string {
+		return replace(ref, false)
+	})
+	content = replaceGroup(content, htmlRefPattern, 2, func(ref string, groups []string) string {
+		// 链接只处理指向图片文件的（如点击查看原图）
+		if strings.EqualFold(groups[1], "a") && !imageExtPattern.MatchString(strings.SplitN(ref, "?", 2)[0]) {
+			return ref
+		}
+		return replace(ref, true)
+	})
+	if remoteSkipped > 0 {
+		item.Warnings = append(item.Warnings, fmt.Sprintf("%d 张原站点图片未下载（试运行或未开启远程下载）", remoteSkipped))
+	}
+	return content, images
+}
+
+// replaceGroup 对正则的第n个分组调用fn替换，其余内容不变
+func replaceGroup(s string, re *regexp.Regexp, n int, fn func(value string, groups []string) string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		groups := make([]string, len(m)/2)
+		for i := range groups {
+			if m[2*i] >= 0 {
+				groups[i] = s[m[2*i]:m[2*i+1]]
+			}
+		}
+		b.WriteString(s[last:m[2*n]])
+		b.WriteString(fn(groups[n], groups))
+		last = m[2*n+1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+var errRemoteSkipped = errors.New("未下载远程图片")
+
+// siteHost 是否为原站点域名
+func (r *run) siteHost(host string) bool {
+	for _, h := range r.site.Hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadImage 读取图片引用，非站内图片返回nil
+func (r *run) loadImage(src *Post, ref string) (*image, error) {
+	u, err := url.Parse(html.UnescapeString(strings.TrimSpace(ref)))
+	if err != nil {
+		return nil, nil
+	}
+
+	var cacheKey string
+	var read func() ([]byte, error)
+	switch {
+	case u.Host != "" && (u.Scheme == "" || u.Scheme == "http" || u.Scheme == "https"):
+		if !r.siteHost(u.Hostname()) {
+			return nil, nil
+		}
+		if r.site.files != nil {
+			// 目录导入时原站点地址对应站点目录中的静态文件
+			cacheKey, read = r.localFile(path.Join(r.site.staticRoot, u.Path))
+			break
+		}
+		if r.opts.DryRun || !r.opts.FetchRemote {
+			return nil, errRemoteSkipped
+		}
+		if u.Scheme == "" {
+			u.Scheme = "https"
+		}
+		cacheKey, read = u.String(), func() ([]byte, error) { return r.fetch(u.String()) }
+	case u.Scheme != "" || u.Path == "" || r.site.files == nil:
+		return nil, nil
+	case strings.HasPrefix(u.Path, "/"):
+		cacheKey, read = r.localFile(path.Join(r.site.staticRoot, u.Path))
+	default:
+		cacheKey, read = r.localFile(path.Join(src.dir, u.Path))
+	}
+	if read == nil {
+		return nil, errors.New("路径无效")
+	}
+
+	if img, ok := r.images[cacheKey]; ok {
+		return img, nil
+	}
+	data, err := read()
+	if err != nil {
+		return nil, err
+	}
+	img, err := r.storeImage(data, path.Base(u.Path))
+	if err != nil {
+		return nil, err
+	}
+	r.images[cacheKey] = img
+	return img, nil
+}
+
+// localFile 目录导入时读取站点目录中的文件
+func (r *run) localFile(name string) (string, func() ([]byte, error)) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !fs.ValidPath(name) {
+		return "", nil
+	}
+	return "file:" + name, func() ([]byte, error) {
+		info, err := fs.Stat(r.site.files, name)
+		if err != nil {
+			return nil, errors.New("文件不存在")
+		}
+		if info.Size() > r.opts.MediaMaxSize {
+			return nil, errors.New("文件超过大小上限")
+		}
+		return fs.ReadFile(r.site.files, name)
+	}
+}
+
+// fetch 下载原站点的图片
+func (r *run) fetch(rawURL string) ([]byte, error) {
+	client := &http.Client{Timeout: r.opts.FetchTimeout}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, r.opts.MediaMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > r.opts.MediaMaxSize {
+		return nil, errors.New("文件超过大小上限")
+	}
+	return data, nil
+}
+
+// storeImage 与媒体上传相同：按内容识别类型、去除元数据、按内容哈希存储
+// 试运行时只计算存储路径，不写入文件
+func (r *run) storeImage(data []byte, filename string) (*image, error) {
+	contentType, ext, err := utils.SniffMediaType(data)
+	if err != nil || !strings.HasPrefix(contentType, "image/") {
+		return nil, errors.New("不是支持的图片格式")
+	}
+	if data, err = utils.StripImageMetadata(data, contentType); err != nil {
+		return nil, err
+	}
+	key, sum := utils.MediaKey(data, ext, models.MediaPublic)
+
+	img := &image{
+		media: models.Media{
+			Key:              key,
+			Filename:         utils.SanitizeFilename(filename),
+			ContentType:      contentType,
+			Size:             int64(len(data)),
+			SHA256:           sum,
+			Visibility:       models.MediaPublic,
+			ProcessingStatus: models.MediaProcessingPending,
+		},
+		url: r.opts.Store.URL(key),
+	}
+	if r.opts.DryRun {
+		return img, nil
+	}
+	var existing int64
+	if err := config.DB.Model(&models.Media{}).Where("storage_key = ?", key).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing == 0 {
+		if err := r.opts.Store.Save(key, bytes.NewReader(data), contentType); err != nil {
+			return nil, err
+		}
+		img.fresh = true
+	}
+	return img, nil
+}
+
+// saveMedia 为文章引用的图片创建媒体记录，重复导入时不重复创建
+func (r *run) saveMedia(tx *gorm.DB, post *models.Post, images []*image, stats *itemStats) error {
+	for _, img := range images {
+		var count int64
+		if err := tx.Model(&models.Media{}).Where("post_id = ? AND storage_key = ?", post.ID, img.media.Key).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		media := img.media
+		postID := post.ID
+		media.UserID = post.UserID
+		media.PostID = &postID
+		if err := tx.Create(&media).Error; err != nil {
+			return err
+		}
+		stats.media = append(stats.media, media.ID)
+		stats.images++
+	}
+	return nil
+}
+
+// forgetImages 文章导入失败、新写入的文件已清理时，从缓存中移除对应图片
+func (r *run) forgetImages(items []models.Media) {
+	for _, m := range items {
+		for ref, img := range r.images {
+			if img.media.Key == m.Key {
+				delete(r.images, ref)
+			}
+		}
+	}
+}
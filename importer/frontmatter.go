@@ -0,0 +1,193 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter 文件头部的元数据（YAML以---包围、TOML以+++包围、JSON为开头的对象）
+type frontMatter map[string]interface{}
+
+// splitFrontMatter 拆分front matter与正文，没有front matter时返回空map
+func splitFrontMatter(data []byte) (frontMatter, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	meta := frontMatter{}
+
+	switch {
+	case strings.HasPrefix(text, "---\n"):
+		head, body, ok := cutDelimited(text[4:], "---", "...")
+		if !ok {
+			return nil, "", errors.New("front matter 缺少结束标记 ---")
+		}
+		if err := yaml.Unmarshal([]byte(head), &meta); err != nil {
+			return nil, "", fmt.Errorf("YAML front matter 解析失败: %w", err)
+		}
+		return meta, body, nil
+	case strings.HasPrefix(text, "+++\n"):
+		head, body, ok := cutDelimited(text[4:], "+++")
+		if !ok {
+			return nil, "", errors.New("front matter 缺少结束标记 +++")
+		}
+		if err := toml.Unmarshal([]byte(head), &meta); err != nil {
+			return nil, "", fmt.Errorf("TOML front matter 解析失败: %w", err)
+		}
+		return meta, body, nil
+	case strings.HasPrefix(text, "{"):
+		dec := json.NewDecoder(strings.NewReader(text))
+		if err := dec.Decode(&meta); err != nil {
+			return nil, "", fmt.Errorf("JSON front matter 解析失败: %w", err)
+		}
+		return meta, strings.TrimLeft(text[dec.InputOffset():], "\n"), nil
+	}
+	return meta, text, nil
+}
+
+// cutDelimited 按独占一行的结束标记拆分
+func cutDelimited(text string, delims ...string) (head, body string, ok bool) {
+	lines := strings.SplitAfter(text, "\n")
+	offset := 0
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, " \t\n")
+		for _, d := range delims {
+			if trimmed == d {
+				return text[:offset], strings.TrimLeft(text[offset+len(line):], "\n"), true
+			}
+		}
+		offset += len(line)
+	}
+	return "", "", false
+}
+
+// get 按顺序查找第一个存在的键（不区分大小写）
+func (m frontMatter) get(keys ...string) (interface{}, bool) {
+	for _, key := range keys {
+		if v, ok := m[key]; ok && v != nil {
+			return v, true
+		}
+		for k, v := range m {
+			if strings.EqualFold(k, key) && v != nil {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// str 字符串值，列表取第一个元素
+func (m frontMatter) str(keys ...string) string {
+	v, ok := m.get(keys...)
+	if !ok {
+		return ""
+	}
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case []interface{}:
+		if len(val) > 0 {
+			return strings.TrimSpace(fmt.Sprint(val[0]))
+		}
+		return ""
+	case map[string]interface{}:
+		return ""
+	default:
+		return strings.TrimSpace(fmt.Sprint(val))
+	}
+}
+
+// strs 字符串列表；值为字符串时按 sep 拆分（sep为空时不拆分）
+func (m frontMatter) strs(sep string, keys ...string) []string {
+	var result []string
+	for _, key := range keys {
+		v, ok := m.get(key)
+		if !ok {
+			continue
+		}
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				result = append(result, fmt.Sprint(item))
+			}
+		case string:
+			switch sep {
+			case "":
+				result = append(result, val)
+			case " ":
+				result = append(result, strings.Fields(val)...)
+			default:
+				result = append(result, strings.Split(val, sep)...)
+			}
+		default:
+			result = append(result, fmt.Sprint(val))
+		}
+	}
+	return result
+}
+
+// boolean 布尔值，兼容字符串形式
+func (m frontMatter) boolean(key string) (value, ok bool) {
+	v, ok := m.get(key)
+	if !ok {
+		return false, false
+	}
+	switch val := v.(type) {
+	case bool:
+		return val, true
+	case string:
+		s := strings.ToLower(strings.TrimSpace(val))
+		return s == "true" || s == "yes" || s == "1", true
+	}
+	return false, false
+}
+
+// date 时间值，YAML/TOML的日期类型或常见格式的字符串
+func (m frontMatter) date(keys ...string) time.Time {
+	v, ok := m.get(keys...)
+	if !ok {
+		return time.Time{}
+	}
+	switch val := v.(type) {
+	case time.Time:
+		return val
+	case toml.LocalDateTime:
+		return val.AsTime(time.Local)
+	case toml.LocalDate:
+		return val.AsTime(time.Local)
+	case string:
+		return parseTime(val)
+	}
+	return time.Time{}
+}
+
+// 来源中常见的时间格式，未带时区的按本地时区解析
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04 -0700",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseTime 解析时间字符串，无法解析时返回零值
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
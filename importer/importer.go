@@ -0,0 +1,556 @@
+package importer
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 内容导入：Markdown（YAML/TOML front matter）目录、Hugo/Jekyll 站点目录和 WordPress WXR 导出文件
+// 各格式先解析为统一的 Site 结构，再由 Run 写入数据库。
+// ImportRecord 记录来源条目与本地数据的对应关系，重复导入同一来源时只更新有变化的文章、追加新评论
+
+// 支持的导入格式
+const (
+	FormatMarkdown = "markdown"
+	FormatHugo     = "hugo"
+	FormatJekyll   = "jekyll"
+	FormatWXR      = "wxr"
+)
+
+// 导入结果
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionUnchanged = "unchanged"
+	ActionSkipped   = "skipped"
+	ActionFailed    = "failed"
+)
+
+// 与文章接口一致的长度限制
+const (
+	maxTitleLength = 100
+	maxTagLength   = 50
+)
+
+// Author 来源中的作者/评论者
+type Author struct {
+	Key         string `json:"key"`          // 登录名或作者名称
+	Email       string `json:"email"`        // 邮箱，用于匹配已有用户
+	DisplayName string `json:"display_name"` // 显示名称
+}
+
+// Comment 待导入的评论
+type Comment struct {
+	ExternalID string    `json:"external_id"`
+	Author     Author    `json:"author"`
+	Content    string    `json:"content"`
+	Date       time.Time `json:"date"`
+}
+
+// Post 待导入的文章
+type Post struct {
+	ExternalID    string    `json:"external_id"` // 来源中的唯一标识：文件路径或WordPress文章ID
+	Title         string    `json:"title"`
+	Slug          string    `json:"slug"`
+	Content       string    `json:"content"`
+	ContentFormat string    `json:"content_format"`
+	Author        Author    `json:"author"`
+	Tags          []string  `json:"tags"`
+	Date          time.Time `json:"date"`
+	Updated       time.Time `json:"updated"`
+	Comments      []Comment `json:"comments"`
+	Warnings      []string  `json:"-"` // 解析阶段产生的提示
+
+	dir string // 文章文件所在目录，相对路径的图片基于此查找
+}
+
+// Site 解析后的导入内容
+type Site struct {
+	Format  string
+	Source  string   // 来源标识，ImportRecord 按来源区分
+	Hosts   []string // 原站点域名，其下的图片地址视为站内图片
+	Posts   []Post
+	Skipped []Item // 解析阶段跳过的条目（草稿、页面等）
+
+	files      fs.FS  // 目录导入时的文件系统，用于读取本地图片
+	staticRoot string // 以 / 开头的图片路径对应的目录
+}
+
+// Options 导入选项
+type Options struct {
+	Source        string            // 覆盖来源标识
+	DryRun        bool              // 仅生成报告，不写入数据库和存储
+	DefaultAuthor *models.User      // 条目缺少作者信息时使用
+	AuthorMap     map[string]string // 来源作者（登录名或邮箱）到本地用户名的映射
+	FetchRemote   bool              // 是否下载原站点的远程图片
+	FetchTimeout  time.Duration
+	MediaMaxSize  int64
+	Store         storage.Storage
+	OnMedia       func(mediaID uint) // 新建图片媒体记录后回调，用于提交后台处理
+}
+
+// Item 单个条目的导入结果
+type Item struct {
+	ExternalID string   `json:"external_id"`
+	Title      string   `json:"title,omitempty"`
+	Action     string   `json:"action"`
+	PostID     uint     `json:"post_id,omitempty"`
+	Slug       string   `json:"slug,omitempty"`
+	Comments   int      `json:"comments,omitempty"` // 新导入的评论数
+	Images     int      `json:"images,omitempty"`   // 导入的图片数
+	Message    string   `json:"message,omitempty"`  // 跳过或失败的原因
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// Report 导入报告
+type Report struct {
+	Format       string         `json:"format"`
+	Source       string         `json:"source"`
+	DryRun       bool           `json:"dry_run"`
+	Posts        map[string]int `json:"posts"` // 按结果统计的文章数
+	Comments     int            `json:"comments"`
+	Images       int            `json:"images"`
+	UsersCreated int            `json:"users_created"`
+	Items        []Item         `json:"items"`
+}
+
+// 同一时间只允许一个导入任务，避免重复创建用户和文章
+var importMu sync.Mutex
+
+// itemStats 单篇文章事务内的统计，事务提交后计入报告
+type itemStats struct {
+	users    int
+	images   int
+	comments int
+	media    []uint
+	newKeys  []models.Media // 本次新写入存储的文件，事务失败时清理
+}
+
+// Run 将解析结果写入数据库并返回导入报告
+// 每篇文章在独立事务中导入，单篇失败不影响其他文章；试运行时写入数据库快照，完成后丢弃
+func Run(site *Site, opts Options) (*Report, error) {
+	importMu.Lock()
+	defer importMu.Unlock()
+
+	if opts.Source != "" {
+		site.Source = opts.Source
+	}
+	report := &Report{
+		Format: site.Format,
+		Source: site.Source,
+		DryRun: opts.DryRun,
+		Posts:  map[string]int{},
+		Items:  []Item{},
+	}
+	for _, item := range site.Skipped {
+		report.Posts[item.Action]++
+		report.Items = append(report.Items, item)
+	}
+
+	db := config.DB
+	if opts.DryRun {
+		snapshot, cleanup, err := dryRunDB()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		db = snapshot
+	}
+
+	// 按发布时间先后导入，文章ID与时间顺序一致
+	posts := append([]Post(nil), site.Posts...)
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].Date.Before(posts[j].Date) })
+
+	imp := &run{site: site, opts: opts, images: map[string]*image{}}
+	for i := range posts {
+		item, stats := imp.importPost(db, &posts[i])
+		report.Posts[item.Action]++
+		report.Items = append(report.Items, item)
+		if item.Action == ActionFailed {
+			if !opts.DryRun && stats != nil {
+				utils.DeleteUnreferencedMedia(opts.Store, stats.newKeys...)
+				imp.forgetImages(stats.newKeys)
+			}
+			continue
+		}
+		if stats != nil {
+			report.UsersCreated += stats.users
+			report.Images += stats.images
+			report.Comments += stats.comments
+			if !opts.DryRun && opts.OnMedia != nil {
+				for _, id := range stats.media {
+					opts.OnMedia(id)
+				}
+			}
+		}
+	}
+
+	utils.Log.Infof("内容导入完成: format: %s, source: %s, dry_run: %v, posts: %v, comments: %d, images: %d, users_created: %d",
+		report.Format, report.Source, report.DryRun, report.Posts, report.Comments, report.Images, report.UsersCreated)
+	return report, nil
+}
+
+// dryRunDB 试运行使用的数据库快照。
+// 在主库的事务中试运行会在整个导入期间持有SQLite写锁，阻塞服务的其他写入；
+// VACUUM INTO 只需读取一致的快照，试运行的写入都落在临时副本上
+func dryRunDB() (*gorm.DB, func(), error) {
+	// VACUUM INTO 要求目标文件不存在
+	dir, err := os.MkdirTemp("", "blog-import-")
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := filepath.Join(dir, "dry-run.db")
+	if err := config.DB.Exec("VACUUM INTO ?", snapshot).Error; err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("生成数据库快照失败: %w", err)
+	}
+	db, err := gorm.Open(sqlite.Open(snapshot), &gorm.Config{Logger: config.DB.Config.Logger})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		os.RemoveAll(dir)
+	}
+	return db, cleanup, nil
+}
+
+// run 一次导入的状态
+type run struct {
+	site   *Site
+	opts   Options
+	images map[string]*image // 已处理的图片地址，同一图片被多篇文章引用时只读取一次
+}
+
+// checksum 条目内容摘要，来源未变化时重复导入不做任何修改
+func checksum(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// importPost 导入单篇文章及其评论、图片
+func (r *run) importPost(db *gorm.DB, src *Post) (Item, *itemStats) {
+	item := Item{ExternalID: src.ExternalID, Title: src.Title, Warnings: append([]string(nil), src.Warnings...)}
+	sum := checksum(src)
+
+	var record models.ImportRecord
+	err := db.Where("source = ? AND kind = ? AND external_id = ?", r.site.Source, models.ImportKindPost, src.ExternalID).
+		First(&record).Error
+	exists := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		item.Action, item.Message = ActionFailed, err.Error()
+		return item, nil
+	}
+
+	var post models.Post
+	if exists {
+		if err := db.First(&post, record.LocalID).Error; err != nil {
+			item.Action, item.Message = ActionSkipped, "之前导入的文章已在本地删除"
+			return item, nil
+		}
+		item.PostID, item.Slug = post.ID, post.Slug
+		if record.Checksum == sum {
+			item.Action = ActionUnchanged
+			return item, nil
+		}
+	}
+
+	stats := &itemStats{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		userID, err := r.resolveUser(tx, src.Author, stats)
+		if err != nil {
+			return err
+		}
+
+		title := strings.TrimSpace(src.Title)
+		if utf8.RuneCountInString(title) > maxTitleLength {
+			title = string([]rune(title)[:maxTitleLength])
+			item.Warnings = append(item.Warnings, fmt.Sprintf("标题超过%d个字符，已截断", maxTitleLength))
+		}
+		tags := r.tagNames(src.Tags, &item)
+		content, images := r.rewriteImages(src, &item, stats)
+		if strings.TrimSpace(content) == "" {
+			return errors.New("文章内容为空")
+		}
+
+		post.Title = title
+		post.Content = content
+		post.ContentFormat = src.ContentFormat
+		post.UserID = userID
+		created := src.Date
+		if created.IsZero() {
+			created = time.Now()
+		}
+		updated := src.Updated
+		if updated.Before(created) {
+			updated = created
+		}
+
+		tagRows, err := models.FindOrCreateTags(tx, tags)
+		if err != nil {
+			return err
+		}
+		if exists {
+			if err := tx.Model(&post).Association("Tags").Replace(tagRows); err != nil {
+				return err
+			}
+			if err := tx.Omit("Tags", "ViewCount").Save(&post).Error; err != nil {
+				return err
+			}
+		} else {
+			post.Slug, err = r.postSlug(tx, src.Slug, title)
+			if err != nil {
+				return err
+			}
+			post.Tags = tagRows
+			if err := tx.Create(&post).Error; err != nil {
+				return err
+			}
+		}
+		// 保留原站点的发布和修改时间
+		if err := tx.Model(&post).UpdateColumns(map[string]interface{}{
+			"created_at": created,
+			"updated_at": updated,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := r.saveMedia(tx, &post, images, stats); err != nil {
+			return err
+		}
+		if err := r.importComments(tx, src, &post, stats); err != nil {
+			return err
+		}
+
+		if exists {
+			return tx.Model(&record).Update("checksum", sum).Error
+		}
+		return tx.Create(&models.ImportRecord{
+			Source: r.site.Source, Kind: models.ImportKindPost, ExternalID: src.ExternalID, LocalID: post.ID, Checksum: sum,
+		}).Error
+	})
+	if err != nil {
+		item.Action, item.Message = ActionFailed, err.Error()
+		utils.Log.Warnf("导入文章失败: %v, source: %s, external_id: %s", err, r.site.Source, src.ExternalID)
+		return item, stats
+	}
+
+	item.PostID, item.Slug = post.ID, post.Slug
+	item.Comments, item.Images = stats.comments, stats.images
+	item.Action = ActionCreated
+	if exists {
+		item.Action = ActionUpdated
+	}
+	return item, stats
+}
+
+// postSlug 使用来源中的slug（不合法时重新生成），与现有文章冲突时追加序号
+func (r *run) postSlug(tx *gorm.DB, slug, title string) (string, error) {
+	base := strings.ToLower(strings.TrimSpace(slug))
+	if !models.IsValidSlug(base) {
+		base = slug
+		if strings.TrimSpace(base) == "" {
+			base = title
+		}
+		base = models.Slugify(base)
+	}
+	return models.UniquePostSlug(tx, base, 0)
+}
+
+// tagNames 规范化标签，超长标签和超出数量上限的标签忽略并给出提示
+func (r *run) tagNames(names []string, item *Item) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range models.NormalizeTagNames(names) {
+		if utf8.RuneCountInString(name) > maxTagLength {
+			item.Warnings = append(item.Warnings, fmt.Sprintf("标签“%s”超过%d个字符，已忽略", name, maxTagLength))
+			continue
+		}
+		result = append(result, name)
+	}
+	if len(result) > models.MaxPostTags {
+		item.Warnings = append(item.Warnings, fmt.Sprintf("标签超过%d个，已忽略：%s",
+			models.MaxPostTags, strings.Join(result[models.MaxPostTags:], ", ")))
+		result = result[:models.MaxPostTags]
+	}
+	return result
+}
+
+// importComments 导入尚未导入过的评论，已导入的评论不做修改
+func (r *run) importComments(tx *gorm.DB, src *Post, post *models.Post, stats *itemStats) error {
+	for _, c := range src.Comments {
+		if strings.TrimSpace(c.Content) == "" {
+			continue
+		}
+		var count int64
+		if err := tx.Model(&models.ImportRecord{}).Where("source = ? AND kind = ? AND external_id = ?",
+			r.site.Source, models.ImportKindComment, c.ExternalID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		userID, err := r.resolveUser(tx, c.Author, stats)
+		if err != nil {
+			return fmt.Errorf("评论 %s: %w", c.ExternalID, err)
+		}
+		date := c.Date
+		if date.IsZero() {
+			date = time.Now()
+		}
+		comment := models.Comment{
+			Content: c.Content,
+			UserID:  userID,
+			PostID:  post.ID,
+		}
+		comment.CreatedAt, comment.UpdatedAt = date, date
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.ImportRecord{
+			Source: r.site.Source, Kind: models.ImportKindComment, ExternalID: c.ExternalID, LocalID: comment.ID,
+		}).Error; err != nil {
+			return err
+		}
+		stats.comments++
+	}
+	return nil
+}
+
+// resolveUser 将来源作者对应到本地用户：
+// 映射表 → 之前导入时的对应关系 → 同名或同邮箱的已有用户 → 创建无密码用户（可通过找回密码或第三方登录使用）
+func (r *run) resolveUser(tx *gorm.DB, author Author, stats *itemStats) (uint, error) {
+	key := strings.TrimSpace(author.Key)
+	email := strings.ToLower(strings.TrimSpace(author.Email))
+	if key == "" && email == "" {
+		if r.opts.DefaultAuthor == nil {
+			return 0, errors.New("缺少作者信息，且未指定默认作者")
+		}
+		return r.opts.DefaultAuthor.ID, nil
+	}
+
+	for _, k := range []string{key, email} {
+		if username, ok := r.opts.AuthorMap[k]; ok && k != "" {
+			var user models.User
+			if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+				return 0, fmt.Errorf("作者映射的用户不存在: %s", username)
+			}
+			return user.ID, nil
+		}
+	}
+
+	externalID := key
+	if externalID == "" {
+		externalID = "email:" + email
+	}
+	var record models.ImportRecord
+	err := tx.Where("source = ? AND kind = ? AND external_id = ?", r.site.Source, models.ImportKindUser, externalID).
+		First(&record).Error
+	if err == nil {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("id = ?", record.LocalID).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		if count > 0 {
+			return record.LocalID, nil
+		}
+		// 对应的用户已注销，重新匹配
+		if err := tx.Unscoped().Delete(&record).Error; err != nil {
+			return 0, err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	var user models.User
+	query := tx.Where("1 = 0")
+	if key != "" {
+		query = query.Or("LOWER(username) = LOWER(?)", key)
+	}
+	if email != "" {
+		query = query.Or("LOWER(email) = ?", email)
+	}
+	err = query.Order("id").First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := r.createUser(tx, key, email, author.DisplayName, &user); err != nil {
+			return 0, err
+		}
+		stats.users++
+	} else if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Create(&models.ImportRecord{
+		Source: r.site.Source, Kind: models.ImportKindUser, ExternalID: externalID, LocalID: user.ID,
+	}).Error; err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// createUser 为来源作者创建本地用户，未设置密码，无法直接用密码登录
+func (r *run) createUser(tx *gorm.DB, key, email, displayName string, user *models.User) error {
+	// 作者名转写为ASCII（中文转拼音），无法转写时使用邮箱前缀
+	base := strings.ReplaceAll(models.TagSlug(key), "-", "_")
+	if base == "" && email != "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	if base == "" {
+		base = "author"
+	}
+	username, err := models.UniqueUsername(tx, base)
+	if err != nil {
+		return err
+	}
+	if displayName == "" {
+		displayName = key
+	}
+	if utf8.RuneCountInString(displayName) > 50 {
+		displayName = string([]rune(displayName)[:50])
+	}
+	if email != "" {
+		var count int64
+		tx.Model(&models.User{}).Where("email = ?", email).Count(&count)
+		if count > 0 {
+			email = ""
+		}
+	}
+
+	randomPwd := make([]byte, 24)
+	if _, err := rand.Read(randomPwd); err != nil {
+		return err
+	}
+	*user = models.User{
+		Username:      username,
+		Password:      base64.RawURLEncoding.EncodeToString(randomPwd),
+		Email:         email,
+		DisplayName:   displayName,
+		Role:          models.RoleUser,
+		PasswordUnset: true,
+	}
+	return tx.Create(user).Error
+}
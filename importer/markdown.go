@@ -0,0 +1,414 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"go-blog-system/render"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 目录导入：通用 Markdown 目录、Hugo 站点（content/ + static/）、Jekyll 站点（_posts/）
+
+// Hugo 站点配置文件，按优先级排列
+var hugoConfigFiles = []string{
+	"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json",
+	"config.toml", "config.yaml", "config.yml", "config.json",
+	"config/_default/hugo.toml", "config/_default/hugo.yaml", "config/_default/config.toml", "config/_default/config.yaml",
+}
+
+var jekyllPostName = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)\.(md|markdown|html)$`)
+
+// OpenZip 打开zip压缩包，压缩包内只有一个顶层目录时以该目录为根
+func OpenZip(r io.ReaderAt, size int64) (fs.FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("zip压缩包格式错误: %w", err)
+	}
+	entries, err := fs.ReadDir(zr, ".")
+	if err != nil {
+		return nil, err
+	}
+	var dirs []fs.DirEntry
+	for _, e := range entries {
+		if e.Name() == "__MACOSX" {
+			continue
+		}
+		dirs = append(dirs, e)
+	}
+	if len(dirs) == 1 && dirs[0].IsDir() {
+		return fs.Sub(zr, dirs[0].Name())
+	}
+	return zr, nil
+}
+
+// DetectFormat 根据目录结构识别格式：有 _posts 为 Jekyll，有 Hugo 配置文件和内容目录为 Hugo，否则为通用 Markdown
+func DetectFormat(fsys fs.FS) string {
+	if isDir(fsys, "_posts") {
+		return FormatJekyll
+	}
+	if name, _ := hugoConfig(fsys); name != "" {
+		return FormatHugo
+	}
+	return FormatMarkdown
+}
+
+func isDir(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
+
+// LoadDir 解析内容目录，format为空时自动识别；label用于在没有站点地址时生成来源标识（如目录名）
+func LoadDir(fsys fs.FS, format, label string) (*Site, error) {
+	if format == "" {
+		format = DetectFormat(fsys)
+	}
+	l := &dirLoader{site: &Site{Format: format, files: fsys, staticRoot: "."}, fsys: fsys}
+	var err error
+	switch format {
+	case FormatHugo:
+		err = l.loadHugo()
+	case FormatJekyll:
+		err = l.loadJekyll()
+	case FormatMarkdown:
+		err = l.walk(".", func(name string) bool { return isMarkdown(name) }, l.markdownPost)
+	default:
+		return nil, fmt.Errorf("不支持的目录格式: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.site.Source == "" {
+		l.site.Source = format + ":" + label
+	}
+	return l.site, nil
+}
+
+type dirLoader struct {
+	site          *Site
+	fsys          fs.FS
+	defaultAuthor string
+}
+
+// setBaseURL 以原站点地址作为来源标识，其域名下的图片视为站内图片
+func (l *dirLoader) setBaseURL(baseURL string) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || u.Host == "" {
+		return
+	}
+	l.site.Source = l.site.Format + ":" + strings.TrimRight(u.Scheme+"://"+u.Host+u.Path, "/")
+	l.site.Hosts = append(l.site.Hosts, u.Hostname())
+}
+
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// walk 遍历目录下符合条件的文件，跳过隐藏目录；单个文件解析失败记为失败条目
+func (l *dirLoader) walk(root string, match func(name string) bool, parse func(name string, meta frontMatter, body string, modTime time.Time) error) error {
+	return fs.WalkDir(l.fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base := d.Name()
+		if d.IsDir() {
+			if name != root && (strings.HasPrefix(base, ".") || base == "__MACOSX" || base == "node_modules") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(base, ".") || !match(name) {
+			return nil
+		}
+		data, err := fs.ReadFile(l.fsys, name)
+		if err != nil {
+			return err
+		}
+		var modTime time.Time
+		if info, err := d.Info(); err == nil {
+			modTime = info.ModTime()
+		}
+		meta, body, err := splitFrontMatter(data)
+		if err == nil {
+			err = parse(name, meta, body, modTime)
+		}
+		if err != nil {
+			l.site.Skipped = append(l.site.Skipped, Item{ExternalID: name, Action: ActionFailed, Message: err.Error()})
+		}
+		return nil
+	})
+}
+
+// skip 记录解析阶段跳过的条目
+func (l *dirLoader) skip(name, title, reason string) {
+	l.site.Skipped = append(l.site.Skipped, Item{ExternalID: name, Title: title, Action: ActionSkipped, Message: reason})
+}
+
+// newPost 由front matter中的通用字段生成文章
+func (l *dirLoader) newPost(name string, meta frontMatter, body string, modTime time.Time) *Post {
+	post := &Post{
+		ExternalID:    name,
+		Title:         meta.str("title"),
+		Slug:          meta.str("slug"),
+		Content:       body,
+		ContentFormat: render.FormatMarkdown,
+		Author:        Author{Key: meta.str("author", "authors")},
+		Date:          meta.date("date", "publishDate", "pubdate", "published"),
+		Updated:       meta.date("lastmod", "last_modified_at", "updated", "modified"),
+		dir:           path.Dir(name),
+	}
+	if strings.EqualFold(path.Ext(name), ".html") {
+		post.ContentFormat = render.FormatHTML
+	}
+	// 本系统导出的数据中记录了原文格式
+	switch format := strings.ToLower(meta.str("format")); format {
+	case render.FormatMarkdown, render.FormatHTML, render.FormatPlain:
+		post.ContentFormat = format
+	}
+	if post.Author.Key == "" {
+		post.Author.Key = l.defaultAuthor
+	}
+	if post.Date.IsZero() {
+		post.Date = modTime
+	}
+	if post.Title == "" {
+		post.Title, post.Content = titleFromHeading(post.Content)
+	}
+	if post.Title == "" {
+		post.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	return post
+}
+
+// unpublished front matter 标记为草稿或不发布
+func unpublished(meta frontMatter) bool {
+	if draft, _ := meta.boolean("draft"); draft {
+		return true
+	}
+	if published, ok := meta.boolean("published"); ok && !published {
+		return true
+	}
+	return false
+}
+
+var headingPattern = regexp.MustCompile(`^#\s+(.+?)\s*#*\s*$`)
+
+// titleFromHeading 正文以一级标题开头时将其作为文章标题并从正文中去除
+func titleFromHeading(body string) (string, string) {
+	trimmed := strings.TrimLeft(body, "\n")
+	line, rest, _ := strings.Cut(trimmed, "\n")
+	if m := headingPattern.FindStringSubmatch(line); m != nil {
+		return m[1], strings.TrimLeft(rest, "\n")
+	}
+	return "", body
+}
+
+// markdownPost 通用 Markdown 文件
+func (l *dirLoader) markdownPost(name string, meta frontMatter, body string, modTime time.Time) error {
+	post := l.newPost(name, meta, body, modTime)
+	if unpublished(meta) {
+		l.skip(name, post.Title, "草稿")
+		return nil
+	}
+	post.Tags = meta.strs(",", "tags", "categories")
+	if post.Slug == "" {
+		post.Slug = fileSlug(name)
+	}
+	l.site.Posts = append(l.site.Posts, *post)
+	return nil
+}
+
+// fileSlug 由文件名生成slug，index/README 使用所在目录名
+func fileSlug(name string) string {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	if strings.EqualFold(base, "index") || strings.EqualFold(base, "readme") {
+		if dir := path.Dir(name); dir != "." {
+			return path.Base(dir)
+		}
+	}
+	return base
+}
+
+// hugoConfig 读取Hugo站点配置，返回配置文件名和内容
+func hugoConfig(fsys fs.FS) (string, frontMatter) {
+	for _, name := range hugoConfigFiles {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			continue
+		}
+		cfg := frontMatter{}
+		switch path.Ext(name) {
+		case ".toml":
+			err = toml.Unmarshal(data, &cfg)
+		case ".json":
+			err = json.Unmarshal(data, &cfg)
+		default:
+			err = yaml.Unmarshal(data, &cfg)
+		}
+		if err != nil {
+			continue
+		}
+		// config.* 也可能是其他工具的配置文件，需同时存在内容目录
+		contentDir := cfg.str("contentDir")
+		if contentDir == "" {
+			contentDir = "content"
+		}
+		if isDir(fsys, contentDir) {
+			return name, cfg
+		}
+	}
+	return "", nil
+}
+
+// loadHugo Hugo站点：导入 content 目录下的页面（不含 _index 列表页），/ 开头的图片在 static 目录中查找
+func (l *dirLoader) loadHugo() error {
+	_, cfg := hugoConfig(l.fsys)
+	if cfg == nil {
+		cfg = frontMatter{}
+	}
+	l.setBaseURL(cfg.str("baseURL"))
+	contentDir := cfg.str("contentDir")
+	if contentDir == "" {
+		contentDir = "content"
+	}
+	l.site.staticRoot = cfg.str("staticDir")
+	if l.site.staticRoot == "" {
+		l.site.staticRoot = "static"
+	}
+
+	match := func(name string) bool {
+		base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+		return (isMarkdown(name) || strings.EqualFold(path.Ext(name), ".html")) && base != "_index"
+	}
+	return l.walk(contentDir, match, func(name string, meta frontMatter, body string, modTime time.Time) error {
+		post := l.newPost(name, meta, body, modTime)
+		if unpublished(meta) {
+			l.skip(name, post.Title, "草稿")
+			return nil
+		}
+		if exp := meta.date("expiryDate"); !exp.IsZero() && exp.Before(time.Now()) {
+			l.skip(name, post.Title, "已过期（expiryDate）")
+			return nil
+		}
+		post.Tags = meta.strs("", "tags", "categories")
+		if post.Slug == "" {
+			if u := strings.Trim(meta.str("url"), "/"); u != "" {
+				post.Slug = path.Base(u)
+			} else {
+				post.Slug = fileSlug(name)
+			}
+		}
+		post.Content = convertHugoShortcodes(post.Content, &post.Warnings)
+		l.site.Posts = append(l.site.Posts, *post)
+		return nil
+	})
+}
+
+// loadJekyll Jekyll站点：导入 _posts 下的文章（_drafts 不导入），/ 开头的图片在站点根目录中查找
+func (l *dirLoader) loadJekyll() error {
+	cfg := frontMatter{}
+	if data, err := fs.ReadFile(l.fsys, "_config.yml"); err == nil {
+		_ = yaml.Unmarshal(data, &cfg)
+	}
+	if u := cfg.str("url"); u != "" {
+		l.setBaseURL(strings.TrimRight(u, "/") + "/" + strings.Trim(cfg.str("baseurl"), "/"))
+	}
+	l.defaultAuthor = cfg.str("author")
+	if author, ok := cfg["author"].(map[string]interface{}); ok {
+		l.defaultAuthor = frontMatter(author).str("name")
+	}
+
+	match := func(name string) bool { return isMarkdown(name) || strings.EqualFold(path.Ext(name), ".html") }
+	return l.walk("_posts", match, func(name string, meta frontMatter, body string, modTime time.Time) error {
+		m := jekyllPostName.FindStringSubmatch(path.Base(name))
+		if m == nil {
+			l.skip(name, "", "文件名不符合 YYYY-MM-DD-标题.md 格式")
+			return nil
+		}
+		post := l.newPost(name, meta, body, time.Time{})
+		if unpublished(meta) {
+			l.skip(name, post.Title, "未发布（published: false）")
+			return nil
+		}
+		if post.Date.IsZero() {
+			post.Date = parseTime(m[1])
+		}
+		if post.Title == "" || post.Title == strings.TrimSuffix(path.Base(name), path.Ext(name)) {
+			post.Title = strings.ReplaceAll(m[2], "-", " ")
+		}
+		if post.Slug == "" {
+			post.Slug = m[2]
+		}
+		post.Tags = meta.strs(" ", "tags", "categories", "category")
+		post.Content = convertLiquid(post.Content, &post.Warnings)
+		l.site.Posts = append(l.site.Posts, *post)
+		return nil
+	})
+}
+
+var (
+	hugoShortcodePattern   = regexp.MustCompile(`\{\{[<%]\s*(/?)(\w+)\s*(.*?)\s*[>%]\}\}`)
+	hugoHighlightPattern   = regexp.MustCompile(`(?s)\{\{[<%]\s*highlight\s+(\w+)[^}]*?[>%]\}\}\n?(.*?)\n?\{\{[<%]\s*/highlight\s*[>%]\}\}`)
+	shortcodeAttrPattern   = regexp.MustCompile(`(\w+)\s*=\s*(?:"([^"]*)"|'([^']*)'|(\S+))`)
+	liquidHighlightPattern = regexp.MustCompile(`(?s)\{%-?\s*highlight\s+(\w+)[^%]*-?%\}\n?(.*?)\n?\{%-?\s*endhighlight\s*-?%\}`)
+	liquidURLFilterPattern = regexp.MustCompile(`\{\{\s*["']([^"']*)["']\s*\|\s*(?:relative_url|absolute_url)\s*\}\}`)
+	liquidSiteURLPattern   = regexp.MustCompile(`\{\{\s*site\.(?:baseurl|url)\s*\}\}`)
+	liquidRawPattern       = regexp.MustCompile(`\{%-?\s*(?:end)?raw\s*-?%\}`)
+)
+
+// shortcodeAttrs 解析短代码参数
+func shortcodeAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range shortcodeAttrPattern.FindAllStringSubmatch(s, -1) {
+		attrs[m[1]] = m[2] + m[3] + m[4]
+	}
+	return attrs
+}
+
+// convertHugoShortcodes 转换常用的Hugo短代码：highlight 转为代码块，figure 转为图片
+func convertHugoShortcodes(body string, warnings *[]string) string {
+	body = hugoHighlightPattern.ReplaceAllString(body, "```$1\n$2\n```")
+	unknown := map[string]bool{}
+	body = hugoShortcodePattern.ReplaceAllStringFunc(body, func(s string) string {
+		m := hugoShortcodePattern.FindStringSubmatch(s)
+		if m[2] == "figure" && m[1] == "" {
+			attrs := shortcodeAttrs(m[3])
+			alt := attrs["alt"]
+			if alt == "" {
+				alt = attrs["caption"]
+			}
+			if alt == "" {
+				alt = attrs["title"]
+			}
+			return "![" + alt + "](" + attrs["src"] + ")"
+		}
+		unknown[m[2]] = true
+		return s
+	})
+	for name := range unknown {
+		*warnings = append(*warnings, fmt.Sprintf("包含未转换的Hugo短代码: %s", name))
+	}
+	return body
+}
+
+// convertLiquid 转换常用的Liquid标签：highlight 转为代码块，去除站点地址变量和 raw 标签
+func convertLiquid(body string, warnings *[]string) string {
+	body = liquidHighlightPattern.ReplaceAllString(body, "```$1\n$2\n```")
+	body = liquidURLFilterPattern.ReplaceAllString(body, "$1")
+	body = liquidSiteURLPattern.ReplaceAllString(body, "")
+	body = liquidRawPattern.ReplaceAllString(body, "")
+	if strings.Contains(body, "{%") {
+		*warnings = append(*warnings, "包含未转换的Liquid标签")
+	}
+	return body
+}
@@ -0,0 +1,232 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"go-blog-system/render"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WordPress WXR 导出文件（工具 → 导出）：导入已发布的文章及已通过审核的评论
+// 分类和标签都导入为标签；页面、附件等其他类型不导入，附件中被文章引用的图片随文章导入
+
+// WXR 中 wp: 命名空间的地址随版本变化（1.0~1.2），字段按本地名称匹配
+type wxrFile struct {
+	Channel struct {
+		Title       string      `xml:"title"`
+		Link        string      `xml:"link"`
+		BaseSiteURL string      `xml:"base_site_url"`
+		BaseBlogURL string      `xml:"base_blog_url"`
+		Authors     []wxrAuthor `xml:"author"`
+		Items       []wxrItem   `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrAuthor struct {
+	ID          string `xml:"author_id"`
+	Login       string `xml:"author_login"`
+	Email       string `xml:"author_email"`
+	DisplayName string `xml:"author_display_name"`
+}
+
+type wxrItem struct {
+	Title        string        `xml:"title"`
+	Link         string        `xml:"link"`
+	PubDate      string        `xml:"pubDate"`
+	Creator      string        `xml:"creator"`
+	Content      string        `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PostID       string        `xml:"post_id"`
+	PostDate     string        `xml:"post_date"`
+	PostDateGMT  string        `xml:"post_date_gmt"`
+	ModifiedGMT  string        `xml:"post_modified_gmt"`
+	PostName     string        `xml:"post_name"`
+	Status       string        `xml:"status"`
+	PostType     string        `xml:"post_type"`
+	PostPassword string        `xml:"post_password"`
+	Categories   []wxrCategory `xml:"category"`
+	Comments     []wxrComment  `xml:"comment"`
+}
+
+type wxrCategory struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",chardata"`
+}
+
+type wxrComment struct {
+	ID          string `xml:"comment_id"`
+	Author      string `xml:"comment_author"`
+	AuthorEmail string `xml:"comment_author_email"`
+	DateGMT     string `xml:"comment_date_gmt"`
+	Date        string `xml:"comment_date"`
+	Content     string `xml:"comment_content"`
+	Approved    string `xml:"comment_approved"`
+	Type        string `xml:"comment_type"`
+	UserID      string `xml:"comment_user_id"`
+}
+
+// LoadWXR 解析 WordPress WXR 导出文件
+func LoadWXR(r io.Reader) (*Site, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	var file wxrFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("WXR文件解析失败: %w", err)
+	}
+	ch := &file.Channel
+	if ch.BaseSiteURL == "" && len(ch.Items) == 0 {
+		return nil, errors.New("不是WordPress导出文件（WXR）")
+	}
+
+	site := &Site{Format: FormatWXR}
+	for _, raw := range []string{ch.BaseBlogURL, ch.BaseSiteURL, ch.Link} {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if site.Source == "" {
+			site.Source = FormatWXR + ":" + strings.TrimRight(u.Scheme+"://"+u.Host+u.Path, "/")
+		}
+		site.Hosts = append(site.Hosts, u.Hostname())
+	}
+	if site.Source == "" {
+		site.Source = FormatWXR + ":" + ch.Title
+	}
+
+	authors := make(map[string]wxrAuthor, len(ch.Authors))
+	authorByID := make(map[string]string, len(ch.Authors))
+	for _, a := range ch.Authors {
+		authors[a.Login] = a
+		authorByID[a.ID] = a.Login
+	}
+	toAuthor := func(login string) Author {
+		a := authors[login]
+		return Author{Key: login, Email: a.Email, DisplayName: a.DisplayName}
+	}
+
+	for _, item := range ch.Items {
+		if item.PostType != "post" {
+			if item.PostType == "page" {
+				site.Skipped = append(site.Skipped, Item{ExternalID: item.PostID, Title: item.Title, Action: ActionSkipped, Message: "页面不导入"})
+			}
+			continue
+		}
+		if item.Status != "publish" || item.PostPassword != "" {
+			reason := "未发布（" + item.Status + "）"
+			if item.PostPassword != "" {
+				reason = "密码保护的文章"
+			}
+			site.Skipped = append(site.Skipped, Item{ExternalID: item.PostID, Title: item.Title, Action: ActionSkipped, Message: reason})
+			continue
+		}
+
+		post := Post{
+			ExternalID:    item.PostID,
+			Title:         item.Title,
+			Slug:          wxrSlug(item.PostName),
+			ContentFormat: render.FormatHTML,
+			Author:        toAuthor(item.Creator),
+			Date:          wxrTime(item.PostDateGMT, item.PostDate),
+			Updated:       wxrTime(item.ModifiedGMT, ""),
+		}
+		if post.Date.IsZero() {
+			post.Date = parseTime(item.PubDate)
+		}
+		post.Content = convertWordPressContent(item.Content, &post.Warnings)
+		for _, c := range item.Categories {
+			if (c.Domain == "post_tag" || c.Domain == "category") && c.Nicename != "uncategorized" {
+				post.Tags = append(post.Tags, strings.TrimSpace(c.Name))
+			}
+		}
+
+		for _, c := range item.Comments {
+			// 只导入已通过审核的普通评论，不导入 pingback/trackback
+			if c.Approved != "1" || (c.Type != "" && c.Type != "comment") {
+				continue
+			}
+			author := Author{Key: authorByID[c.UserID], Email: c.AuthorEmail, DisplayName: c.Author}
+			if author.Key == "" {
+				author.Key = c.Author
+			} else {
+				author = toAuthor(author.Key)
+			}
+			post.Comments = append(post.Comments, Comment{
+				ExternalID: c.ID,
+				Author:     author,
+				Content:    strings.TrimSpace(c.Content),
+				Date:       wxrTime(c.DateGMT, c.Date),
+			})
+		}
+		site.Posts = append(site.Posts, post)
+	}
+	return site, nil
+}
+
+// wxrSlug WordPress 的 post_name 中非ASCII字符为百分号编码
+func wxrSlug(name string) string {
+	if s, err := url.PathUnescape(name); err == nil {
+		return s
+	}
+	return name
+}
+
+// wxrTime 优先使用GMT时间，未发布的文章GMT时间为全零，此时按本地时间解析
+func wxrTime(gmt, local string) time.Time {
+	if gmt != "" && !strings.HasPrefix(gmt, "0000") {
+		if t, err := time.Parse("2006-01-02 15:04:05", gmt); err == nil {
+			return t.Local() // 与其他来源一致使用本地时区，避免写入数据库的时间带UTC时区
+		}
+	}
+	if local != "" && !strings.HasPrefix(local, "0000") {
+		return parseTime(local)
+	}
+	return time.Time{}
+}
+
+var (
+	wpCaptionPattern   = regexp.MustCompile(`(?s)\[caption[^\]]*\](.*?)\[/caption\]`)
+	wpShortcodePattern = regexp.MustCompile(`\[(gallery|embed|video|audio|playlist)\b[^\]]*\]`)
+	wpSrcsetPattern    = regexp.MustCompile(`\s(?:srcset|sizes)\s*=\s*"[^"]*"`)
+	wpBlockTagPattern  = regexp.MustCompile(`(?i)^<(?:p|div|h[1-6]|ul|ol|li|pre|blockquote|table|figure|hr|!--)`)
+)
+
+// convertWordPressContent 处理WordPress内容：去除 caption 短代码和响应式图片属性，
+// 经典编辑器的内容不含段落标签，按空行分段（与WordPress的 wpautop 一致）
+func convertWordPressContent(content string, warnings *[]string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = wpCaptionPattern.ReplaceAllString(content, "$1")
+	// 原站点的多尺寸图片不再使用，导入后由媒体库生成
+	content = wpSrcsetPattern.ReplaceAllString(content, "")
+	if names := wpShortcodePattern.FindAllStringSubmatch(content, -1); len(names) > 0 {
+		seen := map[string]bool{}
+		for _, m := range names {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				*warnings = append(*warnings, "包含未转换的WordPress短代码: "+m[1])
+			}
+		}
+	}
+	if strings.Contains(content, "<p>") || strings.Contains(content, "<!-- wp:") {
+		return content
+	}
+
+	var b strings.Builder
+	for _, block := range strings.Split(content, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		if wpBlockTagPattern.MatchString(block) {
+			b.WriteString(block)
+		} else {
+			b.WriteString("<p>" + strings.ReplaceAll(block, "\n", "<br>\n") + "</p>")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
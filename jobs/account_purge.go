@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// StartAccountPurge 启动后台任务：定期彻底清除已过注销宽限期的账号
+func StartAccountPurge(store storage.Storage, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := PurgeDueAccounts(store); err != nil {
+				utils.Log.Errorf("[Jobs] 清除注销账号失败: %v", err)
+			} else if n > 0 {
+				utils.Log.Infof("[Jobs] 已清除注销账号: %d 个", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// PurgeDueAccounts 清除全部已到期的注销账号，返回清除数量
+func PurgeDueAccounts(store storage.Storage) (int, error) {
+	var users []models.User
+	if err := config.DB.Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range users {
+		if err := PurgeUser(&users[i], store); err != nil {
+			utils.Log.Errorf("[Jobs] 清除账号失败: %v, user_id: %d", err, users[i].ID)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// PurgeUser 按注销方式彻底清除用户数据
+// delete：删除用户及其文章、评论、媒体；anonymize：保留内容，抹除个人信息后以“已注销用户”身份保留
+func PurgeUser(user *models.User, store storage.Storage) error {
+	avatarPrefix := user.Avatar
+	var media []models.Media
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// 账号凭证类数据两种方式都彻底删除
+		for _, model := range []interface{}{
+			&models.Session{}, &models.APIKey{}, &models.RecoveryCode{}, &models.UserIdentity{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// 点赞/回应、收藏、关注关系、通知和对该用户的提及两种方式都删除
+		for _, model := range []interface{}{&models.Reaction{}, &models.Bookmark{}, &models.BookmarkCollection{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("follower_id = ? OR followee_id = ?", user.ID, user.ID).Delete(&models.Follow{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("actor_id = ? OR notification_id IN (?)", user.ID,
+			tx.Model(&models.Notification{}).Select("id").Where("user_id = ? OR actor_id = ?", user.ID, user.ID)).
+			Delete(&models.NotificationActor{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR actor_id = ?", user.ID, user.ID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+
+		// 原用户名保留，他人不能注册后继承其 @提及链接
+		if err := models.ReserveUsername(tx, user.Username); err != nil {
+			return err
+		}
+
+		if user.DeletionMode == models.DeletionModeDelete {
+			// 删除用户的评论、其文章下的全部评论以及文章本身，连同它们收到的回应
+			postIDs := tx.Unscoped().Model(&models.Post{}).Select("id").Where("user_id = ?", user.ID)
+			commentIDs := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("user_id = ? OR post_id IN (?)", user.ID, postIDs)
+			if err := tx.Where("(target_type = ? AND target_id IN (?)) OR (target_type = ? AND target_id IN (?))",
+				models.ReactionTargetPost, postIDs, models.ReactionTargetComment, commentIDs).Delete(&models.Reaction{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("notification_id IN (?)", tx.Model(&models.Notification{}).Select("id").
+				Where("post_id IN (?) OR comment_id IN (?)", postIDs, commentIDs)).Delete(&models.NotificationActor{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN (?) OR comment_id IN (?)", postIDs, commentIDs).Delete(&models.Notification{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("(source_type = ? AND source_id IN (?)) OR (source_type = ? AND source_id IN (?))",
+				models.MentionSourcePost, postIDs, models.MentionSourceComment, commentIDs).Delete(&models.Mention{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("post_id IN (?)", postIDs).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM post_tags WHERE post_id IN (?)", postIDs).Error; err != nil {
+				return err
+			}
+			for _, model := range []interface{}{&models.PostViewStat{}, &models.PostReferrerStat{}, &models.Bookmark{}} {
+				if err := tx.Where("post_id IN (?)", postIDs).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Post{}).Error; err != nil {
+				return err
+			}
+			// 媒体记录随账号删除，文件在提交后按引用情况清理
+			if err := tx.Where("user_id = ?", user.ID).Find(&media).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Media{}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Delete(user).Error
+		}
+
+		// 匿名化：保留用户行以维持文章/评论的作者关联
+		randomPwd := make([]byte, 24)
+		if _, err := rand.Read(randomPwd); err != nil {
+			return err
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(base64.RawURLEncoding.EncodeToString(randomPwd)), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		return tx.Model(user).Updates(map[string]interface{}{
+			"username":               fmt.Sprintf("%s%d", models.DeletedUsernamePrefix, user.ID),
+			"password":               string(hashed),
+			"email":                  gorm.Expr("NULL"),
+			"display_name":           "已注销用户",
+			"bio":                    "",
+			"website":                "",
+			"avatar":                 "",
+			"role":                   models.RoleUser,
+			"totp_secret":            "",
+			"totp_enabled":           false,
+			"two_factor_required":    false,
+			"password_unset":         true,
+			"deletion_scheduled_at":  gorm.Expr("NULL"),
+			"deletion_mode":          "",
+			"reading_list_feed_hash": "",
+			"notifications_muted":    "",
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// 数据库提交后再删除头像和媒体文件
+	utils.DeleteUnreferencedMedia(store, media...)
+	if avatarPrefix != "" {
+		for _, size := range models.AvatarSizes {
+			if err := store.Delete(models.AvatarKey(avatarPrefix, size)); err != nil {
+				utils.Log.Warnf("[Jobs] 删除头像失败: %v, user_id: %d", err, user.ID)
+			}
+		}
+	}
+	utils.Log.Infof("[Jobs] 账号已清除: user_id: %d, mode: %s", user.ID, user.DeletionMode)
+	return nil
+}
@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"go-blog-system/backup"
+	"go-blog-system/config"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"os"
+	"time"
+)
+
+// StartBackupSchedule 启动后台任务：距最新备份超过 BackupInterval 时生成备份，并只保留最新的 BackupRetention 个
+// 以备份目录中的文件时间为准，服务重启不会导致重复备份；BackupInterval 不大于0时不启动
+func StartBackupSchedule(store storage.Storage, cfg *config.AppConfig) {
+	if cfg.BackupInterval <= 0 {
+		return
+	}
+	check := cfg.BackupInterval
+	if check > 10*time.Minute {
+		check = 10 * time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+		for {
+			if backupDue(cfg) {
+				RunScheduledBackup(store, cfg)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// backupDue 备份目录中没有备份或最新备份已超过间隔
+func backupDue(cfg *config.AppConfig) bool {
+	files, err := backup.List(cfg.BackupDir)
+	if err != nil {
+		utils.Log.Errorf("[Jobs] 读取备份目录失败: %v", err)
+		return false
+	}
+	if len(files) == 0 {
+		return true
+	}
+	info, err := os.Stat(files[0])
+	return err != nil || time.Since(info.ModTime()) >= cfg.BackupInterval
+}
+
+// RunScheduledBackup 生成一次备份并清理过期备份
+func RunScheduledBackup(store storage.Storage, cfg *config.AppConfig) {
+	manifest, err := backup.CreateFile(cfg.BackupDir, store, cfg.BackupIncludeMedia)
+	if err != nil {
+		utils.Log.Errorf("[Jobs] 定时备份失败: %v", err)
+		return
+	}
+	utils.Log.Infof("[Jobs] 已生成备份: %s（%d 字节）", manifest.BackupFileName, manifest.BackupFileSize)
+	if cfg.BackupRetention > 0 {
+		removed, err := backup.Prune(cfg.BackupDir, cfg.BackupRetention)
+		if err != nil {
+			utils.Log.Errorf("[Jobs] 清理过期备份失败: %v", err)
+		} else if len(removed) > 0 {
+			utils.Log.Infof("[Jobs] 已清理过期备份: %d 个", len(removed))
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"go-blog-system/utils"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imageMaintenanceInterval 重新入队未处理图片、清理失效缓存的周期
+const imageMaintenanceInterval = time.Minute
+
+// ErrImageBusy 图片处理繁忙，动态缩放在等待时间内未获得处理资源
+var ErrImageBusy = errors.New("图片处理繁忙，请稍后重试")
+
+// ImagePipeline 图片后台处理：生成响应式尺寸与WebP版本，计算尺寸和模糊占位图
+// 上传接口只负责入队；后台worker与动态缩放共用同一组并发名额，避免图片处理占满CPU
+type ImagePipeline struct {
+	store storage.Storage
+	cfg   *config.AppConfig
+	queue chan uint
+	slots chan struct{}
+	cwebp string // 有损WebP编码器路径，为空时使用内置无损编码
+
+	mu       sync.Mutex
+	inflight map[uint]bool // 已入队或处理中的媒体ID
+}
+
+// StartImagePipeline 启动图片处理worker，并定期重新入队遗留的待处理图片
+func StartImagePipeline(store storage.Storage, cfg *config.AppConfig) *ImagePipeline {
+	p := &ImagePipeline{
+		store:    store,
+		cfg:      cfg,
+		queue:    make(chan uint, cfg.ImageQueueSize),
+		slots:    make(chan struct{}, cfg.ImageWorkers),
+		inflight: make(map[uint]bool),
+	}
+	if cfg.ImageWebPEncoder != "" {
+		if path, err := exec.LookPath(cfg.ImageWebPEncoder); err == nil {
+			p.cwebp = path
+		} else {
+			utils.Log.Warnf("未找到WebP编码器 %s，WebP版本使用内置无损编码，照片类图片的体积通常大于JPEG", cfg.ImageWebPEncoder)
+		}
+	}
+	for i := 0; i < cfg.ImageWorkers; i++ {
+		go p.worker()
+	}
+	go func() {
+		ticker := time.NewTicker(imageMaintenanceInterval)
+		defer ticker.Stop()
+		for {
+			p.requeuePending()
+			p.pruneCache()
+			<-ticker.C
+		}
+	}()
+	return p
+}
+
+// Enqueue 提交待处理的媒体，队列已满时保持pending状态，由定时任务稍后重新入队
+func (p *ImagePipeline) Enqueue(mediaID uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inflight[mediaID] {
+		return
+	}
+	select {
+	case p.queue <- mediaID:
+		p.inflight[mediaID] = true
+	default:
+		utils.Log.Warnf("[Jobs] 图片处理队列已满，稍后重试: media_id: %d", mediaID)
+	}
+}
+
+func (p *ImagePipeline) worker() {
+	for id := range p.queue {
+		p.slots <- struct{}{}
+		p.process(id)
+		<-p.slots
+
+		p.mu.Lock()
+		delete(p.inflight, id)
+		p.mu.Unlock()
+	}
+}
+
+// requeuePending 重新入队服务重启或队列已满时遗留的待处理图片
+func (p *ImagePipeline) requeuePending() {
+	var ids []uint
+	if err := config.DB.Model(&models.Media{}).Where("processing_status = ?", models.MediaProcessingPending).
+		Order("id").Limit(p.cfg.ImageQueueSize).Pluck("id", &ids).Error; err != nil {
+		utils.Log.Errorf("[Jobs] 查询待处理图片失败: %v", err)
+		return
+	}
+	for _, id := range ids {
+		p.Enqueue(id)
+	}
+}
+
+// process 处理单个媒体，结果写回媒体记录
+func (p *ImagePipeline) process(mediaID uint) {
+	var media models.Media
+	if err := config.DB.First(&media, mediaID).Error; err != nil {
+		return // 已被删除
+	}
+	if media.ProcessingStatus != models.MediaProcessingPending {
+		return
+	}
+
+	// 相同文件已处理过则直接复用结果
+	var done models.Media
+	err := config.DB.Where("storage_key = ? AND processing_status = ? AND id <> ?",
+		media.Key, models.MediaProcessingReady, media.ID).First(&done).Error
+	if err == nil {
+		media.Width, media.Height, media.BlurHash, media.Variants = done.Width, done.Height, done.BlurHash, done.Variants
+	} else if err := p.generate(&media); err != nil {
+		utils.Log.Warnf("[Jobs] 图片处理失败: %v, media_id: %d", err, media.ID)
+		config.DB.Model(&models.Media{}).Where("id = ?", media.ID).
+			Update("processing_status", models.MediaProcessingFailed)
+		return
+	}
+
+	result := config.DB.Model(&models.Media{}).Where("id = ?", media.ID).Updates(map[string]interface{}{
+		"width":             media.Width,
+		"height":            media.Height,
+		"blur_hash":         media.BlurHash,
+		"variants":          media.Variants,
+		"processing_status": models.MediaProcessingReady,
+	})
+	if result.Error != nil {
+		utils.Log.Errorf("[Jobs] 保存图片处理结果失败: %v, media_id: %d", result.Error, media.ID)
+		return
+	}
+	// 处理期间媒体被删除，清理刚生成的衍生图片
+	if result.RowsAffected == 0 {
+		utils.DeleteUnreferencedMedia(p.store, media)
+		return
+	}
+	utils.Log.Infof("[Jobs] 图片处理完成: media_id: %d, %dx%d, variants: %d",
+		media.ID, media.Width, media.Height, len(media.VariantList()))
+}
+
+// loadImage 读取并解码原图，按EXIF方向校正
+func (p *ImagePipeline) loadImage(key string) (image.Image, error) {
+	rc, err := p.store.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, p.cfg.MediaMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := utils.DecodeImage(data, p.cfg.ImageMaxPixels)
+	if err != nil {
+		return nil, err
+	}
+	return utils.ApplyOrientation(img, utils.ImageOrientation(data)), nil
+}
+
+// variantFormat 衍生图片的基础格式：带透明通道或无损原图用PNG，其余用JPEG
+func variantFormat(media *models.Media, img image.Image) string {
+	if media.ContentType == "image/png" || media.ContentType == "image/gif" || utils.HasAlpha(img) {
+		return "png"
+	}
+	return "jpeg"
+}
+
+// encodeWebP 编码WebP：有 cwebp 时有损编码，否则使用内置无损编码（lossy 为false）
+func (p *ImagePipeline) encodeWebP(img image.Image) (data []byte, lossy bool, err error) {
+	if p.cwebp != "" {
+		data, err = utils.EncodeWebPLossy(p.cwebp, img, p.cfg.ImageWebPQuality)
+		if err == nil {
+			return data, true, nil
+		}
+		utils.Log.Warnf("cwebp 编码失败，改用无损编码: %v", err)
+	}
+	data, err = utils.EncodeWebP(img)
+	return data, false, err
+}
+
+// encodeVariant 按格式编码图片
+func (p *ImagePipeline) encodeVariant(img image.Image, format string) ([]byte, error) {
+	switch format {
+	case "png":
+		return utils.EncodePNG(img)
+	case "webp":
+		data, _, err := p.encodeWebP(img)
+		return data, err
+	default:
+		return utils.EncodeJPEG(utils.FlattenImage(img), 82)
+	}
+}
+
+// formatExt 格式对应的文件扩展名
+func formatExt(format string) string {
+	if format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// generate 生成衍生图片并写入存储
+// 每个小于原图的宽度生成一份JPEG/PNG和一份WebP；有损WebP仅在比对应版本更小时保留，
+// 未安装 cwebp 时WebP为无损编码，体积可能更大，但仍然生成，供需要WebP的客户端使用
+func (p *ImagePipeline) generate(media *models.Media) error {
+	img, err := p.loadImage(media.Key)
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	media.Width, media.Height = b.Dx(), b.Dy()
+	if media.Width >= media.Height {
+		media.BlurHash = utils.EncodeBlurHash(img, 4, 3)
+	} else {
+		media.BlurHash = utils.EncodeBlurHash(img, 3, 4)
+	}
+
+	widths := append([]int(nil), p.cfg.ImageVariantWidths...)
+	sort.Ints(widths)
+	stem := strings.TrimSuffix(media.Key, path.Ext(media.Key))
+	format := variantFormat(media, img)
+
+	var variants []models.MediaVariant
+	save := func(data []byte, width, height int, format, key string) error {
+		if err := p.store.Save(key, bytes.NewReader(data), "image/"+format); err != nil {
+			return err
+		}
+		variants = append(variants, models.MediaVariant{
+			Width: width, Height: height, Format: format, Key: key, Size: int64(len(data)),
+		})
+		return nil
+	}
+
+	for _, w := range widths {
+		if w >= media.Width {
+			break
+		}
+		resized := utils.ResizeToWidth(img, w)
+		rb := resized.Bounds()
+		base, err := p.encodeVariant(resized, format)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s_w%d", stem, w)
+		if err := save(base, rb.Dx(), rb.Dy(), format, name+formatExt(format)); err != nil {
+			return err
+		}
+		if webp, lossy, err := p.encodeWebP(resized); err == nil && (!lossy || len(webp) < len(base)) {
+			if err := save(webp, rb.Dx(), rb.Dy(), "webp", name+".webp"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 原尺寸WebP版本，超大图片编码耗时过长，不生成
+	if media.ContentType != "image/webp" && len(widths) > 0 && media.Width <= widths[len(widths)-1] {
+		if webp, lossy, err := p.encodeWebP(img); err == nil && (!lossy || int64(len(webp)) < media.Size) {
+			if err := save(webp, media.Width, media.Height, "webp", stem+".webp"); err != nil {
+				return err
+			}
+		}
+	}
+
+	media.SetVariants(variants)
+	return nil
+}
+
+// Resize 动态缩放图片并缓存到磁盘，返回缓存文件路径
+// 宽度向上取整到64的倍数以限制缓存版本数量，且不超过原图宽度和配置上限
+func (p *ImagePipeline) Resize(media *models.Media, width int, format string) (string, error) {
+	width = (width + 63) / 64 * 64
+	if width > p.cfg.ImageResizeMaxWidth {
+		width = p.cfg.ImageResizeMaxWidth
+	}
+	if media.Width > 0 && width > media.Width {
+		width = media.Width
+	}
+
+	sum := sha256.Sum256([]byte(media.Key))
+	dir := filepath.Join(p.cfg.ImageCacheDir, hex.EncodeToString(sum[:]))
+	file := filepath.Join(dir, fmt.Sprintf("w%d%s", width, formatExt(format)))
+	if _, err := os.Stat(file); err == nil {
+		return file, nil
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-time.After(10 * time.Second):
+		return "", ErrImageBusy
+	}
+
+	img, err := p.loadImage(media.Key)
+	if err != nil {
+		return "", err
+	}
+	if width < img.Bounds().Dx() {
+		img = utils.ResizeToWidth(img, width)
+	}
+	data, err := p.encodeVariant(img, format)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	// 记录原文件key，供清理缓存时判断原图是否仍被引用
+	if err := os.WriteFile(filepath.Join(dir, ".key"), []byte(media.Key), 0o644); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return file, nil
+}
+
+// ResizeFormat 动态缩放的默认输出格式
+func (p *ImagePipeline) ResizeFormat(media *models.Media) string {
+	if media.ContentType == "image/png" || media.ContentType == "image/gif" {
+		return "png"
+	}
+	if media.ContentType == "image/webp" {
+		return "webp"
+	}
+	return "jpeg"
+}
+
+// pruneCache 删除原图已不再被任何媒体记录引用的缓存目录
+func (p *ImagePipeline) pruneCache() {
+	entries, err := os.ReadDir(p.cfg.ImageCacheDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(p.cfg.ImageCacheDir, entry.Name())
+		key, err := os.ReadFile(filepath.Join(dir, ".key"))
+		if err != nil {
+			continue
+		}
+		var count int64
+		if err := config.DB.Model(&models.Media{}).Where("storage_key = ? AND visibility = ?",
+			string(key), models.MediaPublic).Count(&count).Error; err != nil || count > 0 {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			utils.Log.Warnf("[Jobs] 清理图片缓存失败: %v, dir: %s", err, dir)
+		}
+	}
+}
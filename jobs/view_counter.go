@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ViewCounter 文章阅读计数：请求中只在内存中累加，由后台定期（或累计到一定数量时）批量写入数据库
+// 同一访客（登录用户按用户ID，匿名访客按IP+UA的哈希）在去重时间内重复阅读只计一次；
+// 访客标识只保存在内存中。正常退出时由 main 调用 Stop 写入剩余计数，进程异常终止时最多丢失一个写入间隔的计数
+type ViewCounter struct {
+	cfg   *config.AppConfig
+	flush chan struct{}
+	stop  chan struct{} // 关闭后后台任务写入剩余计数并退出
+	done  chan struct{} // 后台任务退出后关闭
+
+	mu          sync.Mutex
+	seen        map[string]time.Time // 文章+访客 → 最近一次计数时间
+	day         string               // dayVisitors 对应的日期
+	dayVisitors map[string]bool      // 当天已计入独立访客的 文章+访客
+	views       map[viewKey]*viewDelta
+	referrers   map[referrerKey]int64
+	pending     int
+}
+
+type viewKey struct {
+	postID uint
+	day    string
+}
+
+type viewDelta struct {
+	views, visitors int64
+}
+
+type referrerKey struct {
+	postID uint
+	day    string
+	host   string
+}
+
+// View 一次文章阅读
+type View struct {
+	PostID       uint
+	UserID       uint // 未登录为0
+	IP           string
+	UserAgent    string
+	ReferrerHost string // 来源域名，直接访问为空
+}
+
+// StartViewCounter 启动阅读计数的批量写入任务
+func StartViewCounter(cfg *config.AppConfig) *ViewCounter {
+	v := &ViewCounter{
+		cfg:         cfg,
+		flush:       make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		seen:        make(map[string]time.Time),
+		dayVisitors: make(map[string]bool),
+		views:       make(map[viewKey]*viewDelta),
+		referrers:   make(map[referrerKey]int64),
+	}
+	go func() {
+		defer close(v.done)
+		ticker := time.NewTicker(cfg.ViewFlushInterval)
+		defer ticker.Stop()
+		for {
+			stopping := false
+			select {
+			case <-ticker.C:
+			case <-v.flush:
+			case <-v.stop:
+				stopping = true
+			}
+			if err := v.writePending(); err != nil {
+				utils.Log.Errorf("[Jobs] 写入阅读计数失败: %v", err)
+			}
+			if stopping {
+				return
+			}
+		}
+	}()
+	return v
+}
+
+// Stop 停止后台任务并等待剩余计数写入完成；所有写入都由后台任务执行，
+// 返回时不会有进行中的批次。调用前应先停止接收请求，之后记录的阅读不再写入
+func (v *ViewCounter) Stop() {
+	if v == nil {
+		return
+	}
+	close(v.stop)
+	<-v.done
+}
+
+// Record 记录一次阅读，爬虫和去重时间内的重复阅读不计数；v 为nil时不记录（如静态导出）
+func (v *ViewCounter) Record(view View) {
+	if v == nil || utils.IsBot(view.UserAgent) {
+		return
+	}
+	visitor := "u" + strconv.FormatUint(uint64(view.UserID), 10)
+	if view.UserID == 0 {
+		sum := sha256.Sum256([]byte(view.IP + "|" + view.UserAgent))
+		visitor = "a" + hex.EncodeToString(sum[:12])
+	}
+	seenKey := strconv.FormatUint(uint64(view.PostID), 10) + ":" + visitor
+	now := time.Now()
+	day := now.Format(models.StatsDayLayout)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if last, ok := v.seen[seenKey]; ok && now.Sub(last) < v.cfg.ViewDedupWindow {
+		return
+	}
+	v.seen[seenKey] = now
+
+	if day != v.day {
+		v.day = day
+		v.dayVisitors = make(map[string]bool)
+	}
+	delta := v.views[viewKey{view.PostID, day}]
+	if delta == nil {
+		delta = &viewDelta{}
+		v.views[viewKey{view.PostID, day}] = delta
+	}
+	delta.views++
+	if !v.dayVisitors[seenKey] {
+		v.dayVisitors[seenKey] = true
+		delta.visitors++
+	}
+	v.referrers[referrerKey{view.PostID, day, view.ReferrerHost}]++
+
+	v.pending++
+	if v.pending >= v.cfg.ViewFlushBatch {
+		select {
+		case v.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// writePending 将内存中的计数写入数据库，写入失败的计数保留到下次重试；只由后台任务调用
+func (v *ViewCounter) writePending() error {
+	v.mu.Lock()
+	views, referrers := v.views, v.referrers
+	v.views = make(map[viewKey]*viewDelta)
+	v.referrers = make(map[referrerKey]int64)
+	v.pending = 0
+	// 清理已超过去重时间的访客记录
+	now := time.Now()
+	for key, last := range v.seen {
+		if now.Sub(last) >= v.cfg.ViewDedupWindow {
+			delete(v.seen, key)
+		}
+	}
+	v.mu.Unlock()
+
+	if len(views) == 0 {
+		return nil
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for key, delta := range views {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "post_id"}, {Name: "day"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"views":    gorm.Expr("views + ?", delta.views),
+					"visitors": gorm.Expr("visitors + ?", delta.visitors),
+				}),
+			}).Create(&models.PostViewStat{PostID: key.postID, Day: key.day, Views: delta.views, Visitors: delta.visitors}).Error; err != nil {
+				return err
+			}
+			// UpdateColumn 不修改 updated_at，阅读不影响订阅源和静态导出
+			if err := tx.Model(&models.Post{}).Where("id = ?", key.postID).
+				UpdateColumn("view_count", gorm.Expr("view_count + ?", delta.views)).Error; err != nil {
+				return err
+			}
+		}
+		for key, count := range referrers {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "post_id"}, {Name: "day"}, {Name: "host"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"views": gorm.Expr("views + ?", count)}),
+			}).Create(&models.PostReferrerStat{PostID: key.postID, Day: key.day, Host: key.host, Views: count}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		v.merge(views, referrers)
+	}
+	return err
+}
+
+// merge 将写入失败的计数合并回内存
+func (v *ViewCounter) merge(views map[viewKey]*viewDelta, referrers map[referrerKey]int64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for key, delta := range views {
+		if current := v.views[key]; current != nil {
+			current.views += delta.views
+			current.visitors += delta.visitors
+		} else {
+			v.views[key] = delta
+		}
+		v.pending += int(delta.views)
+	}
+	for key, count := range referrers {
+		v.referrers[key] += count
+	}
+}
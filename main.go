@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"go-blog-system/commands"
 	"go-blog-system/config"
 	"go-blog-system/controllers"
+	"go-blog-system/jobs"
 	"go-blog-system/middleware"
+	"go-blog-system/models"
+	"go-blog-system/render"
+	"go-blog-system/spam"
+	"go-blog-system/storage"
 	"go-blog-system/utils"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +32,86 @@ func main() {
 	// 3. 初始化数据库
 	config.InitDB(appCfg)
 
+	// 签名下载地址密钥：未配置时使用自动生成并持久化的随机密钥，不使用公开的默认值
+	if appCfg.MediaURLSecret == "" {
+		secret, err := utils.LoadOrCreateSecret(appCfg.MediaURLSecretFile)
+		if err != nil {
+			utils.Log.Fatalf("签名下载地址密钥加载失败: %v", err)
+		}
+		appCfg.MediaURLSecret = secret
+	}
+
+	// 初始化文件存储
+	var store storage.Storage
+	localStore, err := storage.NewLocalStorage(appCfg.UploadDir, appCfg.UploadBaseURL, appCfg.MediaURLSecret)
+	if err != nil {
+		utils.Log.Fatalf("文件存储初始化失败: %v", err)
+	}
+	store = localStore
+	if appCfg.Storage.Driver == "s3" {
+		store, err = storage.NewS3Storage(storage.S3Options{
+			Endpoint:      appCfg.Storage.S3Endpoint,
+			Region:        appCfg.Storage.S3Region,
+			Bucket:        appCfg.Storage.S3Bucket,
+			AccessKey:     appCfg.Storage.S3AccessKey,
+			SecretKey:     appCfg.Storage.S3SecretKey,
+			UseSSL:        appCfg.Storage.S3UseSSL,
+			PublicBaseURL: appCfg.Storage.S3PublicBaseURL,
+		})
+		if err != nil {
+			utils.Log.Fatalf("S3存储初始化失败: %v", err)
+		}
+	}
+	utils.Log.Infof("文件存储后端: %s", appCfg.Storage.Driver)
+
+	// 命令行子命令（如 export），执行完毕后直接退出，不启动服务
+	if len(os.Args) > 1 {
+		os.Exit(commands.Run(appCfg, store, os.Args[1:]))
+	}
+
+	// 后台任务
+	jobs.StartAccountPurge(store, appCfg.AccountPurgeInterval)
+	imagePipeline := jobs.StartImagePipeline(store, appCfg)
+	jobs.StartBackupSchedule(store, appCfg)
+	viewCounter := jobs.StartViewCounter(appCfg)
+
+	// JWT签名密钥：HS256 未配置 JWT_SECRET 时使用自动生成并持久化的随机密钥
+	if appCfg.JWTAlgorithm == utils.JWTAlgHS256 && appCfg.JWTSecretKey == "" {
+		secret, err := utils.LoadOrCreateSecret(appCfg.JWTSecretKeyFile)
+		if err != nil {
+			utils.Log.Fatalf("JWT密钥加载失败: %v", err)
+		}
+		appCfg.JWTSecretKey = secret
+	}
+	jwtManager, err := utils.NewJWTManager(appCfg)
+	if err != nil {
+		utils.Log.Fatalf("JWT密钥加载失败: %v", err)
+	}
+	utils.Log.Infof("JWT签名算法: %s, kid: %s", appCfg.JWTAlgorithm, jwtManager.SigningKeyID())
+
+	// 第三方登录提供方
+	oidcRegistry := utils.NewOIDCRegistry(appCfg.OIDCProviders)
+
+	if err := utils.ValidatePermalinkPattern(appCfg.PostPermalink); err != nil {
+		utils.Log.Fatalf("文章固定链接格式错误: %v", err)
+	}
+
+	if !models.IsValidCommentMode(appCfg.CommentModeration) {
+		utils.Log.Fatalf("评论审核方式配置错误: %s", appCfg.CommentModeration)
+	}
+
+	// 评论反垃圾检查
+	spamFilter, err := spam.FromConfig(appCfg)
+	if err != nil {
+		utils.Log.Fatalf("反垃圾配置错误: %v", err)
+	}
+
+	// 代码高亮样式表
+	highlightCSS, err := render.HighlightCSS(appCfg.HighlightStyle)
+	if err != nil {
+		utils.Log.Fatalf("生成代码高亮样式失败: %v", err)
+	}
+
 	// 4. Gin引擎配置
 	r := gin.New()
 	r.Use(utils.GinLogger()) // 自定义日志中间件
@@ -29,7 +121,7 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Authorization,Content-Type")
+		c.Header("Access-Control-Allow-Headers", "Authorization,Content-Type,X-API-Key")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -37,53 +129,296 @@ func main() {
 		c.Next()
 	})
 
+	// 本地上传文件访问（私有文件需签名）
+	r.GET(appCfg.UploadBaseURL+"/*filepath", func(c *gin.Context) {
+		controllers.ServeUpload(c, localStore)
+	})
+
+	// JWKS：供其他服务验证本系统签发的Token
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		controllers.JWKS(c, jwtManager)
+	})
+
+	r.GET("/assets/highlight.css", func(c *gin.Context) {
+		controllers.HighlightCSS(c, highlightCSS)
+	})
+
+	// 订阅源、sitemap、robots.txt
+	controllers.RegisterFeedRoutes(r, appCfg, utils.NewSitemapCache(appCfg))
+	// 阅读列表私有订阅源（令牌即凭据，不参与静态导出）
+	r.GET("/reading-list/:token/atom.xml", func(c *gin.Context) {
+		controllers.ReadingListFeed(c, appCfg)
+	})
+
+	// 服务端渲染的站点页面
+	if appCfg.SiteEnabled {
+		siteTheme, err := controllers.LoadTheme(appCfg)
+		if err != nil {
+			utils.Log.Fatalf("主题加载失败: %v", err)
+		}
+		controllers.RegisterSiteRoutes(r, siteTheme, appCfg, viewCounter)
+	}
+
 	// 5. 路由配置
+	// 公开接口的可选认证：登录用户可获得“我是否已点赞”等字段
+	optionalAuth := middleware.OptionalAuthMiddleware(jwtManager)
 	publicGroup := r.Group("/api")
 	{
 		// 用户接口
 		publicGroup.POST("/register", controllers.Register)
 		publicGroup.POST("/login", func(c *gin.Context) {
-			controllers.Login(c, appCfg.JWTSecretKey, appCfg.TokenExpire)
+			controllers.Login(c, jwtManager, appCfg.TokenExpire)
+		})
+		publicGroup.POST("/login/2fa", func(c *gin.Context) {
+			controllers.LoginTwoFactor(c, jwtManager, appCfg.TokenExpire)
+		})
+
+		// 第三方登录
+		publicGroup.GET("/auth/oidc", func(c *gin.Context) {
+			controllers.ListOIDCProviders(c, oidcRegistry)
+		})
+		publicGroup.GET("/auth/oidc/:provider/login", func(c *gin.Context) {
+			controllers.OIDCLogin(c, oidcRegistry)
+		})
+		publicGroup.GET("/auth/oidc/:provider/callback", func(c *gin.Context) {
+			controllers.OIDCCallback(c, oidcRegistry, jwtManager, appCfg.TokenExpire)
 		})
 
 		// 文章接口
-		publicGroup.GET("/posts", controllers.GetPosts)
-		publicGroup.GET("/posts/:id", controllers.GetPost)
+		publicGroup.GET("/posts", optionalAuth, func(c *gin.Context) {
+			controllers.GetPosts(c, appCfg.PostPermalink)
+		})
+		publicGroup.GET("/posts/:id", optionalAuth, func(c *gin.Context) {
+			controllers.GetPost(c, appCfg.PostPermalink, viewCounter)
+		})
+		publicGroup.GET("/permalink", optionalAuth, func(c *gin.Context) {
+			controllers.ResolvePermalink(c, appCfg.PostPermalink, viewCounter)
+		})
 
 		// 评论接口
-		publicGroup.GET("/comments", controllers.GetComments)
+		publicGroup.GET("/comments", optionalAuth, controllers.GetComments)
+
+		// 点赞/回应
+		publicGroup.GET("/reactions/kinds", controllers.ReactionKinds)
+		publicGroup.GET("/posts/:id/reactions", func(c *gin.Context) {
+			controllers.ListReactions(c, models.ReactionTargetPost)
+		})
+		publicGroup.GET("/comments/:id/reactions", func(c *gin.Context) {
+			controllers.ListReactions(c, models.ReactionTargetComment)
+		})
+
+		// 作者主页
+		publicGroup.GET("/users/:username", optionalAuth, func(c *gin.Context) {
+			controllers.GetUserPage(c, store)
+		})
+		publicGroup.GET("/users/:username/followers", func(c *gin.Context) {
+			controllers.ListFollows(c, true)
+		})
+		publicGroup.GET("/users/:username/following", func(c *gin.Context) {
+			controllers.ListFollows(c, false)
+		})
+
+		// 公开图片的动态缩放
+		publicGroup.GET("/media/:id/image", func(c *gin.Context) {
+			controllers.ResizedImage(c, imagePipeline)
+		})
 	}
 
-	// 私有路由（需要JWT认证）
+	// 私有路由（需要JWT或API密钥认证，API密钥按授权范围限制）
 	privateGroup := r.Group("/api")
-	privateGroup.Use(middleware.JWTAuthMiddleware(appCfg.JWTSecretKey))
+	privateGroup.Use(middleware.JWTOrAPIKeyMiddleware(jwtManager))
 	{
 		// 个人信息
-		privateGroup.GET("/profile", func(c *gin.Context) {
-			userID := c.GetInt("user_id")
-			username := c.GetString("username")
-			c.JSON(200, gin.H{
-				"message": "获取个人信息成功",
-				"data": gin.H{
-					"user_id":  userID,
-					"username": username,
-				},
-			})
+		privateGroup.GET("/profile", middleware.RequireScope(models.ScopeProfileRead), func(c *gin.Context) {
+			controllers.GetProfile(c, store)
+		})
+		privateGroup.PUT("/profile", middleware.RequireScope(models.ScopeProfileWrite), func(c *gin.Context) {
+			controllers.UpdateProfile(c, store)
+		})
+		privateGroup.POST("/profile/avatar", middleware.RequireScope(models.ScopeProfileWrite), func(c *gin.Context) {
+			controllers.UploadAvatar(c, store, appCfg.AvatarMaxSize)
 		})
 
 		// 文章接口
-		privateGroup.POST("/posts", controllers.CreatePost)
-		privateGroup.PUT("/posts/:id", controllers.UpdatePost)
-		privateGroup.DELETE("/posts/:id", controllers.DeletePost)
+		privateGroup.POST("/posts", middleware.RequireScope(models.ScopePostsWrite), func(c *gin.Context) {
+			controllers.CreatePost(c, appCfg.PostPermalink)
+		})
+		privateGroup.PUT("/posts/:id", middleware.RequireScope(models.ScopePostsWrite), func(c *gin.Context) {
+			controllers.UpdatePost(c, appCfg.PostPermalink)
+		})
+		privateGroup.DELETE("/posts/:id", middleware.RequireScope(models.ScopePostsWrite), controllers.DeletePost)
 
 		// 评论接口
-		privateGroup.POST("/comments", controllers.CreateComment)
+		privateGroup.POST("/comments", middleware.RequireScope(models.ScopeCommentsWrite), func(c *gin.Context) {
+			controllers.CreateComment(c, appCfg, spamFilter)
+		})
+
+		// 评论审核：文章作者审核自己文章下的评论，编辑/管理员审核全部评论
+		privateGroup.GET("/moderation/comments", middleware.RequireScope(models.ScopeCommentsRead), controllers.ListModerationComments)
+		privateGroup.POST("/moderation/comments", middleware.RequireScope(models.ScopeCommentsWrite), controllers.ModerateComments)
+
+		// 点赞/回应（PUT 添加、DELETE 取消，均为幂等操作）
+		privateGroup.PUT("/posts/:id/reactions/:kind", middleware.RequireScope(models.ScopeReactionsWrite), func(c *gin.Context) {
+			controllers.AddReaction(c, models.ReactionTargetPost)
+		})
+		privateGroup.DELETE("/posts/:id/reactions/:kind", middleware.RequireScope(models.ScopeReactionsWrite), func(c *gin.Context) {
+			controllers.RemoveReaction(c, models.ReactionTargetPost)
+		})
+		privateGroup.PUT("/comments/:id/reactions/:kind", middleware.RequireScope(models.ScopeReactionsWrite), func(c *gin.Context) {
+			controllers.AddReaction(c, models.ReactionTargetComment)
+		})
+		privateGroup.DELETE("/comments/:id/reactions/:kind", middleware.RequireScope(models.ScopeReactionsWrite), func(c *gin.Context) {
+			controllers.RemoveReaction(c, models.ReactionTargetComment)
+		})
+
+		// 用户名补全（@提及）
+		privateGroup.GET("/users/search", middleware.RequireScope(models.ScopeProfileRead), func(c *gin.Context) {
+			controllers.SearchUsers(c, store)
+		})
+
+		// 关注作者与关注动态
+		privateGroup.PUT("/users/:username/follow", middleware.RequireScope(models.ScopeFollowsWrite), controllers.FollowUser)
+		privateGroup.DELETE("/users/:username/follow", middleware.RequireScope(models.ScopeFollowsWrite), controllers.UnfollowUser)
+		privateGroup.GET("/feed", middleware.RequireScope(models.ScopePostsRead), func(c *gin.Context) {
+			controllers.FollowFeed(c, appCfg.PostPermalink)
+		})
+
+		// 站内通知
+		privateGroup.GET("/notifications", middleware.RequireScope(models.ScopeNoticesRead), func(c *gin.Context) {
+			controllers.ListNotifications(c, appCfg.PostPermalink)
+		})
+		privateGroup.GET("/notifications/unread-count", middleware.RequireScope(models.ScopeNoticesRead), controllers.UnreadNotificationCount)
+		privateGroup.POST("/notifications/read", middleware.RequireScope(models.ScopeNoticesWrite), func(c *gin.Context) {
+			controllers.MarkNotificationsRead(c, false)
+		})
+		privateGroup.POST("/notifications/read-all", middleware.RequireScope(models.ScopeNoticesWrite), func(c *gin.Context) {
+			controllers.MarkNotificationsRead(c, true)
+		})
+		privateGroup.GET("/notifications/preferences", middleware.RequireScope(models.ScopeNoticesRead), controllers.GetNotificationPreferences)
+		privateGroup.PUT("/notifications/preferences", middleware.RequireScope(models.ScopeNoticesWrite), controllers.UpdateNotificationPreferences)
+
+		// 收藏与收藏夹（阅读列表）
+		privateGroup.GET("/bookmarks", middleware.RequireScope(models.ScopeBookmarksRead), func(c *gin.Context) {
+			controllers.ListBookmarks(c, appCfg.PostPermalink)
+		})
+		privateGroup.POST("/bookmarks", middleware.RequireScope(models.ScopeBookmarksWrite), controllers.AddBookmark)
+		privateGroup.DELETE("/bookmarks/:post_id", middleware.RequireScope(models.ScopeBookmarksWrite), controllers.RemoveBookmark)
+		privateGroup.GET("/bookmarks/collections", middleware.RequireScope(models.ScopeBookmarksRead), controllers.ListBookmarkCollections)
+		privateGroup.POST("/bookmarks/collections", middleware.RequireScope(models.ScopeBookmarksWrite), controllers.CreateBookmarkCollection)
+		privateGroup.PUT("/bookmarks/collections/:id", middleware.RequireScope(models.ScopeBookmarksWrite), controllers.UpdateBookmarkCollection)
+		privateGroup.DELETE("/bookmarks/collections/:id", middleware.RequireScope(models.ScopeBookmarksWrite), controllers.DeleteBookmarkCollection)
+		privateGroup.GET("/bookmarks/feed.atom", middleware.RequireScope(models.ScopeBookmarksRead), func(c *gin.Context) {
+			controllers.ExportReadingList(c, appCfg)
+		})
+		privateGroup.POST("/bookmarks/feed", middleware.RequireScope(models.ScopeBookmarksWrite), func(c *gin.Context) {
+			controllers.EnableReadingListFeed(c, appCfg)
+		})
+		privateGroup.DELETE("/bookmarks/feed", middleware.RequireScope(models.ScopeBookmarksWrite), controllers.DisableReadingListFeed)
+
+		// 阅读统计：作者查看自己文章的统计，编辑/管理员查看全站
+		privateGroup.GET("/analytics/overview", middleware.RequireScope(models.ScopePostsRead), func(c *gin.Context) {
+			controllers.AnalyticsOverview(c, appCfg)
+		})
+		privateGroup.GET("/analytics/posts/:id", middleware.RequireScope(models.ScopePostsRead), func(c *gin.Context) {
+			controllers.PostAnalytics(c, appCfg)
+		})
+
+		// 媒体库
+		privateGroup.POST("/media", middleware.RequireScope(models.ScopeMediaWrite), func(c *gin.Context) {
+			controllers.UploadMedia(c, store, imagePipeline, appCfg.MediaMaxSize)
+		})
+		privateGroup.GET("/media", middleware.RequireScope(models.ScopeMediaRead), func(c *gin.Context) {
+			controllers.ListMedia(c, store)
+		})
+		privateGroup.PUT("/media/:id", middleware.RequireScope(models.ScopeMediaWrite), func(c *gin.Context) {
+			controllers.UpdateMedia(c, store)
+		})
+		privateGroup.GET("/media/:id/url", middleware.RequireScope(models.ScopeMediaRead), func(c *gin.Context) {
+			controllers.MediaDownloadURL(c, store, appCfg.MediaSignedURLExpire)
+		})
+		privateGroup.DELETE("/media/:id", middleware.RequireScope(models.ScopeMediaWrite), func(c *gin.Context) {
+			controllers.DeleteMedia(c, store)
+		})
+	}
+
+	// 账号安全路由（仅接受JWT，API密钥不能管理密钥或两步验证）
+	accountGroup := r.Group("/api")
+	accountGroup.Use(middleware.JWTAuthMiddleware(jwtManager))
+	{
+		// 两步验证管理
+		accountGroup.POST("/2fa/disable", controllers.DisableTwoFactor)
+		accountGroup.POST("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
+		// 个人数据导出与账号注销
+		accountGroup.GET("/profile/export", func(c *gin.Context) {
+			controllers.ExportAccountData(c, store)
+		})
+		accountGroup.DELETE("/profile", func(c *gin.Context) {
+			controllers.RequestAccountDeletion(c, appCfg.AccountDeletionGraceDays)
+		})
+		accountGroup.POST("/profile/deletion/cancel", controllers.CancelAccountDeletion)
+
+		// 登录会话管理
+		accountGroup.GET("/sessions", controllers.ListSessions)
+		accountGroup.DELETE("/sessions/:id", controllers.RevokeSession)
+		accountGroup.POST("/logout", controllers.Logout)
+
+		// API密钥管理
+		accountGroup.GET("/api-keys", controllers.ListAPIKeys)
+		accountGroup.POST("/api-keys", controllers.CreateAPIKey)
+		accountGroup.DELETE("/api-keys/:id", controllers.RevokeAPIKey)
+
+		// 第三方身份绑定
+		accountGroup.POST("/auth/oidc/:provider/link", func(c *gin.Context) {
+			controllers.OIDCLink(c, oidcRegistry)
+		})
+		accountGroup.GET("/auth/identities", controllers.ListIdentities)
+		accountGroup.DELETE("/auth/identities/:id", controllers.UnlinkIdentity)
+	}
+
+	// 两步验证绑定（同时接受强制绑定临时令牌）
+	twoFactorGroup := r.Group("/api/2fa")
+	twoFactorGroup.Use(middleware.TwoFactorSetupMiddleware(jwtManager))
+	{
+		twoFactorGroup.POST("/setup", controllers.SetupTwoFactor)
+		twoFactorGroup.POST("/confirm", func(c *gin.Context) {
+			controllers.ConfirmTwoFactor(c, jwtManager, appCfg.TokenExpire)
+		})
+	}
+
+	// 管理员路由
+	adminGroup := r.Group("/api/admin")
+	adminGroup.Use(middleware.JWTAuthMiddleware(jwtManager), middleware.RequireRole(models.RoleAdmin))
+	{
+		adminGroup.PUT("/users/:id/role", controllers.SetUserRole)
+		adminGroup.PUT("/users/:id/require-2fa", controllers.SetTwoFactorRequired)
+		// 内容导入：WordPress WXR 或 Markdown/Hugo/Jekyll 目录的zip压缩包
+		adminGroup.POST("/import", func(c *gin.Context) {
+			controllers.ImportContent(c, appCfg, store, imagePipeline)
+		})
 	}
 
 	// 6. 启动服务
-	utils.Log.Info("博客系统启动成功，监听端口: 8080")
-	if err := r.Run(":8080"); err != nil {
-		utils.Log.Fatalf("服务启动失败: %v", err)
-		panic("服务启动失败: " + err.Error())
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		utils.Log.Info("博客系统启动成功，监听端口: 8080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			utils.Log.Fatalf("服务启动失败: %v", err)
+		}
+	}()
+
+	// 7. 收到 SIGINT/SIGTERM 后停止接收新请求，等待进行中的请求完成，再写入内存中的阅读计数
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+	utils.Log.Info("收到退出信号，正在停止服务...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), appCfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		utils.Log.Errorf("服务停止超时: %v", err)
 	}
+	viewCounter.Stop()
+	utils.Log.Info("博客系统已停止")
 }
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API密钥最近使用时间的最小更新间隔，避免每个请求都写库
+const apiKeyTouchInterval = time.Minute
+
+// JWTOrAPIKeyMiddleware 认证中间件：同时接受JWT和个人API密钥
+// API密钥可通过 X-API-Key 头或 Authorization: Bearer 传递
+func JWTOrAPIKeyMiddleware(jwtManager *utils.JWTManager) gin.HandlerFunc {
+	jwtHandler := JWTAuthMiddleware(jwtManager)
+	return func(c *gin.Context) {
+		credential := c.GetHeader("X-API-Key")
+		if credential == "" {
+			credential = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if !utils.IsAPIKey(credential) {
+			jwtHandler(c)
+			return
+		}
+
+		var key models.APIKey
+		if err := config.DB.Where("key_hash = ?", utils.HashAPIKey(credential)).First(&key).Error; err != nil {
+			utils.Log.Warnf("API密钥无效, ip: %s", c.ClientIP())
+			utils.Unauthorized(c, "API密钥无效或已吊销")
+			return
+		}
+		now := time.Now()
+		if key.IsExpired(now) {
+			utils.Log.Warnf("API密钥已过期: key_id: %d, ip: %s", key.ID, c.ClientIP())
+			utils.Unauthorized(c, "API密钥已过期")
+			return
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "username").First(&user, key.UserID).Error; err != nil {
+			utils.Unauthorized(c, "API密钥无效或已吊销")
+			return
+		}
+
+		// 记录最近使用信息（UpdateColumns 不修改 updated_at）
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+			if err := config.DB.Model(&key).UpdateColumns(map[string]interface{}{
+				"last_used_at": now,
+				"last_used_ip": c.ClientIP(),
+			}).Error; err != nil {
+				utils.Log.Warnf("更新API密钥使用记录失败: %v, key_id: %d", err, key.ID)
+			}
+		}
+
+		// 设置上下文
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("auth_type", "api_key")
+		c.Set("api_key_id", key.ID)
+		c.Set("api_key_scopes", key.ScopeList())
+		c.Next()
+	}
+}
+
+// RequireScope 授权范围校验：JWT登录用户拥有全部权限，API密钥需具备指定范围
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "api_key" {
+			c.Next()
+			return
+		}
+		if !containsString(c.GetStringSlice("api_key_scopes"), scope) {
+			utils.Log.Warnf("API密钥缺少授权范围: %s, key_id: %v, path: %s", scope, c.MustGet("api_key_id"), c.FullPath())
+			utils.Forbidden(c, "API密钥缺少授权范围: "+scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware 公开接口的可选认证：携带凭据时按 JWTOrAPIKeyMiddleware 校验并设置用户信息，
+// 未携带时以匿名身份继续（用于“我是否已点赞”等与当前用户相关的字段）
+func OptionalAuthMiddleware(jwtManager *utils.JWTManager) gin.HandlerFunc {
+	auth := JWTOrAPIKeyMiddleware(jwtManager)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" && c.GetHeader("X-API-Key") == "" {
+			c.Next()
+			return
+		}
+		auth(c)
+	}
+}
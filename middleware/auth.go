@@ -1,13 +1,29 @@
 package middleware
 
 import (
+	"go-blog-system/config"
+	"go-blog-system/models"
 	"go-blog-system/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 会话最近活跃时间的最小更新间隔
+const sessionTouchInterval = time.Minute
+
 // JWTAuthMiddleware JWT认证中间件（接收JWT密钥参数）
-func JWTAuthMiddleware(jwtSecret string) gin.HandlerFunc {
+func JWTAuthMiddleware(jwtManager *utils.JWTManager) gin.HandlerFunc {
+	return jwtAuth(jwtManager)
+}
+
+// TwoFactorSetupMiddleware 两步验证绑定接口认证：额外接受强制绑定用的临时令牌
+func TwoFactorSetupMiddleware(jwtManager *utils.JWTManager) gin.HandlerFunc {
+	return jwtAuth(jwtManager, utils.PurposeTwoFactorSetup)
+}
+
+// jwtAuth 校验Token，purposes 为除正常访问令牌外额外允许的令牌用途
+func jwtAuth(jwtManager *utils.JWTManager, purposes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取Token
 		tokenStr := c.GetHeader("Authorization")
@@ -23,16 +39,49 @@ func JWTAuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		// 解析Token
-		claims, err := utils.ParseToken(tokenStr, jwtSecret)
+		claims, err := jwtManager.ParseToken(tokenStr)
 		if err != nil {
 			utils.Log.Warnf("Token解析失败: %v, ip: %s", err, c.ClientIP())
 			utils.Unauthorized(c, "Token无效或已过期")
 			return
 		}
 
+		// 临时令牌只能访问指定接口
+		if claims.Purpose != "" && !containsString(purposes, claims.Purpose) {
+			utils.Log.Warnf("临时令牌访问受限接口: purpose=%s, ip: %s", claims.Purpose, c.ClientIP())
+			utils.Unauthorized(c, "Token无效或已过期")
+			return
+		}
+
+		// 正常访问令牌必须对应未注销的会话
+		if claims.Purpose == "" {
+			var session models.Session
+			if err := config.DB.Where("token_id = ? AND user_id = ?", claims.ID, claims.UserID).First(&session).Error; err != nil {
+				utils.Log.Warnf("会话不存在或已注销: user_id: %d, ip: %s", claims.UserID, c.ClientIP())
+				utils.Unauthorized(c, "登录已失效，请重新登录")
+				return
+			}
+			if now := time.Now(); now.Sub(session.LastSeenAt) > sessionTouchInterval {
+				if err := config.DB.Model(&session).UpdateColumn("last_seen_at", now).Error; err != nil {
+					utils.Log.Warnf("更新会话活跃时间失败: %v, session_id: %d", err, session.ID)
+				}
+			}
+			c.Set("session_id", session.ID)
+		}
+
 		// 设置上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("token_purpose", claims.Purpose)
 		c.Next()
 	}
 }
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 角色校验中间件，需在JWT认证中间件之后使用
+// 角色从数据库实时读取，调整角色后无需重新登录即可生效
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, exists := c.Get("user_id")
+		if !exists {
+			utils.Unauthorized(c, "未获取到用户信息")
+			return
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "role").First(&user, userId).Error; err != nil {
+			utils.Unauthorized(c, "用户不存在")
+			return
+		}
+		if !containsString(roles, user.Role) {
+			utils.Log.Warnf("权限不足: user_id: %d, role: %s, path: %s", user.ID, user.Role, c.FullPath())
+			utils.Forbidden(c, "权限不足")
+			return
+		}
+
+		c.Set("role", user.Role)
+		c.Next()
+	}
+}
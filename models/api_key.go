@@ -0,0 +1,84 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey 对应 api_keys 表，存储用户的个人API密钥（仅保存哈希）
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"user_id"`         // 所属用户ID
+	Name       string     `gorm:"size:50;not null" json:"name"`          // 密钥名称（如 CI发布）
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`        // 密钥前缀，用于在列表中辨认
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // 密钥SHA-256哈希
+	Scopes     string     `gorm:"size:255;not null" json:"-"`            // 授权范围，逗号分隔
+	ExpiresAt  *time.Time `json:"expires_at"`                            // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`                          // 最近使用时间
+	LastUsedIP string     `gorm:"size:64" json:"last_used_ip"`           // 最近使用IP
+}
+
+// API密钥授权范围
+const (
+	ScopePostsRead      = "posts:read"
+	ScopePostsWrite     = "posts:write"
+	ScopeCommentsRead   = "comments:read"
+	ScopeCommentsWrite  = "comments:write"
+	ScopeProfileRead    = "profile:read"
+	ScopeProfileWrite   = "profile:write"
+	ScopeMediaRead      = "media:read"
+	ScopeMediaWrite     = "media:write"
+	ScopeReactionsWrite = "reactions:write"
+	ScopeBookmarksRead  = "bookmarks:read"
+	ScopeBookmarksWrite = "bookmarks:write"
+	ScopeFollowsWrite   = "follows:write"
+	ScopeNoticesRead    = "notifications:read"
+	ScopeNoticesWrite   = "notifications:write"
+)
+
+// AllScopes 全部可用的授权范围
+var AllScopes = []string{
+	ScopePostsRead, ScopePostsWrite,
+	ScopeCommentsRead, ScopeCommentsWrite,
+	ScopeProfileRead, ScopeProfileWrite,
+	ScopeMediaRead, ScopeMediaWrite,
+	ScopeReactionsWrite,
+	ScopeBookmarksRead, ScopeBookmarksWrite,
+	ScopeFollowsWrite,
+	ScopeNoticesRead, ScopeNoticesWrite,
+}
+
+// IsValidScope 判断授权范围是否合法
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeList 返回授权范围列表
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope 判断密钥是否拥有指定授权范围
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired 判断密钥是否已过期
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
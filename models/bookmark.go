@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bookmark 对应 bookmarks 表，用户收藏的文章（稍后阅读）
+// 同一用户对同一文章只收藏一次，可归入一个收藏夹，未归类时 CollectionID 为空
+type Bookmark struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_bookmark_unique,priority:1" json:"user_id"`
+	PostID       uint      `gorm:"not null;uniqueIndex:idx_bookmark_unique,priority:2;index" json:"post_id"`
+	CollectionID *uint     `gorm:"index" json:"collection_id"`
+	Note         string    `gorm:"size:500" json:"note"` // 收藏备注
+	Post         *Post     `gorm:"foreignKey:PostID" json:"post,omitempty"`
+}
+
+// BookmarkCollection 对应 bookmark_collections 表，用户的收藏夹（阅读列表），名称在同一用户下唯一
+type BookmarkCollection struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserID        uint      `gorm:"not null;uniqueIndex:idx_bookmark_collection_name,priority:1" json:"user_id"`
+	Name          string    `gorm:"size:50;not null;uniqueIndex:idx_bookmark_collection_name,priority:2" json:"name"`
+	Description   string    `gorm:"size:255" json:"description"`
+	BookmarkCount int64     `gorm:"-" json:"bookmark_count"` // 收藏夹中的文章数，查询列表时填充
+}
+
+// FillPostBookmarks 为文章列表填充当前用户的收藏状态，userID 为0时全部为未收藏
+func FillPostBookmarks(db *gorm.DB, posts []Post, userID uint) error {
+	if userID == 0 || len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	var bookmarked []uint
+	if err := db.Model(&Bookmark{}).Where("user_id = ? AND post_id IN ?", userID, ids).Pluck("post_id", &bookmarked).Error; err != nil {
+		return err
+	}
+	set := make(map[uint]bool, len(bookmarked))
+	for _, id := range bookmarked {
+		set[id] = true
+	}
+	for i := range posts {
+		posts[i].Bookmarked = set[posts[i].ID]
+	}
+	return nil
+}
@@ -1,14 +1,116 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go-blog-system/render"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Comment 对应 comments 表，存储文章评论信息
 type Comment struct {
-	gorm.Model        // 内置字段：ID、CreatedAt、UpdatedAt、DeletedAt
-	Content    string `gorm:"type:text;not null" json:"content"` // 评论内容，非空
-	UserID     uint   `gorm:"not null" json:"user_id"`           // 关联评论用户ID（外键）
-	PostID     uint   `gorm:"not null" json:"post_id"`           // 关联文章ID（外键）
+	gorm.Model           // 内置字段：ID、CreatedAt、UpdatedAt、DeletedAt
+	Content       string `gorm:"type:text;not null" json:"content"`                       // 评论原文，非空
+	ContentFormat string `gorm:"size:20;not null;default:markdown" json:"content_format"` // 原文格式：markdown/plain
+	RenderedHTML  string `gorm:"type:text" json:"rendered_html"`                          // 渲染并过滤后的HTML（规则比文章更严格）
+	UserID        uint   `gorm:"not null" json:"user_id"`                                 // 关联评论用户ID（外键）
+	PostID        uint   `gorm:"not null" json:"post_id"`                                 // 关联文章ID（外键）
+	ParentID      *uint  `gorm:"index" json:"parent_id,omitempty"`                        // 回复的评论ID，为空表示直接评论文章
+	// 审核状态，只有 approved 的评论公开显示
+	Status      string     `gorm:"size:20;not null;default:approved;index" json:"status"` // approved/pending/rejected/spam
+	ModeratedBy *uint      `json:"-"`                                                     // 最近一次审核操作的用户
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`                                // 最近一次审核时间
+	SpamReason  string     `gorm:"size:200" json:"spam_reason,omitempty"`                 // 被反垃圾检查标记的原因
+	ContentHash string     `gorm:"size:64;index" json:"-"`                                // 归一化内容的哈希，用于识别重复评论
 	// 关联模型：查询时可加载评论用户/所属文章信息
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Post Post `gorm:"foreignKey:PostID" json:"post,omitempty"`
+	// 点赞/回应统计，不入库
+	ReactionStats `gorm:"-"`
+}
+
+// 评论审核状态
+const (
+	CommentApproved = "approved" // 已公开
+	CommentPending  = "pending"  // 待审核
+	CommentRejected = "rejected" // 已拒绝
+	CommentSpam     = "spam"     // 垃圾评论
+)
+
+// 评论审核方式，站点设置 CommentModeration 和文章的 CommentMode 取值相同
+const (
+	CommentModeOpen      = "open"       // 评论直接公开
+	CommentModeFirstTime = "first_time" // 用户首次评论需审核，已有评论通过审核后直接公开
+	CommentModeModerated = "moderated"  // 全部评论需审核
+	CommentModeClosed    = "closed"     // 关闭评论
+)
+
+// IsValidCommentMode 是否为有效的评论审核方式
+func IsValidCommentMode(mode string) bool {
+	switch mode {
+	case CommentModeOpen, CommentModeFirstTime, CommentModeModerated, CommentModeClosed:
+		return true
+	}
+	return false
+}
+
+// ApprovedComments 查询条件：只包含已公开的评论
+func ApprovedComments(db *gorm.DB) *gorm.DB {
+	return db.Where("comments.status = ?", CommentApproved)
+}
+
+// IsTrustedCommenter 文章作者和编辑/管理员的评论不需要审核和反垃圾检查
+func IsTrustedCommenter(post *Post, user *User) bool {
+	return user.ID == post.UserID || user.IsPrivileged()
+}
+
+// NewCommentStatus 按审核方式确定新评论的状态（关闭评论的情况由调用方处理）
+func NewCommentStatus(tx *gorm.DB, mode string, post *Post, user *User) (string, error) {
+	if IsTrustedCommenter(post, user) {
+		return CommentApproved, nil
+	}
+	switch mode {
+	case CommentModeModerated:
+		return CommentPending, nil
+	case CommentModeFirstTime:
+		var count int64
+		if err := tx.Model(&Comment{}).Where("user_id = ? AND status = ?", user.ID, CommentApproved).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return CommentPending, nil
+		}
+	}
+	return CommentApproved, nil
+}
+
+// BeforeSave 保存前根据原文重新渲染HTML（含@提及链接）、计算内容哈希
+func (c *Comment) BeforeSave(tx *gorm.DB) error {
+	if c.ContentFormat == "" {
+		c.ContentFormat = render.FormatMarkdown
+	}
+	var err error
+	if c.RenderedHTML, err = renderMentions(tx, render.Comment(c.ContentFormat, c.Content)); err != nil {
+		return err
+	}
+	c.ContentHash = CommentContentHash(c.Content)
+	return nil
+}
+
+// CommentContentHash 忽略大小写和空白差异后的内容哈希
+func CommentContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.Join(strings.Fields(content), " "))))
+	return hex.EncodeToString(sum[:])
+}
+
+// AfterFind 兼容升级前未渲染的旧数据
+func (c *Comment) AfterFind(tx *gorm.DB) error {
+	if c.RenderedHTML == "" && c.Content != "" {
+		c.RenderedHTML = render.Comment(c.ContentFormat, c.Content)
+	}
+	return nil
 }
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Follow 对应 follows 表，用户关注作者的关系
+type Follow struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	FollowerID uint      `gorm:"not null;uniqueIndex:idx_follow_unique,priority:1" json:"follower_id"`       // 关注者
+	FolloweeID uint      `gorm:"not null;uniqueIndex:idx_follow_unique,priority:2;index" json:"followee_id"` // 被关注的作者
+}
+
+// FollowCounts 用户的粉丝数和关注数
+func FollowCounts(db *gorm.DB, userID uint) (followers, following int64, err error) {
+	if err = db.Model(&Follow{}).Where("followee_id = ?", userID).Count(&followers).Error; err != nil {
+		return
+	}
+	err = db.Model(&Follow{}).Where("follower_id = ?", userID).Count(&following).Error
+	return
+}
+
+// IsFollowing followerID 是否已关注 followeeID
+func IsFollowing(db *gorm.DB, followerID, followeeID uint) (bool, error) {
+	var count int64
+	err := db.Model(&Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count).Error
+	return count > 0, err
+}
+
+// EnsurePostFeedIndex 创建关注动态查询使用的 posts(user_id, deleted_at, created_at, id) 联合索引，
+// 按作者逐个定位并以游标限定时间范围。gorm.Model 中的字段无法通过结构体标签声明联合索引，因此在迁移后单独创建
+func EnsurePostFeedIndex(db *gorm.DB) error {
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_posts_author_created ON posts (user_id, deleted_at, created_at, id)").Error
+}
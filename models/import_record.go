@@ -0,0 +1,20 @@
+package models
+
+import "gorm.io/gorm"
+
+// ImportRecord 记录导入来源中的条目与本地数据的对应关系，重复导入时据此更新而不是重复创建
+type ImportRecord struct {
+	gorm.Model
+	Source     string `gorm:"size:255;not null;uniqueIndex:idx_import_item" json:"source"`      // 导入来源标识，如 wxr:https://old.example.com
+	Kind       string `gorm:"size:20;not null;uniqueIndex:idx_import_item" json:"kind"`         // post/comment/user
+	ExternalID string `gorm:"size:255;not null;uniqueIndex:idx_import_item" json:"external_id"` // 条目在来源中的标识（文件路径、WordPress ID等）
+	LocalID    uint   `gorm:"not null;index" json:"local_id"`                                   // 对应的本地记录ID
+	Checksum   string `gorm:"size:64" json:"checksum"`                                          // 上次导入时的内容摘要，用于判断来源是否有变化
+}
+
+// 导入条目类型
+const (
+	ImportKindPost    = "post"
+	ImportKindComment = "comment"
+	ImportKindUser    = "user"
+)
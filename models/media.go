@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// Media 对应 media 表，媒体库中的图片/附件
+// 相同内容的文件只存储一份（key由内容哈希生成），删除时仅当没有其他记录引用才删除文件
+type Media struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index" json:"user_id"`                       // 上传者
+	PostID      *uint  `gorm:"index" json:"post_id"`                                // 关联文章，为空表示未关联
+	Key         string `gorm:"column:storage_key;size:255;not null;index" json:"-"` // 存储路径
+	Filename    string `gorm:"size:255" json:"filename"`                            // 原始文件名
+	ContentType string `gorm:"size:100;not null" json:"content_type"`               // 根据文件内容识别的MIME类型
+	Size        int64  `gorm:"not null" json:"size"`                                // 文件大小（字节）
+	SHA256      string `gorm:"size:64;not null;index" json:"sha256"`                // 内容哈希
+	Visibility  string `gorm:"size:20;not null;default:public" json:"visibility"`   // public/private
+	// 以下由后台图片处理任务写入
+	Width            int    `json:"width"`                            // 原图宽度（已按EXIF方向校正）
+	Height           int    `json:"height"`                           // 原图高度
+	BlurHash         string `gorm:"size:64" json:"blurhash"`          // 模糊占位图编码
+	Variants         string `gorm:"type:text" json:"-"`               // 衍生图片列表（JSON）
+	ProcessingStatus string `gorm:"size:20" json:"processing_status"` // 非图片为空，图片为 pending/ready/failed
+}
+
+// MediaVariant 图片的衍生版本（响应式尺寸或WebP格式）
+type MediaVariant struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"` // jpeg/png/webp
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+}
+
+// 图片处理状态
+const (
+	MediaProcessingPending = "pending"
+	MediaProcessingReady   = "ready"
+	MediaProcessingFailed  = "failed"
+)
+
+// 媒体可见性
+const (
+	MediaPublic  = "public"  // 可通过公开URL访问，适合文章插图
+	MediaPrivate = "private" // 仅可通过签名URL下载
+)
+
+// TableName 表名
+func (Media) TableName() string {
+	return "media"
+}
+
+// IsImage 是否为可处理的图片
+func (m *Media) IsImage() bool {
+	switch m.ContentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// VariantList 解析衍生图片列表
+func (m *Media) VariantList() []MediaVariant {
+	var variants []MediaVariant
+	if m.Variants != "" {
+		_ = json.Unmarshal([]byte(m.Variants), &variants)
+	}
+	return variants
+}
+
+// SetVariants 保存衍生图片列表
+func (m *Media) SetVariants(variants []MediaVariant) {
+	data, _ := json.Marshal(variants)
+	m.Variants = string(data)
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"go-blog-system/render"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Mention 对应 mentions 表，文章/评论中@提及的用户，保存后与正文同步
+type Mention struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	SourceType string    `gorm:"size:20;not null;uniqueIndex:idx_mention_unique,priority:1" json:"source_type"` // post/comment
+	SourceID   uint      `gorm:"not null;uniqueIndex:idx_mention_unique,priority:2" json:"source_id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_mention_unique,priority:3;index" json:"user_id"` // 被提及的用户
+}
+
+// 提及来源类型
+const (
+	MentionSourcePost    = "post"
+	MentionSourceComment = "comment"
+)
+
+// renderMentions 将渲染结果中提及的已有用户转为主页链接，不存在的用户名保持原样
+func renderMentions(tx *gorm.DB, renderedHTML string) (string, error) {
+	names := render.ParseMentions(renderedHTML)
+	if len(names) == 0 {
+		return renderedHTML, nil
+	}
+	var existing []string
+	// 钩子中的 tx 带有当前语句的条件，需使用新会话查询
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(&User{}).Where("username IN ?", names).Pluck("username", &existing).Error; err != nil {
+		return renderedHTML, err
+	}
+	usernames := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		usernames[name] = true
+	}
+	return render.LinkMentions(renderedHTML, usernames), nil
+}
+
+// SyncMentions 按渲染结果中的提及链接更新提及记录：新增本次提及的用户、删除不再提及的用户，返回新增的用户ID
+func SyncMentions(tx *gorm.DB, sourceType string, sourceID uint, renderedHTML string) ([]uint, error) {
+	var current []uint
+	if names := render.LinkedMentions(renderedHTML); len(names) > 0 {
+		if err := tx.Model(&User{}).Where("username IN ?", names).Pluck("id", &current).Error; err != nil {
+			return nil, err
+		}
+	}
+	var existing []uint
+	if err := tx.Model(&Mention{}).Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Pluck("user_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	had := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		had[id] = true
+	}
+
+	var added []uint
+	for _, id := range current {
+		if !had[id] {
+			if err := tx.Create(&Mention{SourceType: sourceType, SourceID: sourceID, UserID: id}).Error; err != nil {
+				return nil, err
+			}
+			added = append(added, id)
+		}
+	}
+	removed := tx.Where("source_type = ? AND source_id = ?", sourceType, sourceID)
+	if len(current) > 0 {
+		removed = removed.Where("user_id NOT IN ?", current)
+	}
+	if err := removed.Delete(&Mention{}).Error; err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// MentionedUserIDs 文章/评论中提及的用户ID
+func MentionedUserIDs(tx *gorm.DB, sourceType string, sourceID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&Mention{}).Where("source_type = ? AND source_id = ?", sourceType, sourceID).Order("id").Pluck("user_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification 对应 notifications 表，用户的站内通知
+// 同一对象上的同类事件在未读期间合并为一条（如“某某等3人赞了你的文章”），已读后的新事件生成新的通知；
+// 每个接收者同一合并键最多一条未读通知，由 EnsureNotificationGroupIndex 创建的唯一索引保证
+type Notification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`                                                         // 最近一次事件的时间
+	UserID     uint       `gorm:"not null;index:idx_notification_group,priority:1" json:"-"`          // 接收者
+	GroupKey   string     `gorm:"size:100;not null;index:idx_notification_group,priority:2" json:"-"` // 合并键：类型+对象
+	Type       string     `gorm:"size:20;not null" json:"type"`                                       // 通知类型，见 NotificationTypes
+	ActorID    uint       `gorm:"not null" json:"actor_id"`                                           // 最近一次触发事件的用户
+	ActorCount int        `gorm:"not null;default:1" json:"actor_count"`                              // 合并的不同触发者人数，见 NotificationActor
+	PostID     *uint      `json:"post_id,omitempty"`                                                  // 相关文章
+	CommentID  *uint      `json:"comment_id,omitempty"`                                               // 相关评论（合并时为最近一条）
+	ReadAt     *time.Time `gorm:"index" json:"read_at"`                                               // 已读时间，为空表示未读
+	Actor      *User      `gorm:"foreignKey:ActorID" json:"actor,omitempty"`                          // 查询时通过 Preload("Actor") 加载
+	Post       *Post      `gorm:"foreignKey:PostID" json:"post,omitempty"`                            // 查询时通过 Preload("Post") 加载
+}
+
+// NotificationActor 对应 notification_actors 表，合并通知的触发者，用于按人数计算 ActorCount
+type NotificationActor struct {
+	NotificationID uint `gorm:"primaryKey;autoIncrement:false"`
+	ActorID        uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+// EnsureNotificationGroupIndex 创建 notifications(user_id, group_key) 上限定未读通知的部分唯一索引，
+// 并发的事件不会生成重复的未读通知。结构体标签无法声明部分索引，因此在迁移后单独创建；
+// 创建前将重复的未读通知（唯一索引出现之前产生）中较旧的标为已读，并补全未读通知的触发者记录
+func EnsureNotificationGroupIndex(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE notifications SET read_at = ? WHERE read_at IS NULL AND id NOT IN
+			(SELECT MAX(id) FROM notifications WHERE read_at IS NULL GROUP BY user_id, group_key)`, time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`INSERT OR IGNORE INTO notification_actors (notification_id, actor_id)
+			SELECT id, actor_id FROM notifications WHERE read_at IS NULL`).Error; err != nil {
+			return err
+		}
+		return tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_unread_group ON notifications (user_id, group_key) WHERE read_at IS NULL").Error
+	})
+}
+
+// 通知类型
+const (
+	NotifyComment = "comment" // 我的文章收到新评论
+	NotifyReply   = "reply"   // 我的评论收到回复
+	NotifyMention = "mention" // 在文章或评论中被@提及
+	NotifyLike    = "like"    // 我的文章或评论被点赞
+	NotifyFollow  = "follow"  // 被关注
+	NotifyPending = "pending" // 我的文章有待审核的评论
+)
+
+// NotificationTypes 全部通知类型
+var NotificationTypes = []string{NotifyComment, NotifyReply, NotifyMention, NotifyLike, NotifyFollow, NotifyPending}
+
+// IsValidNotificationType 是否为有效的通知类型
+func IsValidNotificationType(typ string) bool {
+	for _, t := range NotificationTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationEnabled 用户是否接收该类通知
+func (u *User) NotificationEnabled(typ string) bool {
+	for _, t := range strings.Split(u.NotificationsMuted, ",") {
+		if t == typ {
+			return false
+		}
+	}
+	return true
+}
+
+// NotificationPreferences 各类通知的开关
+func (u *User) NotificationPreferences() map[string]bool {
+	prefs := make(map[string]bool, len(NotificationTypes))
+	for _, t := range NotificationTypes {
+		prefs[t] = u.NotificationEnabled(t)
+	}
+	return prefs
+}
+
+// NotificationEvent 触发通知的事件
+type NotificationEvent struct {
+	Type      string
+	UserID    uint   // 接收者
+	ActorID   uint   // 触发者
+	Target    string // 合并对象，如 post:1、comment:2；同一接收者、类型和对象的未读通知合并为一条
+	PostID    uint   // 相关文章，0表示无
+	CommentID uint   // 相关评论，0表示无
+}
+
+// Notify 生成通知：触发者是接收者本人、接收者不存在或关闭了该类通知时不生成；
+// 存在同一对象的未读通知时合并到该通知，ActorCount 为不同触发者的人数
+func Notify(db *gorm.DB, e NotificationEvent) error {
+	if e.UserID == 0 || e.UserID == e.ActorID {
+		return nil
+	}
+	var recipient User
+	if err := db.Select("id", "notifications_muted").First(&recipient, e.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !recipient.NotificationEnabled(e.Type) {
+		return nil
+	}
+
+	groupKey := e.Type
+	if e.Target != "" {
+		groupKey += ":" + e.Target
+	}
+	var postID, commentID *uint
+	if e.PostID != 0 {
+		postID = &e.PostID
+	}
+	if e.CommentID != 0 {
+		commentID = &e.CommentID
+	}
+
+	// 先尝试创建未读通知，已存在时（包括并发的同类事件刚刚创建）合并到该通知；
+	// 触发者记录的主键保证同一用户多次触发（如 A、B、A）只计一人
+	return db.Transaction(func(tx *gorm.DB) error {
+		notification := Notification{
+			UserID:     e.UserID,
+			GroupKey:   groupKey,
+			Type:       e.Type,
+			ActorID:    e.ActorID,
+			ActorCount: 1,
+			PostID:     postID,
+			CommentID:  commentID,
+		}
+		result := tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "user_id"}, {Name: "group_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "read_at IS NULL"}}},
+			DoNothing:   true,
+		}).Create(&notification)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return tx.Create(&NotificationActor{NotificationID: notification.ID, ActorID: e.ActorID}).Error
+		}
+
+		var existing Notification
+		if err := tx.Select("id").Where("user_id = ? AND group_key = ? AND read_at IS NULL", e.UserID, groupKey).
+			First(&existing).Error; err != nil {
+			return err
+		}
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&NotificationActor{NotificationID: existing.ID, ActorID: e.ActorID})
+		if result.Error != nil {
+			return result.Error
+		}
+		updates := map[string]interface{}{
+			"actor_id":   e.ActorID,
+			"comment_id": commentID,
+			"updated_at": time.Now(),
+		}
+		if result.RowsAffected == 1 {
+			updates["actor_count"] = gorm.Expr("actor_count + 1")
+		}
+		return tx.Model(&existing).UpdateColumns(updates).Error
+	})
+}
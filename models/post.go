@@ -1,13 +1,69 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"go-blog-system/render"
+
+	"gorm.io/gorm"
+)
 
 // Post 对应 posts 表，存储博客文章信息
 type Post struct {
-	gorm.Model        // 内置字段：ID、CreatedAt、UpdatedAt、DeletedAt
-	Title      string `gorm:"size:200;not null" json:"title"`    // 文章标题，非空
-	Content    string `gorm:"type:text;not null" json:"content"` // 文章内容，文本类型
-	UserID     uint   `gorm:"not null" json:"user_id"`           // 关联用户ID（外键）
+	gorm.Model           // 内置字段：ID、CreatedAt、UpdatedAt、DeletedAt
+	Title         string `gorm:"size:200;not null" json:"title"`                          // 文章标题，非空
+	Slug          string `gorm:"size:120;uniqueIndex;default:null" json:"slug"`           // 文章短链接标识，创建时由标题生成
+	Content       string `gorm:"type:text;not null" json:"content"`                       // 文章原文
+	ContentFormat string `gorm:"size:20;not null;default:markdown" json:"content_format"` // 原文格式：markdown/html/plain
+	RenderedHTML  string `gorm:"type:text" json:"rendered_html"`                          // 渲染并过滤后的HTML，保存时生成
+	UserID        uint   `gorm:"not null" json:"user_id"`                                 // 关联用户ID（外键）
+	CommentMode   string `gorm:"size:20" json:"comment_mode"`                             // 评论审核方式，为空时使用站点设置
+	ViewCount     int64  `gorm:"not null;default:0" json:"view_count"`                    // 累计阅读次数，由后台批量写入
 	// 关联 User 模型（一对一），查询时可通过 Preload("User") 加载用户信息
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	// 文章标签（多对多），查询时通过 Preload("Tags") 加载
+	Tags []Tag `gorm:"many2many:post_tags" json:"tags"`
+	// 按固定链接格式生成的访问路径，不入库
+	Permalink string `gorm:"-" json:"permalink,omitempty"`
+	// 点赞/回应统计，不入库
+	ReactionStats `gorm:"-"`
+	// 当前用户是否已收藏，不入库（未登录时为false）
+	Bookmarked bool `gorm:"-" json:"bookmarked"`
+}
+
+// EffectiveCommentMode 文章实际使用的评论审核方式
+func (p *Post) EffectiveCommentMode(siteMode string) string {
+	if p.CommentMode != "" {
+		return p.CommentMode
+	}
+	return siteMode
+}
+
+// BeforeCreate 未指定slug时根据标题生成唯一slug
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	if p.Slug != "" {
+		return nil
+	}
+	slug, err := UniquePostSlug(tx, Slugify(p.Title), 0)
+	if err != nil {
+		return err
+	}
+	p.Slug = slug
+	return nil
+}
+
+// BeforeSave 保存前根据原文重新渲染HTML（含@提及链接）
+func (p *Post) BeforeSave(tx *gorm.DB) error {
+	if p.ContentFormat == "" {
+		p.ContentFormat = render.FormatMarkdown
+	}
+	var err error
+	p.RenderedHTML, err = renderMentions(tx, render.Post(p.ContentFormat, p.Content))
+	return err
+}
+
+// AfterFind 兼容升级前未渲染的旧数据
+func (p *Post) AfterFind(tx *gorm.DB) error {
+	if p.RenderedHTML == "" && p.Content != "" {
+		p.RenderedHTML = render.Post(p.ContentFormat, p.Content)
+	}
+	return nil
 }
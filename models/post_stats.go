@@ -0,0 +1,22 @@
+package models
+
+// PostViewStat 对应 post_view_stats 表，文章每日阅读统计
+type PostViewStat struct {
+	ID       uint   `gorm:"primaryKey" json:"-"`
+	PostID   uint   `gorm:"not null;uniqueIndex:idx_post_view_day,priority:1" json:"post_id"`
+	Day      string `gorm:"size:10;not null;uniqueIndex:idx_post_view_day,priority:2;index" json:"day"` // 本地日期 YYYY-MM-DD
+	Views    int64  `gorm:"not null;default:0" json:"views"`                                            // 阅读次数（同一访客在去重时间内只计一次）
+	Visitors int64  `gorm:"not null;default:0" json:"visitors"`                                         // 当天的独立访客数
+}
+
+// PostReferrerStat 对应 post_referrer_stats 表，文章每日来源统计
+type PostReferrerStat struct {
+	ID     uint   `gorm:"primaryKey" json:"-"`
+	PostID uint   `gorm:"not null;uniqueIndex:idx_post_referrer_day,priority:1" json:"post_id"`
+	Day    string `gorm:"size:10;not null;uniqueIndex:idx_post_referrer_day,priority:2;index" json:"day"`
+	Host   string `gorm:"size:255;not null;uniqueIndex:idx_post_referrer_day,priority:3" json:"host"` // 来源域名，直接访问为空
+	Views  int64  `gorm:"not null;default:0" json:"views"`
+}
+
+// StatsDayLayout 统计日期格式
+const StatsDayLayout = "2006-01-02"
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Reaction 对应 reactions 表，用户对文章/评论的点赞和表情回应
+// 同一用户对同一对象的每种回应只记录一次，重复提交不产生新记录
+type Reaction struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_reaction_unique,priority:1" json:"user_id"`
+	TargetType string    `gorm:"size:20;not null;uniqueIndex:idx_reaction_unique,priority:2;index:idx_reaction_target,priority:1" json:"target_type"` // post/comment
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_reaction_unique,priority:3;index:idx_reaction_target,priority:2" json:"target_id"`
+	Kind       string    `gorm:"size:20;not null;uniqueIndex:idx_reaction_unique,priority:4" json:"kind"`
+	User       User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// 回应对象类型
+const (
+	ReactionTargetPost    = "post"
+	ReactionTargetComment = "comment"
+)
+
+// ReactionLike 点赞
+const ReactionLike = "like"
+
+// ReactionKinds 支持的回应类型及对应的表情，按展示顺序排列
+var ReactionKinds = []struct {
+	Kind  string `json:"kind"`
+	Emoji string `json:"emoji"`
+}{
+	{ReactionLike, "👍"},
+	{"heart", "❤️"},
+	{"laugh", "😄"},
+	{"hooray", "🎉"},
+	{"confused", "😕"},
+	{"rocket", "🚀"},
+	{"eyes", "👀"},
+}
+
+// IsValidReactionKind 是否为支持的回应类型
+func IsValidReactionKind(kind string) bool {
+	for _, k := range ReactionKinds {
+		if k.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ReactionStats 文章/评论的回应统计，查询列表时批量填充，不入库
+type ReactionStats struct {
+	Reactions   map[string]int64 `json:"reactions"`              // 各类回应的数量
+	LikedByMe   bool             `json:"liked_by_me"`            // 当前用户是否已点赞（未登录时为false）
+	MyReactions []string         `json:"my_reactions,omitempty"` // 当前用户的全部回应
+}
+
+// LoadReactionStats 批量统计多个对象的回应，userID 为0时不查询当前用户的回应
+// 无论对象数量多少都只执行两次查询
+func LoadReactionStats(db *gorm.DB, targetType string, ids []uint, userID uint) (map[uint]*ReactionStats, error) {
+	stats := make(map[uint]*ReactionStats, len(ids))
+	for _, id := range ids {
+		stats[id] = &ReactionStats{Reactions: map[string]int64{}}
+	}
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	var counts []struct {
+		TargetID uint
+		Kind     string
+		Count    int64
+	}
+	if err := db.Model(&Reaction{}).Select("target_id, kind, COUNT(*) AS count").
+		Where("target_type = ? AND target_id IN ?", targetType, ids).
+		Group("target_id, kind").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		stats[c.TargetID].Reactions[c.Kind] = c.Count
+	}
+
+	if userID == 0 {
+		return stats, nil
+	}
+	var mine []Reaction
+	if err := db.Select("target_id", "kind").
+		Where("user_id = ? AND target_type = ? AND target_id IN ?", userID, targetType, ids).
+		Order("id").Find(&mine).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range mine {
+		s := stats[r.TargetID]
+		s.MyReactions = append(s.MyReactions, r.Kind)
+		if r.Kind == ReactionLike {
+			s.LikedByMe = true
+		}
+	}
+	return stats, nil
+}
+
+// FillPostReactions 为文章列表填充回应统计
+func FillPostReactions(db *gorm.DB, posts []Post, userID uint) error {
+	ids := make([]uint, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+	stats, err := LoadReactionStats(db, ReactionTargetPost, ids, userID)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].ReactionStats = *stats[posts[i].ID]
+	}
+	return nil
+}
+
+// FillCommentReactions 为评论列表填充回应统计
+func FillCommentReactions(db *gorm.DB, comments []Comment, userID uint) error {
+	ids := make([]uint, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+	stats, err := LoadReactionStats(db, ReactionTargetComment, ids, userID)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].ReactionStats = *stats[comments[i].ID]
+	}
+	return nil
+}
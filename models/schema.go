@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// SchemaVersion 当前数据库结构版本，表结构有需要迁移处理的变化时递增
+// 备份中记录该版本，恢复时不接受比当前程序更新的备份
+//
+//	2 评论审核状态、文章评论审核方式
+//	3 评论反垃圾标记和内容哈希
+//	4 点赞与表情回应
+//	5 文章浏览统计
+//	6 收藏与收藏夹
+//	7 关注作者
+//	8 站内通知
+//	9 @提及
+//	10 未读通知唯一索引、通知触发者
+//	11 注销账号保留的用户名
+const SchemaVersion = 11
+
+// SchemaInfo 数据库结构版本（单行表）
+type SchemaInfo struct {
+	ID        uint `gorm:"primaryKey"`
+	Version   int  `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+// TableName 表名
+func (SchemaInfo) TableName() string {
+	return "schema_info"
+}
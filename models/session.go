@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session 对应 sessions 表，每次登录签发的Token对应一条会话记录
+type Session struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index" json:"-"`               // 所属用户ID
+	TokenID    string    `gorm:"size:64;not null;uniqueIndex" json:"-"` // Token的jti
+	Device     string    `gorm:"size:100" json:"device"`                // 设备描述（由User-Agent解析）
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`            // 原始User-Agent
+	IP         string    `gorm:"size:64" json:"ip"`                     // 登录IP
+	LastSeenAt time.Time `json:"last_seen_at"`                          // 最近活跃时间
+	ExpiresAt  time.Time `gorm:"index" json:"expires_at"`               // 过期时间（与Token一致）
+}
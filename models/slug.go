@@ -0,0 +1,105 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+)
+
+// PostSlugRedirect 记录文章的历史slug，旧链接永久重定向到当前slug
+type PostSlugRedirect struct {
+	gorm.Model
+	OldSlug string `gorm:"size:120;uniqueIndex;not null" json:"old_slug"` // 历史slug
+	PostID  uint   `gorm:"not null;index" json:"post_id"`                 // 当前对应的文章
+}
+
+// MaxSlugLength slug最大长度（不含冲突后缀）
+const MaxSlugLength = 80
+
+var (
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	numericPattern = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// transliterate 将文本转写为slug：中文转为拼音，其余字符转写为ASCII，文章和标签的slug共用
+func transliterate(s string) string {
+	// unidecode 的汉字表缺少“一”（U+4E00），预先替换
+	return slug.Make(strings.ReplaceAll(s, "一", " yi "))
+}
+
+// Slugify 由标题生成slug：中文转为拼音，其余字符转写为ASCII
+// 纯数字会与文章ID混淆，统一加上 post- 前缀
+func Slugify(title string) string {
+	s := transliterate(title)
+	if len(s) > MaxSlugLength {
+		s = s[:MaxSlugLength]
+		if i := strings.LastIndexByte(s, '-'); i > MaxSlugLength/2 {
+			s = s[:i]
+		}
+		s = strings.TrimRight(s, "-")
+	}
+	if s == "" {
+		return "post"
+	}
+	if numericPattern.MatchString(s) {
+		return "post-" + s
+	}
+	return s
+}
+
+// IsValidSlug 校验手动指定的slug：小写字母数字及连字符，且不能为纯数字
+func IsValidSlug(s string) bool {
+	return len(s) <= MaxSlugLength && slugPattern.MatchString(s) && !numericPattern.MatchString(s)
+}
+
+// UniquePostSlug 在base基础上追加 -2、-3… 直到不与其他文章（含已删除文章和历史slug）冲突
+// excludeID 为当前文章ID，新建时传0
+func UniquePostSlug(tx *gorm.DB, base string, excludeID uint) (string, error) {
+	candidate := base
+	for i := 2; ; i++ {
+		taken, err := postSlugTaken(tx, candidate, excludeID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// postSlugTaken slug是否已被其他文章占用
+func postSlugTaken(tx *gorm.DB, s string, excludeID uint) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&Post{}).Where("slug = ? AND id <> ?", s, excludeID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := tx.Model(&PostSlugRedirect{}).Where("old_slug = ? AND post_id <> ?", s, excludeID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// BackfillPostSlugs 为升级前没有slug的文章补全slug
+func BackfillPostSlugs(db *gorm.DB) error {
+	var posts []Post
+	if err := db.Unscoped().Select("id", "title").Where("slug IS NULL OR slug = ''").Order("id").Find(&posts).Error; err != nil {
+		return err
+	}
+	for _, post := range posts {
+		s, err := UniquePostSlug(db, Slugify(post.Title), post.ID)
+		if err != nil {
+			return err
+		}
+		if err := db.Unscoped().Model(&Post{}).Where("id = ?", post.ID).UpdateColumn("slug", s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
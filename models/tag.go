@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Tag 对应 tags 表，文章标签（多对多，中间表 post_tags）
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"size:50;uniqueIndex;not null" json:"name"` // 标签名
+	Slug string `gorm:"size:80;uniqueIndex;not null" json:"slug"` // 用于URL的标识
+}
+
+// 每篇文章的标签数量上限
+const MaxPostTags = 10
+
+// NormalizeTagNames 去除首尾空白、空标签和重复标签（不区分大小写）
+func NormalizeTagNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// TagSlug 由标签名生成slug，无法转写时返回空字符串
+func TagSlug(name string) string {
+	s := transliterate(name)
+	if len(s) > MaxSlugLength {
+		s = strings.TrimRight(s[:MaxSlugLength], "-")
+	}
+	return s
+}
+
+// FindOrCreateTags 按名称查找标签，不存在则创建（名称不区分大小写）
+func FindOrCreateTags(tx *gorm.DB, names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	for _, name := range NormalizeTagNames(names) {
+		var tag Tag
+		err := tx.Where("LOWER(name) = LOWER(?)", name).First(&tag).Error
+		if err == nil {
+			tags = append(tags, tag)
+			continue
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+
+		base := TagSlug(name)
+		if base == "" {
+			base = "tag"
+		}
+		candidate := base
+		for i := 2; ; i++ {
+			var count int64
+			if err := tx.Model(&Tag{}).Where("slug = ?", candidate).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				break
+			}
+			candidate = base + "-" + strconv.Itoa(i)
+		}
+		tag = Tag{Name: name, Slug: candidate}
+		if err := tx.Create(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode 对应 recovery_codes 表，存储两步验证恢复码（仅保存哈希）
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"not null;index" json:"user_id"`   // 所属用户ID
+	CodeHash string     `gorm:"size:64;not null;index" json:"-"` // 恢复码SHA-256哈希
+	UsedAt   *time.Time `json:"used_at"`                         // 使用时间，为空表示未使用
+}
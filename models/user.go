@@ -1,17 +1,61 @@
 package models
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // User 对应 users 表，存储用户核心信息
 type User struct {
 	// GORM 内置字段：ID（主键）、CreatedAt、UpdatedAt、DeletedAt（软删除）
 	gorm.Model
-	Username string `gorm:"size:50;uniqueIndex;not null" json:"username"` // 用户名，唯一且非空
-	Password string `gorm:"size:100;not null" json:"-"`                   // 密码（加密存储，前端不返回）
-	Email    string `gorm:"size:100;uniqueIndex" json:"email"`            // 邮箱，唯一
+	Username string `gorm:"size:50;uniqueIndex;not null" json:"username"`   // 用户名，唯一且非空
+	Password string `gorm:"size:100;not null" json:"-"`                     // 密码（加密存储，前端不返回）
+	Email    string `gorm:"size:100;uniqueIndex;default:null" json:"email"` // 邮箱，唯一（第三方登录用户可为空）
+	// 个人资料
+	DisplayName string `gorm:"size:50" json:"display_name"` // 显示名称
+	Bio         string `gorm:"size:500" json:"bio"`         // 个人简介
+	Website     string `gorm:"size:200" json:"website"`     // 个人网站
+	Avatar      string `gorm:"size:255" json:"-"`           // 头像存储路径前缀（不含尺寸后缀）
+	// 角色与两步验证
+	Role              string `gorm:"size:20;not null;default:user" json:"role"` // 角色：user/editor/admin
+	TOTPSecret        string `gorm:"size:64" json:"-"`                          // TOTP密钥（未确认时也会暂存）
+	TOTPEnabled       bool   `gorm:"not null;default:false" json:"-"`           // 是否已启用两步验证
+	TOTPLastStep      int64  `json:"-"`                                         // 上次使用的TOTP时间步，防止动态码重放
+	TwoFactorRequired bool   `gorm:"not null;default:false" json:"-"`           // 管理员强制要求两步验证
+	PasswordUnset     bool   `gorm:"not null;default:false" json:"-"`           // 通过第三方登录创建、未设置密码
+	// 账号注销
+	DeletionScheduledAt *time.Time `json:"-"`                // 计划彻底清除的时间，为空表示未申请注销
+	DeletionMode        string     `gorm:"size:20" json:"-"` // 注销时对文章/评论的处理方式：anonymize/delete
+	// 阅读列表私有订阅源令牌的哈希，为空表示未开启
+	ReadingListFeedHash string `gorm:"size:64;index" json:"-"`
+	// 已关闭的站内通知类型，逗号分隔
+	NotificationsMuted string `gorm:"size:100" json:"-"`
+}
+
+// 用户角色
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// AvatarSizes 头像缩略图尺寸（像素）
+var AvatarSizes = []int{256, 128, 64}
+
+// AvatarKey 生成指定尺寸头像的存储路径
+func AvatarKey(prefix string, size int) string {
+	return prefix + "_" + strconv.Itoa(size) + ".jpg"
 }
 
 // BeforeCreate GORM 钩子：创建用户前自动加密密码
@@ -25,8 +69,121 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// 注销时对用户内容的处理方式
+const (
+	DeletionModeAnonymize = "anonymize" // 保留文章和评论，作者显示为已注销用户
+	DeletionModeDelete    = "delete"    // 删除全部文章和评论
+)
+
+// IsPrivileged 是否为编辑/管理员等特权账号
+func (u *User) IsPrivileged() bool {
+	return u.Role == RoleEditor || u.Role == RoleAdmin
+}
+
 // CheckPassword 验证密码是否正确
 func (u *User) CheckPassword(password string) bool {
+	if u.PasswordUnset {
+		return false
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// usernamePattern 用户名只能包含字母、数字、下划线，与@提及的匹配规则一致
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,20}$`)
+
+// IsValidUsername 注册时的用户名格式校验
+func IsValidUsername(name string) bool {
+	return usernamePattern.MatchString(name)
+}
+
+// SanitizeUsername 保留字母、数字、下划线，长度限制在 3~20
+func SanitizeUsername(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	name := b.String()
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	if len(name) < 3 {
+		name = "user" + name
+	}
+	return name
+}
+
+// DeletedUsernamePrefix 匿名化注销账号使用的用户名前缀（deleted_<id>），不能被注册
+const DeletedUsernamePrefix = "deleted_"
+
+// ReservedUsername 对应 reserved_usernames 表，注销账号释放的用户名，不能再被注册，
+// 避免他人注册后继承原用户在文章/评论中的 @提及链接。只保存哈希，不保留注销用户的个人信息
+type ReservedUsername struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Hash      string `gorm:"size:64;not null;uniqueIndex"`
+}
+
+func usernameHash(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+// ReserveUsername 保留注销账号的用户名
+func ReserveUsername(tx *gorm.DB, name string) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ReservedUsername{Hash: usernameHash(name)}).Error
+}
+
+// IsReservedUsername 用户名是否不可注册：匿名化账号的前缀或注销账号保留的用户名
+func IsReservedUsername(tx *gorm.DB, name string) (bool, error) {
+	if strings.HasPrefix(strings.ToLower(name), DeletedUsernamePrefix) {
+		return true, nil
+	}
+	var count int64
+	err := tx.Model(&ReservedUsername{}).Where("hash = ?", usernameHash(name)).Count(&count).Error
+	return count > 0, err
+}
+
+// UniqueUsername 由base生成不与现有用户（含已删除用户）及保留用户名冲突的用户名
+func UniqueUsername(tx *gorm.DB, base string) (string, error) {
+	base = SanitizeUsername(base)
+	if strings.HasPrefix(base, DeletedUsernamePrefix) {
+		base = "user_" + strings.TrimPrefix(base, DeletedUsernamePrefix)
+	}
+	for i := 0; i < 100; i++ {
+		candidate := base
+		if i > 0 {
+			suffix := strconv.Itoa(i + 1)
+			if len(candidate)+len(suffix)+1 > 20 {
+				candidate = candidate[:20-len(suffix)-1]
+			}
+			candidate = candidate + "_" + suffix
+		}
+		reserved, err := IsReservedUsername(tx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if reserved {
+			continue
+		}
+		var count int64
+		if err := tx.Unscoped().Model(&User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+
+	// 连续冲突时使用随机后缀
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if len(base) > 11 {
+		base = base[:11]
+	}
+	return fmt.Sprintf("%s_%x", base, b), nil
+}
@@ -0,0 +1,12 @@
+package models
+
+import "gorm.io/gorm"
+
+// UserIdentity 对应 user_identities 表，记录用户绑定的第三方登录身份
+type UserIdentity struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index" json:"user_id"`                                     // 关联用户ID
+	Provider string `gorm:"size:50;not null;uniqueIndex:idx_provider_subject" json:"provider"` // 提供方标识
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_provider_subject" json:"-"`       // 提供方用户唯一标识（sub）
+	Email    string `gorm:"size:100" json:"email"`                                             // 提供方返回的邮箱
+}
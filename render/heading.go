@@ -0,0 +1,82 @@
+package render
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// headingIDs 标题ID生成器：保留中文等Unicode字母，重名时追加序号
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+// Generate 根据标题文本生成ID
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	lastDash := false
+	for _, r := range strings.TrimSpace(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(unicode.ToLower(r))
+			lastDash = false
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			if !lastDash && b.Len() > 0 {
+				b.WriteByte('-')
+				lastDash = true
+			}
+		}
+	}
+	id := strings.TrimSuffix(b.String(), "-")
+	if id == "" {
+		id = "heading"
+	}
+
+	result := id
+	for i := 1; s.used[result]; i++ {
+		result = id + "-" + strconv.Itoa(i)
+	}
+	s.used[result] = true
+	return []byte(result)
+}
+
+// Put 登记已存在的ID（手动指定的标题属性）
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
+
+// headingAnchorTransformer 为带ID的标题追加锚点链接，便于直接分享到段落
+type headingAnchorTransformer struct{}
+
+// Transform 实现 parser.ASTTransformer
+func (headingAnchorTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+		idBytes, ok := id.([]byte)
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+
+		anchor := ast.NewLink()
+		anchor.Destination = append([]byte("#"), idBytes...)
+		anchor.SetAttributeString("class", []byte("heading-anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MaxMentions 单篇文章或评论最多识别的提及数，超出的 @用户名 保持原样，不生成链接和通知
+const MaxMentions = 20
+
+var (
+	// mentionPattern @提及：@ 前不能是字母数字或 . / @（排除邮箱和链接），用户名为3-20位字母、数字、下划线（注册时限制）
+	mentionPattern = regexp.MustCompile(`(^|[^\w.@/])@(\w{3,20})\b`)
+	// mentionLinkPattern LinkMentions 生成的提及链接
+	mentionLinkPattern = regexp.MustCompile(`<a href="/authors/(\w+)" class="mention">`)
+	// tagPattern HTML标签
+	tagPattern = regexp.MustCompile(`<(/?)([a-zA-Z0-9]+)[^>]*>`)
+)
+
+// mapText 对渲染后HTML中的正文文本逐段调用 fn，链接、代码块和行内代码中的文本保持原样
+func mapText(renderedHTML string, fn func(text string) string) string {
+	var b strings.Builder
+	skip := 0 // 当前所在的 a/code/pre 层数
+	last := 0
+	for _, loc := range tagPattern.FindAllStringSubmatchIndex(renderedHTML, -1) {
+		text := renderedHTML[last:loc[0]]
+		if skip == 0 {
+			text = fn(text)
+		}
+		b.WriteString(text)
+		b.WriteString(renderedHTML[loc[0]:loc[1]])
+		last = loc[1]
+
+		switch strings.ToLower(renderedHTML[loc[4]:loc[5]]) {
+		case "a", "code", "pre":
+			if loc[3] > loc[2] {
+				if skip > 0 {
+					skip--
+				}
+			} else {
+				skip++
+			}
+		}
+	}
+	text := renderedHTML[last:]
+	if skip == 0 {
+		text = fn(text)
+	}
+	b.WriteString(text)
+	return b.String()
+}
+
+// ParseMentions 提取渲染后HTML正文中@提及的用户名（去重，保持出现顺序，最多 MaxMentions 个），链接和代码中的不算
+func ParseMentions(renderedHTML string) []string {
+	var names []string
+	seen := map[string]bool{}
+	mapText(renderedHTML, func(text string) string {
+		for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			if len(names) >= MaxMentions {
+				break
+			}
+			if !seen[m[2]] {
+				seen[m[2]] = true
+				names = append(names, m[2])
+			}
+		}
+		return text
+	})
+	return names
+}
+
+// LinkMentions 将渲染后HTML正文中的 @用户名 转为作者主页链接，只处理 usernames 中的用户
+func LinkMentions(renderedHTML string, usernames map[string]bool) string {
+	if len(usernames) == 0 {
+		return renderedHTML
+	}
+	return mapText(renderedHTML, func(text string) string {
+		// 用户名只含字母数字下划线，无需转义
+		return mentionPattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := mentionPattern.FindStringSubmatch(match)
+			if !usernames[m[2]] {
+				return match
+			}
+			return m[1] + `<a href="/authors/` + m[2] + `" class="mention">@` + m[2] + `</a>`
+		})
+	})
+}
+
+// LinkedMentions 返回 LinkMentions 已转为链接的用户名（去重，最多 MaxMentions 个）
+func LinkedMentions(renderedHTML string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mentionLinkPattern.FindAllStringSubmatch(renderedHTML, -1) {
+		if len(names) >= MaxMentions {
+			break
+		}
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,49 @@
+package render
+
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// classPattern 代码高亮及锚点使用的class
+	classPattern = regexp.MustCompile(`^[a-zA-Z0-9_\- ]+$`)
+	// headingIDPattern 标题ID，允许中文等Unicode字母
+	headingIDPattern = regexp.MustCompile(`^[\p{L}\p{N}_\-]+$`)
+	// languagePattern 代码块语言标记，如 language-go
+	languagePattern = regexp.MustCompile(`^language-[a-zA-Z0-9_+\-]+$`)
+)
+
+// postPolicy 文章白名单：在UGC策略基础上放开代码高亮、标题锚点和任务列表
+var postPolicy = newPostPolicy()
+
+// commentPolicy 评论白名单：仅保留段落、强调、链接、列表、引用和代码
+var commentPolicy = newCommentPolicy()
+
+func newPostPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(classPattern).OnElements("pre", "span", "a", "div")
+	p.AllowAttrs("class").Matching(languagePattern).OnElements("code")
+	p.AllowAttrs("tabindex").Matching(bluemonday.Integer).OnElements("pre")
+	p.AllowAttrs("id").Matching(headingIDPattern).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	// GFM任务列表
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "strong", "em", "del", "code", "pre", "blockquote", "ul", "ol", "li")
+	p.AllowAttrs("class").Matching(classPattern).OnElements("pre", "span")
+	p.AllowAttrs("class").Matching(languagePattern).OnElements("code")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowStandardURLs()
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
@@ -0,0 +1,105 @@
+package render
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// 内容格式
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPlain    = "plain"
+)
+
+// IsValidFormat 校验内容格式是否受支持
+func IsValidFormat(format string) bool {
+	return format == FormatMarkdown || format == FormatHTML || format == FormatPlain
+}
+
+// 代码高亮只输出class，配色由 HighlightCSS 生成的样式表决定，避免sanitize时丢失内联样式
+var highlighter = highlighting.NewHighlighting(
+	highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+	highlighting.WithGuessLanguage(false),
+)
+
+// postMarkdown 文章渲染器：GFM、代码高亮、标题锚点，允许内嵌HTML（随后统一过滤）
+var postMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote, highlighter),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithASTTransformers(util.Prioritized(headingAnchorTransformer{}, 100)),
+	),
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// commentMarkdown 评论渲染器：仅基础语法，不解析内嵌HTML
+var commentMarkdown = goldmark.New(
+	goldmark.WithExtensions(extension.Strikethrough, extension.Linkify, highlighter),
+	goldmark.WithRendererOptions(goldmarkhtml.WithHardWraps()),
+)
+
+// Post 将文章内容渲染为安全的HTML
+func Post(format, content string) string {
+	switch format {
+	case FormatHTML:
+		return postPolicy.Sanitize(content)
+	case FormatPlain:
+		return plainToHTML(content)
+	default:
+		var buf bytes.Buffer
+		ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+		if err := postMarkdown.Convert([]byte(content), &buf, parser.WithContext(ctx)); err != nil {
+			return plainToHTML(content)
+		}
+		return postPolicy.SanitizeReader(&buf).String()
+	}
+}
+
+// Comment 将评论内容渲染为安全的HTML，评论不接受HTML格式
+func Comment(format, content string) string {
+	if format == FormatPlain {
+		return plainToHTML(content)
+	}
+	var buf bytes.Buffer
+	if err := commentMarkdown.Convert([]byte(content), &buf); err != nil {
+		return plainToHTML(content)
+	}
+	return commentPolicy.SanitizeReader(&buf).String()
+}
+
+// plainToHTML 纯文本转HTML：转义后按空行分段，段内换行转为<br>
+func plainToHTML(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	var b strings.Builder
+	for _, para := range strings.Split(content, "\n\n") {
+		para = strings.Trim(para, "\n")
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// HighlightCSS 生成代码高亮样式表，未知样式名回退为默认样式
+func HighlightCSS(style string) (string, error) {
+	var buf bytes.Buffer
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+	if err := formatter.WriteCSS(&buf, styles.Get(style)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,47 @@
+package render
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	textPolicy   = bluemonday.StrictPolicy()
+	spacePattern = regexp.MustCompile(`\s+`)
+	// 标题锚点链接（见 headingAnchorTransformer），摘要中不需要
+	anchorPattern = regexp.MustCompile(`<a [^>]*class="heading-anchor"[^>]*>#</a>`)
+	// 渲染结果中的站内相对地址（以单个 / 开头）
+	relativeURLPattern = regexp.MustCompile(`(href|src)="(/[^/"][^"]*|/)"`)
+	imagePattern       = regexp.MustCompile(`<img [^>]*src="([^"]+)"`)
+)
+
+// Summary 从渲染后的HTML中提取纯文本摘要，超过maxRunes个字符时截断并追加省略号
+func Summary(renderedHTML string, maxRunes int) string {
+	text := html.UnescapeString(textPolicy.Sanitize(anchorPattern.ReplaceAllString(renderedHTML, "")))
+	text = strings.TrimSpace(spacePattern.ReplaceAllString(text, " "))
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:maxRunes])) + "…"
+}
+
+// AbsoluteURLs 将HTML中的站内相对链接和图片地址补全为绝对地址，供订阅源等站外场景使用
+func AbsoluteURLs(renderedHTML, baseURL string) string {
+	base := strings.TrimRight(baseURL, "/")
+	return relativeURLPattern.ReplaceAllStringFunc(renderedHTML, func(attr string) string {
+		m := relativeURLPattern.FindStringSubmatch(attr)
+		return m[1] + `="` + base + m[2] + `"`
+	})
+}
+
+// FirstImage 返回HTML中第一张图片的地址，用作分享卡片的配图
+func FirstImage(renderedHTML string) string {
+	if m := imagePattern.FindStringSubmatch(renderedHTML); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
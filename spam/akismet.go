@@ -0,0 +1,89 @@
+package spam
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Classifier 外部垃圾评论分类服务
+type Classifier interface {
+	Classify(in *Input) (spam bool, err error)
+}
+
+// External 将外部分类服务接入检查流程；服务不可用时检查项返回错误，评论按未命中处理
+type External struct {
+	Service    string
+	Classifier Classifier
+}
+
+func (e External) Name() string { return e.Service }
+
+func (e External) Check(in *Input) (bool, string, error) {
+	spam, err := e.Classifier.Classify(in)
+	if err != nil || !spam {
+		return false, "", err
+	}
+	return true, "外部分类服务判定为垃圾评论", nil
+}
+
+// Akismet Akismet 兼容的 comment-check 接口（Akismet 或自建的兼容服务）
+type Akismet struct {
+	Endpoint string // 服务地址，如 https://rest.akismet.com
+	Key      string
+	Blog     string // 站点地址
+	Client   *http.Client
+}
+
+// NewAkismet 创建 Akismet 检查项
+func NewAkismet(endpoint, key, blog string, timeout time.Duration) Checker {
+	return External{
+		Service: "akismet",
+		Classifier: &Akismet{
+			Endpoint: strings.TrimRight(endpoint, "/"),
+			Key:      key,
+			Blog:     blog,
+			Client:   &http.Client{Timeout: timeout},
+		},
+	}
+}
+
+// Classify 调用 /1.1/comment-check，响应为 true（垃圾评论）或 false
+func (a *Akismet) Classify(in *Input) (bool, error) {
+	form := url.Values{
+		"api_key":              {a.Key},
+		"blog":                 {a.Blog},
+		"user_ip":              {in.IP},
+		"user_agent":           {in.UserAgent},
+		"referrer":             {in.Referrer},
+		"permalink":            {in.PostURL},
+		"comment_type":         {"comment"},
+		"comment_author":       {in.Username},
+		"comment_author_email": {in.Email},
+		"comment_content":      {in.Content},
+		"comment_date_gmt":     {time.Now().UTC().Format(time.RFC3339)},
+	}
+	resp, err := a.Client.PostForm(a.Endpoint+"/1.1/comment-check", form)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.TrimSpace(string(body)) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if help := resp.Header.Get("X-akismet-debug-help"); help != "" {
+		return false, fmt.Errorf("akismet: %s", help)
+	}
+	return false, fmt.Errorf("akismet: 无法识别的响应（HTTP %d）: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,153 @@
+package spam
+
+import (
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Honeypot 蜜罐字段：评论表单中对用户隐藏的字段（接口字段 website），被填写说明是自动程序提交
+type Honeypot struct{}
+
+func (Honeypot) Name() string { return "honeypot" }
+
+func (Honeypot) Check(in *Input) (bool, string, error) {
+	if strings.TrimSpace(in.Honeypot) != "" {
+		return true, "填写了隐藏字段", nil
+	}
+	return false, "", nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s)<>"]+`)
+
+// CountLinks 统计内容中的链接数量（含Markdown链接和裸链接）
+func CountLinks(content string) int {
+	return len(linkPattern.FindAllStringIndex(content, -1))
+}
+
+// LinkLimit 链接数量上限，Max 为负数时不限制
+type LinkLimit struct {
+	Max int
+}
+
+func (LinkLimit) Name() string { return "links" }
+
+func (l LinkLimit) Check(in *Input) (bool, string, error) {
+	if l.Max < 0 {
+		return false, "", nil
+	}
+	if n := CountLinks(in.Content); n > l.Max {
+		return true, fmt.Sprintf("包含 %d 个链接，超过上限 %d", n, l.Max), nil
+	}
+	return false, "", nil
+}
+
+// BannedContent 屏蔽词（不区分大小写的子串匹配）和屏蔽规则（正则表达式）
+type BannedContent struct {
+	words    []string
+	patterns []*regexp.Regexp
+}
+
+// NewBannedContent 创建屏蔽词检查，空白的屏蔽词会被忽略
+func NewBannedContent(words []string, patterns []*regexp.Regexp) *BannedContent {
+	b := &BannedContent{patterns: patterns}
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			b.words = append(b.words, w)
+		}
+	}
+	return b
+}
+
+func (*BannedContent) Name() string { return "banned" }
+
+func (b *BannedContent) Check(in *Input) (bool, string, error) {
+	text := strings.ToLower(in.Content)
+	for _, w := range b.words {
+		if strings.Contains(text, w) {
+			return true, "包含屏蔽词: " + w, nil
+		}
+	}
+	for _, re := range b.patterns {
+		if re.MatchString(in.Content) {
+			return true, "匹配屏蔽规则: " + re.String(), nil
+		}
+	}
+	return false, "", nil
+}
+
+// Duplicate 重复内容：同一用户在 Window 内重复发表相同内容，或 Window 内已有 FloodLimit 条相同的带链接内容
+// 内容比较忽略大小写和空白差异，Window 不大于0时不检查
+type Duplicate struct {
+	Window     time.Duration
+	FloodLimit int
+}
+
+func (Duplicate) Name() string { return "duplicate" }
+
+func (d Duplicate) Check(in *Input) (bool, string, error) {
+	if d.Window <= 0 {
+		return false, "", nil
+	}
+	hash := models.CommentContentHash(in.Content)
+	since := time.Now().Add(-d.Window)
+
+	var count int64
+	if err := config.DB.Model(&models.Comment{}).Where("user_id = ? AND content_hash = ? AND created_at >= ?", in.UserID, hash, since).
+		Count(&count).Error; err != nil {
+		return false, "", err
+	}
+	if count > 0 {
+		return true, "重复发表相同内容", nil
+	}
+
+	// 相同的推广链接由多个账号发表
+	if d.FloodLimit > 0 && CountLinks(in.Content) > 0 {
+		if err := config.DB.Model(&models.Comment{}).Where("content_hash = ? AND created_at >= ?", hash, since).
+			Count(&count).Error; err != nil {
+			return false, "", err
+		}
+		if count >= int64(d.FloodLimit) {
+			return true, fmt.Sprintf("相同的带链接内容已出现 %d 次", count), nil
+		}
+	}
+	return false, "", nil
+}
+
+// Reputation 用户信誉：被标记为垃圾的评论达到 SpamLimit 条且多于已公开的评论时，后续评论直接标记
+// SpamLimit 不大于0时不检查
+type Reputation struct {
+	SpamLimit int
+}
+
+func (Reputation) Name() string { return "reputation" }
+
+func (r Reputation) Check(in *Input) (bool, string, error) {
+	if r.SpamLimit <= 0 {
+		return false, "", nil
+	}
+	var stats []struct {
+		Status string
+		Count  int64
+	}
+	if err := config.DB.Model(&models.Comment{}).Select("status, COUNT(*) AS count").
+		Where("user_id = ? AND status IN ?", in.UserID, []string{models.CommentSpam, models.CommentApproved}).
+		Group("status").Scan(&stats).Error; err != nil {
+		return false, "", err
+	}
+	var spam, approved int64
+	for _, s := range stats {
+		if s.Status == models.CommentSpam {
+			spam = s.Count
+		} else {
+			approved = s.Count
+		}
+	}
+	if spam >= int64(r.SpamLimit) && spam > approved {
+		return true, fmt.Sprintf("该用户已有 %d 条垃圾评论", spam), nil
+	}
+	return false, "", nil
+}
@@ -0,0 +1,100 @@
+package spam
+
+import (
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/utils"
+	"regexp"
+	"time"
+)
+
+// 评论反垃圾检查：依次执行各检查项，任一项判定为垃圾评论即停止，评论进入 spam 状态等待人工复核，
+// 而不是直接拒绝，误判的评论可在审核队列中恢复
+
+// Input 待检查的评论及其上下文
+type Input struct {
+	Content       string
+	ContentFormat string
+	UserID        uint
+	Username      string
+	Email         string
+	UserCreatedAt time.Time
+	PostID        uint
+	PostURL       string // 文章的绝对地址
+	IP            string
+	UserAgent     string
+	Referrer      string
+	Honeypot      string // 隐藏表单字段的值，正常用户不会填写
+}
+
+// Checker 反垃圾检查项
+type Checker interface {
+	// Name 检查项名称，出现在判定原因中
+	Name() string
+	// Check 返回是否为垃圾评论及原因
+	Check(in *Input) (spam bool, reason string, err error)
+}
+
+// Result 检查结果
+type Result struct {
+	Spam    bool
+	Checker string // 判定为垃圾评论的检查项
+	Reason  string
+}
+
+// Pipeline 按顺序执行的检查项
+type Pipeline struct {
+	checkers []Checker
+}
+
+// NewPipeline 由检查项组成检查流程
+func NewPipeline(checkers ...Checker) *Pipeline {
+	return &Pipeline{checkers: checkers}
+}
+
+// Add 追加检查项
+func (p *Pipeline) Add(c Checker) {
+	p.checkers = append(p.checkers, c)
+}
+
+// Check 依次执行检查项；检查项出错时记录日志并跳过，不影响正常评论
+func (p *Pipeline) Check(in *Input) Result {
+	if p == nil {
+		return Result{}
+	}
+	for _, c := range p.checkers {
+		spam, reason, err := c.Check(in)
+		if err != nil {
+			utils.Log.Warnf("[Spam] 检查项 %s 执行失败: %v, user_id: %d", c.Name(), err, in.UserID)
+			continue
+		}
+		if spam {
+			return Result{Spam: true, Checker: c.Name(), Reason: reason}
+		}
+	}
+	return Result{}
+}
+
+// FromConfig 按配置组装检查流程：蜜罐字段、链接数量、屏蔽词、重复内容、用户信誉，配置了 Akismet 密钥时追加外部分类服务
+func FromConfig(cfg *config.AppConfig) (*Pipeline, error) {
+	patterns := make([]*regexp.Regexp, 0, len(cfg.CommentBannedPatterns))
+	for _, s := range cfg.CommentBannedPatterns {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("屏蔽规则格式错误 %q: %w", s, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	p := NewPipeline(
+		Honeypot{},
+		LinkLimit{Max: cfg.CommentMaxLinks},
+		NewBannedContent(cfg.CommentBannedWords, patterns),
+		Duplicate{Window: cfg.CommentDuplicateWindow, FloodLimit: cfg.CommentDuplicateLimit},
+		Reputation{SpamLimit: cfg.CommentReputationLimit},
+	)
+	if cfg.AkismetKey != "" {
+		p.Add(NewAkismet(cfg.AkismetEndpoint, cfg.AkismetKey, cfg.SiteURL, cfg.AkismetTimeout))
+	}
+	return p, nil
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage 本地磁盘存储
+type LocalStorage struct {
+	Root    string // 存储根目录
+	BaseURL string // 对外访问前缀（如 /uploads）
+	secret  []byte // 签名URL密钥
+}
+
+// NewLocalStorage 创建本地磁盘存储，secret用于签发私有文件的临时下载地址
+func NewLocalStorage(root, baseURL, secret string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{Root: root, BaseURL: strings.TrimRight(baseURL, "/"), secret: []byte(secret)}, nil
+}
+
+// fullPath 将key转换为磁盘路径，禁止跳出根目录
+func (s *LocalStorage) fullPath(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", errors.New("storage: 非法的文件路径")
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
+
+// Save 保存文件
+func (s *LocalStorage) Save(key string, r io.Reader, contentType string) error {
+	p, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	// 先写临时文件再重命名，避免读到写了一半的文件
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Open 读取文件
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	p, err := s.fullPath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete 删除文件
+func (s *LocalStorage) Delete(key string) error {
+	p, err := s.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL 返回文件访问地址
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + path.Clean("/"+key)
+}
+
+// SignedURL 返回带过期时间和HMAC签名的下载地址，由 VerifySignature 校验
+func (s *LocalStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	if _, err := s.fullPath(key); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("signature", s.sign(key, expires))
+	return s.URL(key) + "?" + q.Encode(), nil
+}
+
+// VerifySignature 校验签名URL的参数
+func (s *LocalStorage) VerifySignature(key, expires, signature string) bool {
+	ts, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > ts {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(key, expires)))
+}
+
+// sign 计算 key+过期时间 的签名
+func (s *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(path.Clean("/" + key)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FilePath 返回key对应的磁盘路径，供HTTP文件服务使用
+func (s *LocalStorage) FilePath(key string) (string, error) {
+	return s.fullPath(key)
+}
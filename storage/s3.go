@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options S3兼容存储配置（AWS S3、MinIO、R2等）
+type S3Options struct {
+	Endpoint      string // 服务地址，如 s3.amazonaws.com、127.0.0.1:9000
+	Region        string
+	Bucket        string
+	AccessKey     string
+	SecretKey     string
+	UseSSL        bool
+	PublicBaseURL string // 公开文件访问前缀（如CDN地址），为空时使用 endpoint/bucket
+}
+
+// S3Storage S3兼容对象存储
+// 公开文件（PublicPrefixes）允许匿名读取，私有文件（private/ 前缀）仅通过预签名URL访问；
+// bucket 没有策略时自动设置该读取策略，已有策略时保持不变，由运维人员自行确保公开前缀可匿名读取
+type S3Storage struct {
+	client  *minio.Client
+	bucket  string
+	baseURL string
+}
+
+// s3Timeout 单次对象操作超时时间
+const s3Timeout = 5 * time.Minute
+
+// NewS3Storage 创建S3兼容存储，bucket不存在时自动创建
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("storage: S3 endpoint 和 bucket 不能为空")
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, opts.Bucket, minio.MakeBucketOptions{Region: opts.Region}); err != nil {
+			return nil, err
+		}
+	}
+	if err := ensurePublicReadPolicy(ctx, client, opts.Bucket); err != nil {
+		// 部分S3兼容服务（如R2）不支持bucket策略，只提示不中断启动
+		log.Printf("[Storage] 设置bucket公开读取策略失败: %v，请手动允许匿名读取 %s 前缀", err, strings.Join(PublicPrefixes, "、"))
+	}
+
+	baseURL := strings.TrimRight(opts.PublicBaseURL, "/")
+	if baseURL == "" {
+		scheme := "http"
+		if opts.UseSSL {
+			scheme = "https"
+		}
+		baseURL = scheme + "://" + opts.Endpoint + "/" + opts.Bucket
+	}
+	return &S3Storage{client: client, bucket: opts.Bucket, baseURL: baseURL}, nil
+}
+
+// ensurePublicReadPolicy bucket 没有策略时设置公开前缀的匿名读取策略，私有前缀不在其中
+func ensurePublicReadPolicy(ctx context.Context, client *minio.Client, bucket string) error {
+	current, err := client.GetBucketPolicy(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if current != "" {
+		return nil
+	}
+	resources := make([]string, 0, len(PublicPrefixes))
+	for _, prefix := range PublicPrefixes {
+		resources = append(resources, "arn:aws:s3:::"+bucket+"/"+prefix+"*")
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string][]string{"AWS": {"*"}},
+			"Action":    []string{"s3:GetObject"},
+			"Resource":  resources,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return client.SetBucketPolicy(ctx, bucket, string(policy))
+}
+
+// objectKey 规范化key，去掉开头的斜杠
+func objectKey(key string) string {
+	return strings.TrimLeft(key, "/")
+}
+
+// Save 上传对象
+func (s *S3Storage) Save(key string, r io.Reader, contentType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	// 已知长度时直接上传，避免为未知长度的数据缓冲分片
+	size := int64(-1)
+	if l, ok := r.(interface{ Len() int }); ok {
+		size = int64(l.Len())
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, objectKey(key), r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// Open 读取对象
+func (s *S3Storage) Open(key string) (io.ReadCloser, error) {
+	ctx := context.Background()
+	if _, err := s.client.StatObject(ctx, s.bucket, objectKey(key), minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s.client.GetObject(ctx, s.bucket, objectKey(key), minio.GetObjectOptions{})
+}
+
+// Delete 删除对象，对象不存在时S3同样返回成功
+func (s *S3Storage) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	return s.client.RemoveObject(ctx, s.bucket, objectKey(key), minio.RemoveObjectOptions{})
+}
+
+// URL 返回对象的公开访问地址
+func (s *S3Storage) URL(key string) string {
+	return s.baseURL + "/" + objectKey(key)
+}
+
+// SignedURL 返回预签名的临时下载地址
+func (s *S3Storage) SignedURL(key string, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, objectKey(key), ttl, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
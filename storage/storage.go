@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: 文件不存在")
+
+// PrivatePrefix 私有文件的key前缀，只能通过签名URL访问
+const PrivatePrefix = "private/"
+
+// PublicPrefixes 公开文件（媒体库公开文件及其缩略图、头像）的key前缀，S3存储据此设置匿名读取策略
+var PublicPrefixes = []string{"media/", "avatars/"}
+
+// IsPrivateKey 判断key是否为私有文件
+func IsPrivateKey(key string) bool {
+	return strings.HasPrefix(strings.TrimLeft(key, "/"), PrivatePrefix)
+}
+
+// Storage 文件存储后端接口，头像、媒体库等上传文件均通过该接口读写
+type Storage interface {
+	// Save 保存文件，key为存储路径（如 avatars/1/abc_128.jpg）
+	Save(key string, r io.Reader, contentType string) error
+	// Open 读取文件
+	Open(key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(key string) error
+	// URL 返回文件的公开访问地址
+	URL(key string) string
+	// SignedURL 返回带签名的临时下载地址，过期后失效
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
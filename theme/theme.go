@@ -0,0 +1,162 @@
+package theme
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// 内置默认主题，编译进二进制
+//
+//go:embed default
+var defaultTheme embed.FS
+
+// Pages 主题需要提供的页面模板（templates/<name>.html）
+var Pages = []string{"home", "post", "author", "tag", "archive", "search", "error"}
+
+// Theme 已加载的主题：每个页面模板与公共布局组合成独立的模板集
+type Theme struct {
+	pages       map[string]*template.Template
+	static      fs.FS
+	fingerprint string
+}
+
+// overlayFS 优先读取自定义主题目录，不存在的文件回退到内置主题
+type overlayFS struct {
+	custom fs.FS
+	base   fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.custom.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir 合并两边的目录内容，同名文件以自定义主题为准
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	custom, customErr := fs.ReadDir(o.custom, name)
+	base, baseErr := fs.ReadDir(o.base, name)
+	if customErr != nil && baseErr != nil {
+		return nil, baseErr
+	}
+	seen := make(map[string]bool, len(custom))
+	entries := append([]fs.DirEntry(nil), custom...)
+	for _, e := range custom {
+		seen[e.Name()] = true
+	}
+	for _, e := range base {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Load 加载主题，dir为自定义主题目录（为空时只用内置主题）
+// 自定义目录与内置主题结构相同，只需放入要覆盖的文件：
+// templates/layout.html、templates/partials.html、templates/<页面>.html 以及 static/ 下的静态文件
+func Load(dir string, funcs template.FuncMap) (*Theme, error) {
+	files, err := fs.Sub(defaultTheme, "default")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("主题目录不存在: %s", dir)
+		}
+		files = overlayFS{custom: os.DirFS(dir), base: files}
+	}
+
+	// 主题内容摘要，静态导出据此判断主题是否变化
+	hash := sha256.New()
+	parseFile := func(tmpl *template.Template, name string) error {
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return fmt.Errorf("读取模板失败: %s: %w", name, err)
+		}
+		if _, err := tmpl.Parse(string(data)); err != nil {
+			return fmt.Errorf("解析模板失败: %s: %w", name, err)
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(data))
+		hash.Write(data)
+		return nil
+	}
+
+	base := template.New("layout").Funcs(funcs)
+	for _, name := range []string{"templates/layout.html", "templates/partials.html"} {
+		if err := parseFile(base, name); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &Theme{pages: make(map[string]*template.Template, len(Pages))}
+	for _, page := range Pages {
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err := parseFile(tmpl, "templates/"+page+".html"); err != nil {
+			return nil, err
+		}
+		t.pages[page] = tmpl
+	}
+	if t.static, err = fs.Sub(files, "static"); err != nil {
+		return nil, err
+	}
+	err = fs.WalkDir(t.static, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(t.static, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "static/%s\x00%d\x00", name, len(data))
+		hash.Write(data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.fingerprint = hex.EncodeToString(hash.Sum(nil))
+	return t, nil
+}
+
+// Render 渲染页面，先写入缓冲区，模板出错时不会输出残缺的页面
+func (t *Theme) Render(w io.Writer, page string, data interface{}) error {
+	tmpl, ok := t.pages[page]
+	if !ok {
+		return fmt.Errorf("页面模板不存在: %s", page)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Static 主题静态文件
+func (t *Theme) Static() fs.FS {
+	return t.static
+}
+
+// Fingerprint 主题模板与静态文件的内容摘要
+func (t *Theme) Fingerprint() string {
+	return t.fingerprint
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// decodeBase83 测试用的 base83 解码
+func decodeBase83(s string) int {
+	v := 0
+	for _, c := range s {
+		v = v*83 + strings.IndexRune(blurHashCharacters, c)
+	}
+	return v
+}
+
+func solidImage(w, h int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestEncodeBase83(t *testing.T) {
+	cases := []struct {
+		value, length int
+		want          string
+	}{
+		{0, 1, "0"},
+		{82, 1, "~"},
+		{83, 2, "10"},
+		{0xffffff, 4, "TSUA"},
+	}
+	for _, tc := range cases {
+		if got := encodeBase83(tc.value, tc.length); got != tc.want {
+			t.Errorf("encodeBase83(%d, %d) = %q, want %q", tc.value, tc.length, got, tc.want)
+		}
+	}
+	for _, v := range []int{0, 1, 82, 83, 6888, 0x123456, 0xffffff} {
+		if got := decodeBase83(encodeBase83(v, 4)); got != v {
+			t.Errorf("base83 往返 %d -> %d", v, got)
+		}
+	}
+}
+
+// acChannels 解出交流分量三个通道的量化值
+func acChannels(pair string) [3]int {
+	v := decodeBase83(pair)
+	return [3]int{v / (19 * 19), v / 19 % 19, v % 19}
+}
+
+func TestEncodeBlurHashSolid(t *testing.T) {
+	c := color.NRGBA{R: 200, G: 40, B: 90, A: 255}
+	hash := EncodeBlurHash(solidImage(64, 48, c), 4, 3)
+
+	if len(hash) != 4+2*4*3 {
+		t.Fatalf("长度 = %d, want %d: %s", len(hash), 4+2*4*3, hash)
+	}
+	if got := decodeBase83(hash[:1]); got != (4-1)+(3-1)*9 {
+		t.Errorf("分量标志 = %d", got)
+	}
+	dc := decodeBase83(hash[2:6])
+	if r, g, b := dc>>16, dc>>8&0xff, dc&0xff; r != 200 || g != 40 || b != 90 {
+		t.Errorf("平均色 = (%d,%d,%d), want (200,40,90)", r, g, b)
+	}
+
+	// 与尺寸无关：内部统一缩小到约32px后计算
+	if other := EncodeBlurHash(solidImage(640, 480, c), 4, 3); other != hash {
+		t.Errorf("不同尺寸的纯色图片结果不同: %s / %s", other, hash)
+	}
+	// 只有平均色分量时长度为6
+	if one := EncodeBlurHash(solidImage(1, 1, c), 1, 1); len(one) != 6 || one[2:] != hash[2:6] {
+		t.Errorf("1x1 分量 = %q", one)
+	}
+}
+
+func TestEncodeBlurHashGray(t *testing.T) {
+	// 左黑右白的水平渐变
+	gradient := image.NewNRGBA(image.Rect(0, 0, 100, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 100; x++ {
+			v := uint8(x * 255 / 99)
+			gradient.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	hash := EncodeBlurHash(gradient, 4, 3)
+	if len(hash) != 28 {
+		t.Fatalf("长度 = %d: %s", len(hash), hash)
+	}
+	for _, ch := range hash {
+		if !strings.ContainsRune(blurHashCharacters, ch) {
+			t.Fatalf("非法字符 %q: %s", ch, hash)
+		}
+	}
+	// 灰度图片每个分量的三个通道相同
+	dc := decodeBase83(hash[2:6])
+	if r, g, b := dc>>16, dc>>8&0xff, dc&0xff; r != g || g != b {
+		t.Errorf("平均色不是灰色: (%d,%d,%d)", r, g, b)
+	}
+	for i := 6; i < len(hash); i += 2 {
+		if ch := acChannels(hash[i : i+2]); ch[0] != ch[1] || ch[1] != ch[2] {
+			t.Errorf("交流分量 %d 通道不一致: %v", (i-6)/2, ch)
+		}
+	}
+
+	// 第一个横向分量的基函数左正右负，左暗右亮时为负（量化值小于中间值9），与同平均色的纯色图片不同
+	if ch := acChannels(hash[6:8]); ch[0] >= 9 {
+		t.Errorf("横向分量 = %v，应小于9", ch)
+	}
+	solid := EncodeBlurHash(solidImage(100, 20, color.NRGBA{uint8(dc >> 16), uint8(dc >> 16), uint8(dc >> 16), 255}), 4, 3)
+	if solid[6:8] == hash[6:8] {
+		t.Error("渐变与纯色图片的横向分量不应相同")
+	}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor 分页游标格式错误
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor 将列表最后一项的（时间, ID）编码为不透明的分页游标
+func EncodeCursor(t time.Time, id uint) string {
+	raw := t.Format(time.RFC3339Nano) + "|" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标
+func DecodeCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return t, uint(id), nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// cwebp 单张图片的编码超时时间
+const cwebpTimeout = time.Minute
+
+// EncodeWebPLossy 调用 cwebp 将图片有损编码为WebP，透明通道保留
+// 内置编码器只支持无损格式，照片类图片的无损WebP通常比JPEG更大，因此优先使用 cwebp
+func EncodeWebPLossy(cwebp string, img image.Image, quality int) ([]byte, error) {
+	src, err := EncodePNG(img)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "cwebp-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.webp")
+	if err := os.WriteFile(in, src, 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cwebpTimeout)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cwebp, "-quiet", "-q", strconv.Itoa(quality), "-metadata", "none", in, "-o", out)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, errors.New("cwebp: " + string(msg))
+		}
+		return nil, err
+	}
+	return os.ReadFile(out)
+}
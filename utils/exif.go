@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// 上传图片的元数据清理：去除EXIF（含GPS定位）、XMP、IPTC、注释等信息。
+// 清理在字节层面完成，不重新编码，原图画质不受影响；JPEG仅保留方向信息以免图片显示方向错误。
+
+var errInvalidImageData = errors.New("图片数据格式错误")
+
+// StripImageMetadata 去除图片中的元数据，不支持的格式原样返回
+func StripImageMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	default:
+		return data, nil
+	}
+}
+
+// stripJPEGMetadata 删除APP1(EXIF/XMP)、APP13(IPTC)等段及注释，保留JFIF、ICC、Adobe段
+// 原图带方向信息时，在JFIF段（或SOI）之后写入仅含方向的EXIF段
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, errInvalidImageData
+	}
+
+	orientation := ImageOrientation(data)
+	var out bytes.Buffer
+	out.Write(data[:2])
+	if orientation != 1 && !(data[2] == 0xff && data[3] == 0xe0) {
+		out.Write(orientationSegment(orientation))
+	}
+
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xff {
+			return nil, errInvalidImageData
+		}
+		marker := data[pos+1]
+		// 填充字节
+		if marker == 0xff {
+			pos++
+			continue
+		}
+		// 图像数据开始，其后内容原样保留
+		if marker == 0xda {
+			out.Write(data[pos:])
+			return out.Bytes(), nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, errInvalidImageData
+		}
+		payload := data[pos+4 : end]
+
+		keep := true
+		switch {
+		case marker == 0xe2:
+			keep = bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+		case marker == 0xe1, marker == 0xfe, marker >= 0xe3 && marker <= 0xed, marker == 0xef:
+			keep = false
+		}
+		if keep {
+			out.Write(data[pos:end])
+		}
+		if marker == 0xe0 && pos == 2 && orientation != 1 {
+			out.Write(orientationSegment(orientation))
+		}
+		pos = end
+	}
+}
+
+// orientationSegment 生成只包含方向标签的APP1段
+func orientationSegment(orientation int) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(8)) // IFD0偏移
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(1)) // 条目数
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(0x0112))
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(3)) // SHORT
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(1))
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(orientation))
+	_ = binary.Write(&tiff, binary.BigEndian, uint16(0))
+	_ = binary.Write(&tiff, binary.BigEndian, uint32(0)) // 无后续IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	seg := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// exifOrientation 从APP1载荷中读取方向标签（0x0112），读取失败返回0
+func exifOrientation(payload []byte) int {
+	if !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+		return 0
+	}
+	tiff := payload[6:]
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8:]))
+			if o >= 1 && o <= 8 {
+				return o
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// ImageOrientation 读取JPEG的EXIF方向，其他格式或无方向信息时返回1
+func ImageOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xff {
+		marker := data[pos+1]
+		if marker == 0xda {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			break
+		}
+		if marker == 0xe1 {
+			if o := exifOrientation(data[pos+4 : pos+2+length]); o > 0 {
+				return o
+			}
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// ApplyOrientation 按EXIF方向旋转/翻转图片，使其正向显示
+func ApplyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	in := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90°
+				dx, dy = h-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转90°
+				dx, dy = y, w-1-x
+			}
+			si := in.PixOffset(x, y)
+			di := out.PixOffset(dx, dy)
+			copy(out.Pix[di:di+4], in.Pix[si:si+4])
+		}
+	}
+	return out
+}
+
+// stripPNGMetadata 删除eXIf及文本、时间等辅助块
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	const sig = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(sig)) {
+		return nil, errInvalidImageData
+	}
+	drop := map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+	var out bytes.Buffer
+	out.WriteString(sig)
+	pos := len(sig)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errInvalidImageData
+		}
+		if !drop[string(data[pos+4:pos+8])] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+	return out.Bytes(), nil
+}
+
+// stripWebPMetadata 删除EXIF/XMP块并清除VP8X中对应的标志位
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errInvalidImageData
+	}
+
+	var body bytes.Buffer
+	pos := 12
+	for pos+8 <= len(data) {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size&1
+		if end > len(data) {
+			end = len(data)
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF、XMP标志
+			}
+			body.Write(chunk)
+		default:
+			body.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	_ = binary.Write(&out, binary.LittleEndian, uint32(4+body.Len()))
+	out.WriteString("WEBP")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// 测试数据中用于确认元数据已被清除的标记
+const (
+	gpsMarker     = "GPS-SECRET-LOCATION"
+	xmpMarker     = "xmp-secret-creator"
+	commentMarker = "comment-secret"
+)
+
+// exifWithGPS 生成带方向标签和GPS子IFD的APP1段（小端序）
+func exifWithGPS(orientation int) []byte {
+	le := binary.LittleEndian
+	var tiff bytes.Buffer
+	tiff.WriteString("II\x2a\x00")
+	binary.Write(&tiff, le, uint32(8))
+	// IFD0：方向、GPS IFD指针
+	binary.Write(&tiff, le, uint16(2))
+	binary.Write(&tiff, le, []uint16{0x0112, 3})
+	binary.Write(&tiff, le, uint32(1))
+	binary.Write(&tiff, le, []uint16{uint16(orientation), 0})
+	gpsIFD := uint32(8 + 2 + 2*12 + 4)
+	binary.Write(&tiff, le, []uint16{0x8825, 4})
+	binary.Write(&tiff, le, uint32(1))
+	binary.Write(&tiff, le, gpsIFD)
+	binary.Write(&tiff, le, uint32(0))
+	// GPS IFD：GPSProcessingMethod（ASCII，数据在IFD之后）
+	binary.Write(&tiff, le, uint16(1))
+	binary.Write(&tiff, le, []uint16{0x001b, 2})
+	binary.Write(&tiff, le, uint32(len(gpsMarker)+1))
+	binary.Write(&tiff, le, gpsIFD+2+12+4)
+	binary.Write(&tiff, le, uint32(0))
+	tiff.WriteString(gpsMarker + "\x00")
+
+	return jpegSegment(0xe1, append([]byte("Exif\x00\x00"), tiff.Bytes()...))
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+// testJPEG 生成在SOI之后插入 segments 的JPEG
+func testJPEG(t *testing.T, segments ...[]byte) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("编码JPEG失败: %v", err)
+	}
+	data := buf.Bytes()
+	out := append([]byte(nil), data[:2]...)
+	for _, seg := range segments {
+		out = append(out, seg...)
+	}
+	return append(out, data[2:]...)
+}
+
+func assertNoMarkers(t *testing.T, data []byte, markers ...string) {
+	t.Helper()
+	for _, m := range markers {
+		if bytes.Contains(data, []byte(m)) {
+			t.Errorf("清理后仍包含 %q", m)
+		}
+	}
+}
+
+func TestStripJPEGMetadataRemovesGPS(t *testing.T) {
+	jfif := jpegSegment(0xe0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	icc := jpegSegment(0xe2, []byte("ICC_PROFILE\x00\x01\x01fake-profile"))
+	src := testJPEG(t,
+		jfif,
+		exifWithGPS(6),
+		jpegSegment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00"+xmpMarker)),
+		jpegSegment(0xed, []byte("Photoshop 3.0\x00iptc")),
+		icc,
+		jpegSegment(0xfe, []byte(commentMarker)),
+	)
+	if !bytes.Contains(src, []byte(gpsMarker)) || ImageOrientation(src) != 6 {
+		t.Fatal("测试数据构造错误")
+	}
+
+	out, err := StripImageMetadata(src, "image/jpeg")
+	if err != nil {
+		t.Fatalf("StripImageMetadata: %v", err)
+	}
+	assertNoMarkers(t, out, gpsMarker, xmpMarker, commentMarker, "Photoshop 3.0")
+	if !bytes.Contains(out, jfif) || !bytes.Contains(out, icc) {
+		t.Error("JFIF/ICC 段不应被删除")
+	}
+	if got := ImageOrientation(out); got != 6 {
+		t.Errorf("方向 = %d, want 6", got)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("清理后无法解码: %v", err)
+	}
+
+	// 图像数据原样保留
+	sos := bytes.Index(src, []byte{0xff, 0xda})
+	if !bytes.HasSuffix(out, src[sos:]) {
+		t.Error("图像数据被修改")
+	}
+}
+
+func TestStripJPEGMetadataWithoutJFIF(t *testing.T) {
+	src := testJPEG(t, exifWithGPS(3))
+	out, err := StripImageMetadata(src, "image/jpeg")
+	if err != nil {
+		t.Fatalf("StripImageMetadata: %v", err)
+	}
+	assertNoMarkers(t, out, gpsMarker)
+	if got := ImageOrientation(out); got != 3 {
+		t.Errorf("方向 = %d, want 3", got)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("清理后无法解码: %v", err)
+	}
+
+	// 无方向信息时不写入新的EXIF段
+	plain := testJPEG(t, exifWithGPS(1))
+	out, err = StripImageMetadata(plain, "image/jpeg")
+	if err != nil {
+		t.Fatalf("StripImageMetadata: %v", err)
+	}
+	if bytes.Contains(out, []byte("Exif\x00\x00")) {
+		t.Error("方向为1时不应保留EXIF段")
+	}
+}
+
+func TestStripJPEGMetadataInvalid(t *testing.T) {
+	if _, err := StripImageMetadata([]byte("not a jpeg"), "image/jpeg"); err == nil {
+		t.Error("非JPEG数据应返回错误")
+	}
+	truncated := testJPEG(t, exifWithGPS(6))
+	if _, err := StripImageMetadata(truncated[:30], "image/jpeg"); err == nil {
+		t.Error("截断的数据应返回错误")
+	}
+}
+
+func pngChunk(typ string, data []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(typ)
+	buf.Write(data)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	return buf.Bytes()
+}
+
+func TestStripPNGMetadata(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	img.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 128})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("编码PNG失败: %v", err)
+	}
+	data := buf.Bytes()
+	ihdrEnd := 8 + 12 + 13
+	var src []byte
+	src = append(src, data[:ihdrEnd]...)
+	src = append(src, pngChunk("eXIf", exifWithGPS(1)[10:])...) // 去掉JPEG段头和Exif前缀
+	src = append(src, pngChunk("tEXt", []byte("Comment\x00"+commentMarker))...)
+	src = append(src, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"+xmpMarker))...)
+	src = append(src, data[ihdrEnd:]...)
+
+	out, err := StripImageMetadata(src, "image/png")
+	if err != nil {
+		t.Fatalf("StripImageMetadata: %v", err)
+	}
+	assertNoMarkers(t, out, gpsMarker, commentMarker, xmpMarker)
+	if !bytes.Equal(out, data) {
+		t.Error("清理后应与原始PNG一致")
+	}
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("清理后无法解码: %v", err)
+	}
+}
+
+func riffChunk(fourCC string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(fourCC)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 6, 5))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 3)
+	}
+	simple, err := EncodeWebP(img)
+	if err != nil {
+		t.Fatalf("EncodeWebP: %v", err)
+	}
+	vp8l := simple[12:] // 去掉RIFF头，保留VP8L块
+
+	// 扩展格式：VP8X（带EXIF、XMP、ICC标志）+ VP8L + EXIF + XMP
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04 | 0x20
+	w, h := 6-1, 5-1
+	vp8x[4], vp8x[5], vp8x[6] = byte(w), byte(w>>8), byte(w>>16)
+	vp8x[7], vp8x[8], vp8x[9] = byte(h), byte(h>>8), byte(h>>16)
+	var body []byte
+	body = append(body, riffChunk("VP8X", vp8x)...)
+	body = append(body, vp8l...)
+	body = append(body, riffChunk("EXIF", exifWithGPS(1)[10:])...)
+	body = append(body, riffChunk("XMP ", []byte(xmpMarker))...)
+	src := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(4+len(body)))...)
+	src = append(append(src, "WEBP"...), body...)
+
+	out, err := StripImageMetadata(src, "image/webp")
+	if err != nil {
+		t.Fatalf("StripImageMetadata: %v", err)
+	}
+	assertNoMarkers(t, out, gpsMarker, xmpMarker)
+	if got := int(binary.LittleEndian.Uint32(out[4:])); got != len(out)-8 {
+		t.Errorf("RIFF 长度 = %d, want %d", got, len(out)-8)
+	}
+	if flags := out[20]; flags&(0x08|0x04) != 0 || flags&0x20 == 0 {
+		t.Errorf("VP8X 标志 = %#x，应清除EXIF/XMP并保留其他标志", flags)
+	}
+	if _, err := webp.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("清理后无法解码: %v", err)
+	}
+}
+
+func TestImageOrientation(t *testing.T) {
+	for o := 1; o <= 8; o++ {
+		if got := ImageOrientation(testJPEG(t, exifWithGPS(o))); got != o {
+			t.Errorf("ImageOrientation = %d, want %d", got, o)
+		}
+	}
+	if got := ImageOrientation(testJPEG(t)); got != 1 {
+		t.Errorf("无EXIF时 ImageOrientation = %d, want 1", got)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 2x1 图片：左红右蓝
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	red, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}
+	src.SetNRGBA(0, 0, red)
+	src.SetNRGBA(1, 0, blue)
+
+	cases := []struct {
+		orientation int
+		size        image.Point
+		red         image.Point // 红色像素的位置
+	}{
+		{1, image.Pt(2, 1), image.Pt(0, 0)},
+		{2, image.Pt(2, 1), image.Pt(1, 0)},
+		{3, image.Pt(2, 1), image.Pt(1, 0)},
+		{6, image.Pt(1, 2), image.Pt(0, 0)},
+		{8, image.Pt(1, 2), image.Pt(0, 1)},
+	}
+	for _, tc := range cases {
+		got := ApplyOrientation(src, tc.orientation)
+		b := got.Bounds()
+		if b.Size() != tc.size {
+			t.Errorf("orientation %d: 尺寸 %v, want %v", tc.orientation, b.Size(), tc.size)
+			continue
+		}
+		c := color.NRGBAModel.Convert(got.At(b.Min.X+tc.red.X, b.Min.Y+tc.red.Y)).(color.NRGBA)
+		if c != red {
+			t.Errorf("orientation %d: %v 处颜色 %v, want 红色", tc.orientation, tc.red, c)
+		}
+	}
+}
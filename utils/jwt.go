@@ -1,45 +1,101 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 // Claims JWT声明结构体
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
-	jwt.StandardClaims
+	Purpose  string `json:"purpose,omitempty"` // 令牌用途，为空表示正常访问令牌
+	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userID uint, username string, jwtSecret string, expireHours int) (string, error) {
-	expireTime := time.Now().Add(time.Hour * time.Duration(expireHours))
+// 临时令牌用途
+const (
+	PurposeTwoFactorLogin = "2fa_login" // 已通过密码校验，等待输入动态码
+	PurposeTwoFactorSetup = "2fa_setup" // 被强制要求启用两步验证，仅可访问绑定接口
+)
+
+// ChallengeTokenExpire 两步验证临时令牌有效期
+const ChallengeTokenExpire = 5 * time.Minute
+
+// ErrTokenKeyNotFound Token的kid不在当前可用密钥中
+var ErrTokenKeyNotFound = errors.New("未知的签名密钥")
+
+// GenerateToken 生成JWT Token，同时返回声明（含jti，用于登记会话）
+func (m *JWTManager) GenerateToken(userID uint, username string, expireHours int) (string, *Claims, error) {
+	return m.sign(userID, username, "", time.Hour*time.Duration(expireHours))
+}
+
+// GenerateChallengeToken 生成两步验证临时令牌（短有效期，不可用于访问普通接口）
+func (m *JWTManager) GenerateChallengeToken(userID uint, username, purpose string) (string, error) {
+	token, _, err := m.sign(userID, username, purpose, ChallengeTokenExpire)
+	return token, err
+}
+
+// sign 使用当前签名密钥签发Token，header中携带kid
+func (m *JWTManager) sign(userID uint, username, purpose string, ttl time.Duration) (string, *Claims, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expireTime.Unix(),
-			Issuer:    "go-blog-system",
+		Purpose:  purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti),
+			Issuer:    m.issuer,
+			Audience:  jwt.ClaimStrings{m.audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
+	key := m.signingKey
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, &claims, nil
 }
 
-// ParseToken 解析JWT Token
-func ParseToken(tokenStr string, jwtSecret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+// ParseToken 解析并校验JWT Token
+// 严格校验：kid必须为已知密钥、alg必须与该密钥一致，并校验issuer/audience/过期时间
+func (m *JWTManager) ParseToken(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := m.keys[kid]
+		if !ok {
+			return nil, ErrTokenKeyNotFound
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.verifyKey, nil
+	},
+		jwt.WithValidMethods(m.methods),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(m.audience),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
 		return nil, err
 	}
-
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if !token.Valid {
+		return nil, jwt.ErrTokenSignatureInvalid
 	}
-	return nil, jwt.ErrSignatureInvalid
+	return claims, nil
 }
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go-blog-system/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// insecureJWTSecret 旧版本内置的 HS256 默认密钥，已公开，任何人都能用它签发Token
+const insecureJWTSecret = "blog-jwt-secret-2025"
+
+// 密钥文件命名：<kid>.pem 为私钥（可签名+验证），<kid>.pub.pem 为公钥（仅验证，用于轮换后的旧密钥）
+const (
+	privateKeySuffix = ".pem"
+	publicKeySuffix  = ".pub.pem"
+)
+
+// jwtKey 单个签名/验证密钥
+type jwtKey struct {
+	ID        string
+	method    jwt.SigningMethod
+	signKey   interface{}      // 私钥或HMAC密钥，仅验证的公钥为nil
+	verifyKey interface{}      // 公钥或HMAC密钥
+	publicKey crypto.PublicKey // 非对称公钥，用于JWKS
+}
+
+// JWTManager JWT签发与校验管理器，支持多把验证密钥以实现密钥轮换
+type JWTManager struct {
+	issuer     string
+	audience   string
+	signingKey *jwtKey
+	keys       map[string]*jwtKey
+	methods    []string
+}
+
+// NewJWTManager 根据配置加载密钥
+// HS256 使用共享密钥（JWT_SECRET 或自动生成的密钥文件）；RS256/EdDSA 从密钥目录加载，目录为空时自动生成一把
+func NewJWTManager(cfg *config.AppConfig) (*JWTManager, error) {
+	m := &JWTManager{
+		issuer:   cfg.JWTIssuer,
+		audience: cfg.JWTAudience,
+		keys:     make(map[string]*jwtKey),
+	}
+
+	if cfg.JWTAlgorithm == JWTAlgHS256 {
+		if len(cfg.JWTSecretKey) < 16 {
+			return nil, errors.New("HS256 密钥长度不能少于16字节")
+		}
+		if cfg.JWTSecretKey == insecureJWTSecret {
+			return nil, errors.New("HS256 密钥不能使用旧版本内置的公开默认值，请修改 JWT_SECRET")
+		}
+		key := &jwtKey{ID: "hs256", method: jwt.SigningMethodHS256, signKey: []byte(cfg.JWTSecretKey), verifyKey: []byte(cfg.JWTSecretKey)}
+		m.keys[key.ID] = key
+		m.signingKey = key
+		m.methods = []string{JWTAlgHS256}
+		return m, nil
+	}
+	if cfg.JWTAlgorithm != JWTAlgRS256 && cfg.JWTAlgorithm != JWTAlgEdDSA {
+		return nil, fmt.Errorf("不支持的JWT算法: %s", cfg.JWTAlgorithm)
+	}
+
+	if err := os.MkdirAll(cfg.JWTKeyDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := m.loadKeyDir(cfg.JWTKeyDir); err != nil {
+		return nil, err
+	}
+
+	// 选择签名密钥：优先使用指定kid，否则使用kid排序最大的私钥（建议以日期命名kid）
+	var signable []string
+	for kid, key := range m.keys {
+		if key.signKey != nil && key.method.Alg() == cfg.JWTAlgorithm {
+			signable = append(signable, kid)
+		}
+	}
+	sort.Strings(signable)
+	switch {
+	case cfg.JWTSigningKeyID != "":
+		key, ok := m.keys[cfg.JWTSigningKeyID]
+		if !ok || key.signKey == nil {
+			return nil, fmt.Errorf("签名密钥不存在或缺少私钥: %s", cfg.JWTSigningKeyID)
+		}
+		m.signingKey = key
+	case len(signable) > 0:
+		m.signingKey = m.keys[signable[len(signable)-1]]
+	default:
+		key, err := generateKeyFile(cfg.JWTKeyDir, cfg.JWTAlgorithm)
+		if err != nil {
+			return nil, err
+		}
+		m.keys[key.ID] = key
+		m.signingKey = key
+	}
+
+	seen := map[string]bool{}
+	for _, key := range m.keys {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			m.methods = append(m.methods, alg)
+		}
+	}
+	return m, nil
+}
+
+// SigningKeyID 当前签名密钥ID
+func (m *JWTManager) SigningKeyID() string {
+	return m.signingKey.ID
+}
+
+// loadKeyDir 加载目录中的全部密钥文件
+func (m *JWTManager) loadKeyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeySuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		var key *jwtKey
+		if strings.HasSuffix(name, publicKeySuffix) {
+			key, err = parsePublicKey(strings.TrimSuffix(name, publicKeySuffix), data)
+		} else {
+			key, err = parsePrivateKey(strings.TrimSuffix(name, privateKeySuffix), data)
+		}
+		if err != nil {
+			return fmt.Errorf("加载密钥 %s 失败: %w", name, err)
+		}
+		// 同一kid同时存在私钥和公钥时以私钥为准
+		if existing, ok := m.keys[key.ID]; ok && existing.signKey != nil {
+			continue
+		}
+		m.keys[key.ID] = key
+	}
+	return nil
+}
+
+// parsePrivateKey 解析PKCS#8私钥
+func parsePrivateKey(kid string, data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的PEM")
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA密钥长度不能小于2048位")
+		}
+		return &jwtKey{ID: kid, method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey, publicKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		pub := k.Public().(ed25519.PublicKey)
+		return &jwtKey{ID: kid, method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: pub, publicKey: pub}, nil
+	default:
+		return nil, errors.New("仅支持RSA和Ed25519密钥")
+	}
+}
+
+// parsePublicKey 解析PKIX公钥（仅用于验证）
+func parsePublicKey(kid string, data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的PEM")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return &jwtKey{ID: kid, method: jwt.SigningMethodRS256, verifyKey: k, publicKey: k}, nil
+	case ed25519.PublicKey:
+		return &jwtKey{ID: kid, method: jwt.SigningMethodEdDSA, verifyKey: k, publicKey: k}, nil
+	default:
+		return nil, errors.New("仅支持RSA和Ed25519密钥")
+	}
+}
+
+// generateKeyFile 生成新密钥并以PKCS#8格式保存
+func generateKeyFile(dir, alg string) (*jwtKey, error) {
+	var priv crypto.Signer
+	var err error
+	if alg == JWTAlgRS256 {
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	kid := time.Now().Format("20060102150405")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+privateKeySuffix), data, 0600); err != nil {
+		return nil, err
+	}
+	Log.Infof("已生成新的JWT签名密钥: kid=%s, alg=%s", kid, alg)
+	return parsePrivateKey(kid, data)
+}
+
+// JWK 公钥的JSON Web Key表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS 返回全部可用于验证的公钥（对称密钥不会公开）
+func (m *JWTManager) JWKS() []JWK {
+	kids := make([]string, 0, len(m.keys))
+	for kid := range m.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		key := m.keys[kid]
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: JWTAlgRS256,
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				Kty: "OKP", Kid: kid, Use: "sig", Alg: JWTAlgEdDSA,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return keys
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"go-blog-system/storage"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// 媒体库允许上传的文件类型（按内容识别）及保存时使用的扩展名
+// 不允许SVG/HTML等可执行脚本的类型
+var mediaTypeExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+	"audio/mpeg":      ".mp3",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+}
+
+// ErrUnsupportedMedia 不支持的媒体类型
+var ErrUnsupportedMedia = errors.New("不支持的文件类型，仅支持图片、PDF、ZIP、纯文本及常见音视频")
+
+// SniffMediaType 根据文件内容（而非扩展名或客户端声明）识别媒体类型
+func SniffMediaType(data []byte) (contentType, ext string, err error) {
+	contentType, _, err = mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "", "", ErrUnsupportedMedia
+	}
+	ext, ok := mediaTypeExtensions[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedMedia
+	}
+	return contentType, ext, nil
+}
+
+// MediaKey 以内容哈希生成存储路径，相同内容的文件共用一份存储
+func MediaKey(data []byte, ext, visibility string) (key, sum string) {
+	hash := sha256.Sum256(data)
+	sum = hex.EncodeToString(hash[:])
+	key = "media/" + sum[:2] + "/" + sum + ext
+	if visibility == models.MediaPrivate {
+		key = storage.PrivatePrefix + key
+	}
+	return key, sum
+}
+
+// SanitizeFilename 清理客户端提供的文件名，仅用于展示和下载时的文件名
+func SanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	if len(name) > 200 {
+		name = name[len(name)-200:]
+	}
+	return name
+}
+
+// DeleteUnreferencedMedia 删除已不被任何媒体记录引用的文件及其衍生图片
+func DeleteUnreferencedMedia(store storage.Storage, items ...models.Media) {
+	for i := range items {
+		var count int64
+		if err := config.DB.Model(&models.Media{}).Where("storage_key = ?", items[i].Key).Count(&count).Error; err != nil {
+			Log.Warnf("检查媒体引用失败: %v, key: %s", err, items[i].Key)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		keys := []string{items[i].Key}
+		for _, v := range items[i].VariantList() {
+			keys = append(keys, v.Key)
+		}
+		for _, key := range keys {
+			if err := store.Delete(key); err != nil {
+				Log.Warnf("删除媒体文件失败: %v, key: %s", err, key)
+			}
+		}
+	}
+}
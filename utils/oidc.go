@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"go-blog-system/config"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrOIDCProviderNotFound 未配置的登录提供方
+var ErrOIDCProviderNotFound = errors.New("未配置该登录提供方")
+
+// OIDC授权请求有效期
+const OIDCStateExpire = 10 * time.Minute
+
+// OIDCClient 单个OIDC提供方客户端
+type OIDCClient struct {
+	Name       string
+	OAuth2     *oauth2.Config
+	Verifier   *oidc.IDTokenVerifier
+	TrustEmail bool // 是否信任该提供方的已验证邮箱
+}
+
+// OIDCIdentity ID Token 中解析出的用户信息
+type OIDCIdentity struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Nonce             string `json:"nonce"`
+}
+
+// OIDCAuthRequest 发起授权时暂存的状态（state/nonce/PKCE）
+type OIDCAuthRequest struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	BindingHash  string // 发起授权的浏览器所持Cookie的哈希，回调时校验，防止登录/绑定CSRF
+	LinkUserID   uint   // 非0表示为已登录用户绑定身份
+	CreatedAt    time.Time
+}
+
+// OIDCRegistry OIDC提供方注册表，首次使用时才进行服务发现
+type OIDCRegistry struct {
+	mu       sync.Mutex
+	configs  map[string]config.OIDCProvider
+	clients  map[string]*OIDCClient
+	requests map[string]*OIDCAuthRequest // key 为 state
+}
+
+// NewOIDCRegistry 创建OIDC注册表
+func NewOIDCRegistry(providers []config.OIDCProvider) *OIDCRegistry {
+	r := &OIDCRegistry{
+		configs:  make(map[string]config.OIDCProvider),
+		clients:  make(map[string]*OIDCClient),
+		requests: make(map[string]*OIDCAuthRequest),
+	}
+	for _, p := range providers {
+		r.configs[p.Name] = p
+	}
+	return r
+}
+
+// Providers 返回已配置的提供方名称
+func (r *OIDCRegistry) Providers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.configs))
+	for name := range r.configs {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Client 获取提供方客户端（发现失败不缓存，下次请求重试）
+func (r *OIDCRegistry) Client(ctx context.Context, name string) (*OIDCClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[name]; ok {
+		return client, nil
+	}
+	cfg, ok := r.configs[name]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	client := &OIDCClient{
+		Name: name,
+		OAuth2: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID}, cfg.Scopes...),
+		},
+		Verifier:   provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		TrustEmail: cfg.TrustEmail,
+	}
+	r.clients[name] = client
+	return client, nil
+}
+
+// AuthCodeURL 生成授权地址，并暂存 state/nonce/PKCE 校验信息；
+// binding 为与本次授权绑定的随机串，由调用方写入发起授权的浏览器的Cookie，回调时传给 TakeAuthRequest
+func (r *OIDCRegistry) AuthCodeURL(client *OIDCClient, linkUserID uint) (authURL, binding string, err error) {
+	state, err := RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	binding, err = RandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	r.mu.Lock()
+	r.cleanupLocked()
+	r.requests[state] = &OIDCAuthRequest{
+		Provider:     client.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		BindingHash:  oidcBindingHash(binding),
+		LinkUserID:   linkUserID,
+		CreatedAt:    time.Now(),
+	}
+	r.mu.Unlock()
+
+	return client.OAuth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), binding, nil
+}
+
+// TakeAuthRequest 取出并删除state对应的授权请求（一次性）；
+// binding 须与发起授权时生成的一致，回调来自其他浏览器（攻击者转交的回调地址）时不通过
+func (r *OIDCRegistry) TakeAuthRequest(state, provider, binding string) (*OIDCAuthRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, ok := r.requests[state]
+	if !ok {
+		return nil, false
+	}
+	delete(r.requests, state)
+	if req.Provider != provider || time.Since(req.CreatedAt) > OIDCStateExpire {
+		return nil, false
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(oidcBindingHash(binding)), []byte(req.BindingHash)) != 1 {
+		return nil, false
+	}
+	return req, true
+}
+
+func oidcBindingHash(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// Exchange 使用授权码换取并校验ID Token
+func (c *OIDCClient) Exchange(ctx context.Context, code string, req *OIDCAuthRequest) (*OIDCIdentity, error) {
+	token, err := c.OAuth2.Exchange(ctx, code, oauth2.VerifierOption(req.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("响应中缺少 id_token")
+	}
+	idToken, err := c.Verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity OIDCIdentity
+	if err := idToken.Claims(&identity); err != nil {
+		return nil, err
+	}
+	if identity.Nonce != req.Nonce {
+		return nil, errors.New("nonce 校验失败")
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("id_token 缺少 sub")
+	}
+	return &identity, nil
+}
+
+// cleanupLocked 清理过期的授权请求，调用方需持有锁
+func (r *OIDCRegistry) cleanupLocked() {
+	for state, req := range r.requests {
+		if time.Since(req.CreatedAt) > OIDCStateExpire {
+			delete(r.requests, state)
+		}
+	}
+}
+
+// RandomToken 生成URL安全的随机串
+func RandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
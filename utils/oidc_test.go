@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"context"
+	"go-blog-system/config"
+	"go-blog-system/internal/oidctest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestOIDCRegistry(t *testing.T) (*OIDCRegistry, *OIDCClient, *oidctest.Provider) {
+	t.Helper()
+	provider := oidctest.NewProvider(t, "blog")
+	registry := NewOIDCRegistry([]config.OIDCProvider{{
+		Name:         "mock",
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "blog",
+		ClientSecret: "secret",
+		RedirectURL:  "http://blog.test/api/auth/oidc/mock/callback",
+		Scopes:       []string{"profile", "email"},
+	}})
+	client, err := registry.Client(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("服务发现失败: %v", err)
+	}
+	return registry, client, provider
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	registry, client, _ := newTestOIDCRegistry(t)
+
+	authURL, binding, err := registry.AuthCodeURL(client, 0)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if binding == "" || strings.Contains(authURL, binding) {
+		t.Errorf("binding 不应为空或出现在授权地址中: %q", binding)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("授权地址格式错误: %v", err)
+	}
+	q := u.Query()
+	for _, key := range []string{"state", "nonce", "code_challenge"} {
+		if q.Get(key) == "" {
+			t.Errorf("授权地址缺少 %s: %s", key, authURL)
+		}
+	}
+	if got := q.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	if got := q.Get("scope"); got != "openid profile email" {
+		t.Errorf("scope = %q", got)
+	}
+
+	// 每次授权使用不同的 state 和 nonce
+	other, _, err := registry.AuthCodeURL(client, 0)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	otherURL, _ := url.Parse(other)
+	if otherURL.Query().Get("state") == q.Get("state") || otherURL.Query().Get("nonce") == q.Get("nonce") {
+		t.Error("state/nonce 重复")
+	}
+}
+
+func TestOIDCTakeAuthRequest(t *testing.T) {
+	registry, client, _ := newTestOIDCRegistry(t)
+
+	authURL, binding, err := registry.AuthCodeURL(client, 7)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	state := u.Query().Get("state")
+
+	if _, ok := registry.TakeAuthRequest("unknown", "mock", binding); ok {
+		t.Error("未知 state 不应通过")
+	}
+	req, ok := registry.TakeAuthRequest(state, "mock", binding)
+	if !ok {
+		t.Fatal("有效 state 未通过")
+	}
+	if req.LinkUserID != 7 || req.Nonce != u.Query().Get("nonce") {
+		t.Errorf("授权请求内容不一致: %+v", req)
+	}
+	if _, ok := registry.TakeAuthRequest(state, "mock", binding); ok {
+		t.Error("state 只能使用一次")
+	}
+
+	// 其他提供方的回调不能使用该 state
+	authURL, binding, _ = registry.AuthCodeURL(client, 0)
+	u, _ = url.Parse(authURL)
+	if _, ok := registry.TakeAuthRequest(u.Query().Get("state"), "other", binding); ok {
+		t.Error("state 不应在其他提供方通过")
+	}
+}
+
+func TestOIDCTakeAuthRequestBinding(t *testing.T) {
+	registry, client, _ := newTestOIDCRegistry(t)
+
+	// 缺少或不匹配发起授权的浏览器Cookie时不通过，且 state 作废
+	for _, binding := range []string{"", "forged-binding"} {
+		authURL, want, err := registry.AuthCodeURL(client, 0)
+		if err != nil {
+			t.Fatalf("AuthCodeURL: %v", err)
+		}
+		u, _ := url.Parse(authURL)
+		state := u.Query().Get("state")
+		if _, ok := registry.TakeAuthRequest(state, "mock", binding); ok {
+			t.Errorf("binding %q 不应通过", binding)
+		}
+		if _, ok := registry.TakeAuthRequest(state, "mock", want); ok {
+			t.Error("校验失败后 state 应作废")
+		}
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	registry, client, provider := newTestOIDCRegistry(t)
+	provider.SetUser(oidctest.Identity{
+		Subject:           "user-1",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+		Name:              "Alice",
+	})
+
+	authURL, binding, err := registry.AuthCodeURL(client, 0)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, state := provider.Authorize(t, authURL)
+	req, ok := registry.TakeAuthRequest(state, "mock", binding)
+	if !ok {
+		t.Fatal("回调 state 无效")
+	}
+
+	identity, err := client.Exchange(context.Background(), code, req)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := OIDCIdentity{
+		Subject:           "user-1",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+		Name:              "Alice",
+		Nonce:             req.Nonce,
+	}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	// 授权码只能兑换一次
+	if _, err := client.Exchange(context.Background(), code, req); err == nil {
+		t.Error("重复兑换授权码应失败")
+	}
+}
+
+func TestOIDCExchangeRejectsWrongVerifier(t *testing.T) {
+	registry, client, provider := newTestOIDCRegistry(t)
+	provider.SetUser(oidctest.Identity{Subject: "user-1"})
+
+	authURL, binding, _ := registry.AuthCodeURL(client, 0)
+	code, state := provider.Authorize(t, authURL)
+	req, ok := registry.TakeAuthRequest(state, "mock", binding)
+	if !ok {
+		t.Fatal("回调 state 无效")
+	}
+	tampered := *req
+	tampered.CodeVerifier = "wrong-verifier-wrong-verifier-wrong-verifier"
+	if _, err := client.Exchange(context.Background(), code, &tampered); err == nil {
+		t.Error("code_verifier 不匹配时应失败")
+	}
+}
+
+func TestOIDCExchangeRejectsWrongNonce(t *testing.T) {
+	registry, client, provider := newTestOIDCRegistry(t)
+	provider.SetUser(oidctest.Identity{Subject: "user-1"})
+
+	authURL, binding, _ := registry.AuthCodeURL(client, 0)
+	code, state := provider.Authorize(t, authURL)
+	req, ok := registry.TakeAuthRequest(state, "mock", binding)
+	if !ok {
+		t.Fatal("回调 state 无效")
+	}
+	tampered := *req
+	tampered.Nonce = "other-nonce"
+	if _, err := client.Exchange(context.Background(), code, &tampered); err == nil {
+		t.Error("nonce 不匹配时应失败")
+	}
+}
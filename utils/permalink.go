@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"go-blog-system/models"
+	"strconv"
+	"strings"
+)
+
+// 固定链接支持的占位符
+var permalinkPlaceholders = map[string]bool{
+	":year":  true,
+	":month": true,
+	":day":   true,
+	":slug":  true,
+	":id":    true,
+}
+
+// ValidatePermalinkPattern 校验固定链接格式，如 /:year/:month/:slug
+// 必须以 / 开头，且包含 :slug 或 :id 以唯一定位文章
+func ValidatePermalinkPattern(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return errors.New("固定链接格式必须以 / 开头")
+	}
+	identified := false
+	for _, seg := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if seg == "" {
+			return errors.New("固定链接格式不能包含空路径段")
+		}
+		if strings.HasPrefix(seg, ":") {
+			if !permalinkPlaceholders[seg] {
+				return fmt.Errorf("不支持的占位符: %s", seg)
+			}
+			if seg == ":slug" || seg == ":id" {
+				identified = true
+			}
+		}
+	}
+	if !identified {
+		return errors.New("固定链接格式必须包含 :slug 或 :id")
+	}
+	return nil
+}
+
+// BuildPermalink 按格式生成文章访问路径，日期取文章创建时间
+func BuildPermalink(pattern string, post *models.Post) string {
+	segs := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i, seg := range segs {
+		switch seg {
+		case ":year":
+			segs[i] = fmt.Sprintf("%04d", post.CreatedAt.Year())
+		case ":month":
+			segs[i] = fmt.Sprintf("%02d", int(post.CreatedAt.Month()))
+		case ":day":
+			segs[i] = fmt.Sprintf("%02d", post.CreatedAt.Day())
+		case ":slug":
+			segs[i] = post.Slug
+		case ":id":
+			segs[i] = strconv.FormatUint(uint64(post.ID), 10)
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+// MatchPermalink 将访问路径与格式逐段匹配，返回占位符对应的值
+func MatchPermalink(pattern, path string) (map[string]string, bool) {
+	patternSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegs) != len(pathSegs) {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(patternSegs))
+	for i, seg := range patternSegs {
+		if strings.HasPrefix(seg, ":") {
+			if pathSegs[i] == "" {
+				return nil, false
+			}
+			values[seg[1:]] = pathSegs[i]
+			continue
+		}
+		if seg != pathSegs[i] {
+			return nil, false
+		}
+	}
+	return values, true
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadOrCreateSecret 读取密钥文件，文件不存在时生成随机密钥并保存（仅所有者可读写），
+// 保证未显式配置密钥的部署在重启后仍使用同一密钥
+func LoadOrCreateSecret(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err == nil {
+		secret := strings.TrimSpace(string(data))
+		if len(secret) < 16 {
+			return "", errors.New("密钥文件内容过短: " + name)
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return "", err
+	}
+	secret, err := RandomToken()
+	if err != nil {
+		return "", err
+	}
+	// 先写入临时文件再以硬链接发布，并发启动的进程不会读到未写完的文件，也不会互相覆盖
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".secret-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(secret + "\n"); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Link(tmp.Name(), name); err != nil {
+		if os.IsExist(err) {
+			return LoadOrCreateSecret(name)
+		}
+		return "", err
+	}
+	return secret, nil
+}
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go-blog-system/config"
+	"go-blog-system/models"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// SitemapMaxURLs 单个sitemap文件的URL数量上限（sitemaps.org协议规定）
+const SitemapMaxURLs = 50000
+
+// SitemapURL sitemap中的一条地址
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []SitemapURL `xml:"sitemap"`
+}
+
+const sitemapXmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// Sitemap 生成好的sitemap：URL总数不超过上限时Index为单个urlset，否则为索引文件，分页内容在Pages中
+type Sitemap struct {
+	Index   []byte
+	Pages   [][]byte
+	Updated time.Time
+}
+
+// SitemapCache 缓存生成的sitemap，文章/作者/标签有变化时重新生成
+type SitemapCache struct {
+	cfg         *config.AppConfig
+	mu          sync.Mutex
+	fingerprint string
+	sitemap     *Sitemap
+}
+
+// NewSitemapCache 创建sitemap缓存
+func NewSitemapCache(cfg *config.AppConfig) *SitemapCache {
+	return &SitemapCache{cfg: cfg}
+}
+
+// Get 返回当前sitemap，数据有变化时重新生成
+func (s *SitemapCache) Get() (*Sitemap, error) {
+	fingerprint, err := sitemapFingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sitemap != nil && s.fingerprint == fingerprint {
+		return s.sitemap, nil
+	}
+	sitemap, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	s.sitemap, s.fingerprint = sitemap, fingerprint
+	Log.Infof("sitemap已重新生成: pages: %d", len(sitemap.Pages))
+	return sitemap, nil
+}
+
+// sitemapFingerprint 汇总文章、用户、标签的数量与最近修改时间（含软删除），任一变化即需重新生成
+func sitemapFingerprint() (string, error) {
+	var fingerprint string
+	for _, model := range []interface{}{&models.Post{}, &models.User{}, &models.Tag{}} {
+		var row struct {
+			Count   int64
+			Updated string
+			Deleted string
+		}
+		if err := config.DB.Unscoped().Model(model).
+			Select("COUNT(*) AS count, COALESCE(MAX(updated_at), '') AS updated, COALESCE(MAX(deleted_at), '') AS deleted").
+			Scan(&row).Error; err != nil {
+			return "", err
+		}
+		fingerprint += fmt.Sprintf("%d|%s|%s;", row.Count, row.Updated, row.Deleted)
+	}
+	return fingerprint, nil
+}
+
+// build 收集首页、全部文章、有文章的作者页和标签页，lastmod取相关文章的最近更新时间
+func (s *SitemapCache) build() (*Sitemap, error) {
+	var posts []models.Post
+	if err := config.DB.Select("id", "slug", "user_id", "created_at", "updated_at").Order("id").Find(&posts).Error; err != nil {
+		return nil, err
+	}
+	var users []models.User
+	if err := config.DB.Select("id", "username").Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var tags []models.Tag
+	if err := config.DB.Select("id", "slug").Order("slug").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	var postTags []struct {
+		PostID uint
+		TagID  uint
+	}
+	if err := config.DB.Table("post_tags").Select("post_id", "tag_id").Scan(&postTags).Error; err != nil {
+		return nil, err
+	}
+
+	// 作者页、标签页的lastmod取其下文章的最近更新时间，没有文章的不收录
+	postUpdated := make(map[uint]time.Time, len(posts))
+	authorUpdated := make(map[uint]time.Time)
+	for _, post := range posts {
+		postUpdated[post.ID] = post.UpdatedAt
+		if post.UpdatedAt.After(authorUpdated[post.UserID]) {
+			authorUpdated[post.UserID] = post.UpdatedAt
+		}
+	}
+	tagUpdated := make(map[uint]time.Time)
+	for _, pt := range postTags {
+		if t, ok := postUpdated[pt.PostID]; ok && t.After(tagUpdated[pt.TagID]) {
+			tagUpdated[pt.TagID] = t
+		}
+	}
+
+	site := s.cfg.SiteURL
+	var updated time.Time
+	urls := make([]SitemapURL, 0, len(posts)+len(authorUpdated)+len(tagUpdated)+1)
+	// 未启用站点页面时首页、文章页、作者页和标签页都不存在，输出空的sitemap
+	if s.cfg.SiteEnabled {
+		urls = append(urls, SitemapURL{Loc: site + "/"})
+		for i := range posts {
+			urls = append(urls, SitemapURL{
+				Loc:     site + BuildPermalink(s.cfg.PostPermalink, &posts[i]),
+				LastMod: sitemapTime(posts[i].UpdatedAt),
+			})
+			if posts[i].UpdatedAt.After(updated) {
+				updated = posts[i].UpdatedAt
+			}
+		}
+		for _, user := range users {
+			if t, ok := authorUpdated[user.ID]; ok {
+				urls = append(urls, SitemapURL{Loc: site + "/authors/" + url.PathEscape(user.Username), LastMod: sitemapTime(t)})
+			}
+		}
+		for _, tag := range tags {
+			if t, ok := tagUpdated[tag.ID]; ok {
+				urls = append(urls, SitemapURL{Loc: site + "/tags/" + url.PathEscape(tag.Slug), LastMod: sitemapTime(t)})
+			}
+		}
+		if !updated.IsZero() {
+			urls[0].LastMod = sitemapTime(updated)
+		}
+	}
+
+	sitemap := &Sitemap{Updated: updated}
+	if len(urls) <= SitemapMaxURLs {
+		data, err := marshalSitemap(sitemapURLSet{Xmlns: sitemapXmlns, URLs: urls})
+		if err != nil {
+			return nil, err
+		}
+		sitemap.Index = data
+		return sitemap, nil
+	}
+
+	// 超过上限时拆分为多个文件，/sitemap.xml 作为索引
+	index := sitemapIndex{Xmlns: sitemapXmlns}
+	for start := 0; start < len(urls); start += SitemapMaxURLs {
+		end := start + SitemapMaxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+		data, err := marshalSitemap(sitemapURLSet{Xmlns: sitemapXmlns, URLs: urls[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		sitemap.Pages = append(sitemap.Pages, data)
+		index.Sitemaps = append(index.Sitemaps, SitemapURL{
+			Loc:     fmt.Sprintf("%s/sitemaps/%d.xml", site, len(sitemap.Pages)),
+			LastMod: sitemapTime(updated),
+		})
+	}
+	data, err := marshalSitemap(index)
+	if err != nil {
+		return nil, err
+	}
+	sitemap.Index = data
+	return sitemap, nil
+}
+
+// sitemapTime lastmod使用W3C日期时间格式
+func sitemapTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func marshalSitemap(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// 浏览器识别规则（顺序敏感：Edge/Opera 的UA中也包含 Chrome，Chrome 的UA中也包含 Safari）
+var browserRules = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"Go-http-client", "Go"},
+	{"python-requests", "Python"},
+	{"PostmanRuntime", "Postman"},
+}
+
+// 操作系统识别规则
+var osRules = []struct{ token, name string }{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// DescribeUserAgent 将User-Agent解析为简短的设备描述，如 "Chrome on Windows"
+func DescribeUserAgent(ua string) string {
+	if ua == "" {
+		return "未知设备"
+	}
+
+	browser := ""
+	for _, rule := range browserRules {
+		if strings.Contains(ua, rule.token) {
+			browser = rule.name
+			break
+		}
+	}
+	system := ""
+	for _, rule := range osRules {
+		if strings.Contains(ua, rule.token) {
+			system = rule.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return TruncateUTF8(ua, 50)
+}
+
+// TruncateUTF8 截取字符串的前 n 个字节，不截断多字节字符
+func TruncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// 爬虫、链接预览、监控和命令行工具的UA特征（小写）
+var botTokens = []string{
+	"bot", "crawl", "spider", "slurp", "archiver", "preview", "facebookexternalhit", "embedly",
+	"headless", "lighthouse", "pingdom", "uptime", "monitor", "feed",
+	"curl/", "wget/", "python-", "go-http-client", "java/", "okhttp", "axios/", "node-fetch", "postmanruntime",
+}
+
+// IsBot 是否为爬虫等非读者访问，空UA也视为非读者
+func IsBot(ua string) bool {
+	if ua == "" {
+		return true
+	}
+	ua = strings.ToLower(ua)
+	for _, token := range botTokens {
+		if strings.Contains(ua, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package utils
+
+import "net/url"
+
+// IsHTTPURL 判断字符串是否为合法的 http/https 链接
+func IsHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
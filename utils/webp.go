@@ -0,0 +1,568 @@
+package utils
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+)
+
+// WebP 无损（VP8L）编码器
+// 使用减绿变换 + 分块预测变换 + 左侧/上方像素的回溯引用 + 哈夫曼编码，
+// 压缩率不及 libwebp，但纯Go实现、无需cgo。对图表、截图等图片通常小于PNG，照片则往往大于JPEG。
+
+// VP8L 哈夫曼码表大小
+const (
+	vp8lNumLiteralCodes  = 256
+	vp8lNumLengthCodes   = 24
+	vp8lNumDistanceCodes = 40
+	vp8lMaxCodeLength    = 15
+	vp8lMaxCopyLength    = 4096
+	vp8lMinCopyLength    = 3
+	vp8lMaxDimension     = 1 << 14
+)
+
+// 码长编码的写入顺序（规范规定）
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// vp8lBitWriter 低位优先的位写入器
+type vp8lBitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+func (w *vp8lBitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nacc
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+func (w *vp8lBitWriter) bytes() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+	return w.buf
+}
+
+// huffmanCode 规范哈夫曼码，codes 已按低位优先反转
+type huffmanCode struct {
+	lengths []uint8
+	codes   []uint16
+	single  bool // 仅一个符号时不占用任何位
+}
+
+func (h *huffmanCode) write(w *vp8lBitWriter, symbol int) {
+	if h.single {
+		return
+	}
+	w.writeBits(uint32(h.codes[symbol]), uint(h.lengths[symbol]))
+}
+
+// huffNode 构建哈夫曼树的节点
+type huffNode struct {
+	freq        uint32
+	symbol      int // 叶子节点的符号，内部节点为-1
+	left, right *huffNode
+}
+
+type huffHeap []*huffNode
+
+func (h huffHeap) Len() int { return len(h) }
+func (h huffHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].symbol < h[j].symbol
+}
+func (h huffHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *huffHeap) Push(x interface{}) { *h = append(*h, x.(*huffNode)) }
+func (h *huffHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// huffmanLengths 根据频次计算码长，超过maxLen时压缩频次后重算
+func huffmanLengths(hist []uint32, maxLen int) []uint8 {
+	freq := append([]uint32(nil), hist...)
+	for {
+		lengths := make([]uint8, len(freq))
+		h := &huffHeap{}
+		for s, f := range freq {
+			if f > 0 {
+				*h = append(*h, &huffNode{freq: f, symbol: s})
+			}
+		}
+		if h.Len() == 0 {
+			return lengths
+		}
+		if h.Len() == 1 {
+			lengths[(*h)[0].symbol] = 1
+			return lengths
+		}
+		heap.Init(h)
+		for h.Len() > 1 {
+			a := heap.Pop(h).(*huffNode)
+			b := heap.Pop(h).(*huffNode)
+			heap.Push(h, &huffNode{freq: a.freq + b.freq, symbol: -1, left: a, right: b})
+		}
+
+		tooLong := false
+		var walk func(n *huffNode, depth int)
+		walk = func(n *huffNode, depth int) {
+			if n.symbol >= 0 {
+				if depth > maxLen {
+					tooLong = true
+				}
+				lengths[n.symbol] = uint8(depth)
+				return
+			}
+			walk(n.left, depth+1)
+			walk(n.right, depth+1)
+		}
+		walk((*h)[0], 0)
+		if !tooLong {
+			return lengths
+		}
+		for s, f := range freq {
+			if f > 0 {
+				freq[s] = f>>1 | 1
+			}
+		}
+	}
+}
+
+// newHuffmanCode 由码长生成规范哈夫曼码
+func newHuffmanCode(lengths []uint8) *huffmanCode {
+	h := &huffmanCode{lengths: lengths, codes: make([]uint16, len(lengths))}
+	var count [vp8lMaxCodeLength + 1]int
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+			used++
+		}
+	}
+	h.single = used <= 1
+
+	var next [vp8lMaxCodeLength + 2]int
+	code := 0
+	for l := 1; l <= vp8lMaxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		// 反转为低位优先
+		var rev uint16
+		for i := uint8(0); i < l; i++ {
+			rev = rev<<1 | uint16(c>>i&1)
+		}
+		h.codes[s] = rev
+	}
+	return h
+}
+
+// writeHuffmanCode 写入码表并返回可用于编码的哈夫曼码
+func writeHuffmanCode(w *vp8lBitWriter, hist []uint32) *huffmanCode {
+	var symbols []int
+	for s, f := range hist {
+		if f > 0 {
+			symbols = append(symbols, s)
+		}
+	}
+
+	// 不超过2个符号且均小于256时使用简单码
+	if len(symbols) <= 2 && (len(symbols) == 0 || symbols[len(symbols)-1] < 256) {
+		if len(symbols) == 0 {
+			symbols = []int{0}
+		}
+		lengths := make([]uint8, len(hist))
+		w.writeBits(1, 1)
+		w.writeBits(uint32(len(symbols)-1), 1)
+		if symbols[0] < 2 {
+			w.writeBits(0, 1)
+			w.writeBits(uint32(symbols[0]), 1)
+		} else {
+			w.writeBits(1, 1)
+			w.writeBits(uint32(symbols[0]), 8)
+		}
+		lengths[symbols[0]] = 1
+		if len(symbols) == 2 {
+			w.writeBits(uint32(symbols[1]), 8)
+			lengths[symbols[1]] = 1
+		}
+		return newHuffmanCode(lengths)
+	}
+
+	lengths := huffmanLengths(hist, vp8lMaxCodeLength)
+	code := newHuffmanCode(lengths)
+
+	// 码长本身再用一层哈夫曼码（最长7位）编码，不使用重复码16/17/18
+	clHist := make([]uint32, 19)
+	for _, l := range lengths {
+		clHist[l]++
+	}
+	clLengths := huffmanLengths(clHist, 7)
+	clCode := newHuffmanCode(clLengths)
+
+	numCodes := 4
+	for i := len(vp8lCodeLengthOrder) - 1; i >= 4; i-- {
+		if clLengths[vp8lCodeLengthOrder[i]] > 0 {
+			numCodes = i + 1
+			break
+		}
+	}
+	w.writeBits(0, 1)
+	w.writeBits(uint32(numCodes-4), 4)
+	for i := 0; i < numCodes; i++ {
+		w.writeBits(uint32(clLengths[vp8lCodeLengthOrder[i]]), 3)
+	}
+	w.writeBits(0, 1) // 不使用 max_symbol
+	for _, l := range lengths {
+		clCode.write(w, int(l))
+	}
+	return code
+}
+
+// vp8lPrefix 将长度/距离值编码为前缀码和额外位
+func vp8lPrefix(value int) (prefix int, extraBits uint, extra uint32) {
+	n := value - 1
+	if n < 4 {
+		return n, 0, 0
+	}
+	hb := 0
+	for v := n; v > 1; v >>= 1 {
+		hb++
+	}
+	second := (n >> (hb - 1)) & 1
+	extraBits = uint(hb - 1)
+	extra = uint32(n & (1<<extraBits - 1))
+	return 2*hb + second, extraBits, extra
+}
+
+// vp8lToken 字面像素或回溯引用
+type vp8lToken struct {
+	argb     uint32
+	length   int // >0 表示回溯引用
+	distCode int
+}
+
+// vp8lPredictorBits 预测变换的分块大小（2^4 = 16x16）
+const vp8lPredictorBits = 4
+
+// EncodeWebP 将图片编码为无损WebP
+func EncodeWebP(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return nil, errors.New("WebP 图片尺寸超出范围")
+	}
+
+	// 转为ARGB并应用减绿变换
+	pixels := make([]uint32, width*height)
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			r := c.R - c.G
+			bl := c.B - c.G
+			pixels[y*width+x] = uint32(c.A)<<24 | uint32(r)<<16 | uint32(c.G)<<8 | uint32(bl)
+		}
+	}
+
+	w := &vp8lBitWriter{}
+	w.writeBits(0x2f, 8)
+	w.writeBits(uint32(width-1), 14)
+	w.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+	w.writeBits(0, 3) // version
+
+	// 变换按编码顺序写入，解码时逆序还原
+	w.writeBits(1, 1) // 有变换
+	w.writeBits(2, 2) // SUBTRACT_GREEN
+	w.writeBits(1, 1) // 有变换
+	w.writeBits(0, 2) // PREDICTOR
+	w.writeBits(vp8lPredictorBits-2, 3)
+	residuals, modes, modesWidth := vp8lPredict(pixels, width, height)
+	writeEntropyImage(w, modes, modesWidth, false)
+	w.writeBits(0, 1) // 变换结束
+
+	writeEntropyImage(w, residuals, width, true)
+	return riffWebP(w.bytes()), nil
+}
+
+// writeEntropyImage 写入熵编码图像：主图像额外带元前缀码标志位
+func writeEntropyImage(w *vp8lBitWriter, pixels []uint32, width int, main bool) {
+	// 贪心匹配：与左侧像素或上方像素的最长连续重复
+	tokens := make([]vp8lToken, 0, len(pixels)/2)
+	for i := 0; i < len(pixels); {
+		best, bestCode := 0, 0
+		if i >= 1 {
+			if n := matchLength(pixels, i, 1); n > best {
+				best, bestCode = n, 2 // 距离码2：左侧像素
+			}
+		}
+		if i >= width {
+			if n := matchLength(pixels, i, width); n > best {
+				best, bestCode = n, 1 // 距离码1：上方像素
+			}
+		}
+		if best >= vp8lMinCopyLength {
+			tokens = append(tokens, vp8lToken{length: best, distCode: bestCode})
+			i += best
+			continue
+		}
+		tokens = append(tokens, vp8lToken{argb: pixels[i]})
+		i++
+	}
+
+	// 统计频次
+	green := make([]uint32, vp8lNumLiteralCodes+vp8lNumLengthCodes)
+	red := make([]uint32, 256)
+	blue := make([]uint32, 256)
+	alpha := make([]uint32, 256)
+	dist := make([]uint32, vp8lNumDistanceCodes)
+	for _, t := range tokens {
+		if t.length > 0 {
+			lp, _, _ := vp8lPrefix(t.length)
+			dp, _, _ := vp8lPrefix(t.distCode)
+			green[vp8lNumLiteralCodes+lp]++
+			dist[dp]++
+			continue
+		}
+		green[t.argb>>8&0xff]++
+		red[t.argb>>16&0xff]++
+		blue[t.argb&0xff]++
+		alpha[t.argb>>24]++
+	}
+
+	w.writeBits(0, 1) // 不使用颜色缓存
+	if main {
+		w.writeBits(0, 1) // 不使用元前缀码
+	}
+	greenCode := writeHuffmanCode(w, green)
+	redCode := writeHuffmanCode(w, red)
+	blueCode := writeHuffmanCode(w, blue)
+	alphaCode := writeHuffmanCode(w, alpha)
+	distCode := writeHuffmanCode(w, dist)
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			lp, lbits, lextra := vp8lPrefix(t.length)
+			greenCode.write(w, vp8lNumLiteralCodes+lp)
+			w.writeBits(lextra, lbits)
+			dp, dbits, dextra := vp8lPrefix(t.distCode)
+			distCode.write(w, dp)
+			w.writeBits(dextra, dbits)
+			continue
+		}
+		greenCode.write(w, int(t.argb>>8&0xff))
+		redCode.write(w, int(t.argb>>16&0xff))
+		blueCode.write(w, int(t.argb&0xff))
+		alphaCode.write(w, int(t.argb>>24))
+	}
+}
+
+// matchLength 计算从i开始与距离dist之前的像素连续相同的长度
+func matchLength(pixels []uint32, i, dist int) int {
+	n := 0
+	for i+n < len(pixels) && n < vp8lMaxCopyLength && pixels[i+n] == pixels[i+n-dist] {
+		n++
+	}
+	return n
+}
+
+// vp8lPredict 逐块选择残差最小的预测模式，返回残差图像及模式子图像
+func vp8lPredict(pixels []uint32, width, height int) (residuals, modes []uint32, modesWidth int) {
+	blockSize := 1 << vp8lPredictorBits
+	modesWidth = (width + blockSize - 1) >> vp8lPredictorBits
+	modesHeight := (height + blockSize - 1) >> vp8lPredictorBits
+	modes = make([]uint32, modesWidth*modesHeight)
+	residuals = make([]uint32, len(pixels))
+
+	for by := 0; by < modesHeight; by++ {
+		for bx := 0; bx < modesWidth; bx++ {
+			bestMode, bestCost := 0, -1
+			for mode := 0; mode < 14; mode++ {
+				cost := 0
+				for y := by * blockSize; y < height && y < (by+1)*blockSize; y++ {
+					for x := bx * blockSize; x < width && x < (bx+1)*blockSize; x++ {
+						cost += residualCost(vp8lSub(pixels[y*width+x], vp8lPredictPixel(pixels, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+			modes[by*modesWidth+bx] = 0xff000000 | uint32(bestMode)<<8
+			for y := by * blockSize; y < height && y < (by+1)*blockSize; y++ {
+				for x := bx * blockSize; x < width && x < (bx+1)*blockSize; x++ {
+					i := y*width + x
+					residuals[i] = vp8lSub(pixels[i], vp8lPredictPixel(pixels, width, x, y, bestMode))
+				}
+			}
+		}
+	}
+	return residuals, modes, modesWidth
+}
+
+// vp8lPredictPixel 按规范计算预测值：左上角固定为不透明黑，首行取左侧，首列取上方
+func vp8lPredictPixel(pixels []uint32, width, x, y, mode int) uint32 {
+	i := y*width + x
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return pixels[i-1]
+	case x == 0:
+		return pixels[i-width]
+	}
+	// 最右列的右上像素按内存顺序取当前行首像素
+	l, t, tl, tr := pixels[i-1], pixels[i-width], pixels[i-width-1], pixels[i-width+1]
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return vp8lAverage2(vp8lAverage2(l, tr), t)
+	case 6:
+		return vp8lAverage2(l, tl)
+	case 7:
+		return vp8lAverage2(l, t)
+	case 8:
+		return vp8lAverage2(tl, t)
+	case 9:
+		return vp8lAverage2(t, tr)
+	case 10:
+		return vp8lAverage2(vp8lAverage2(l, tl), vp8lAverage2(t, tr))
+	case 11:
+		return vp8lSelect(l, t, tl)
+	case 12:
+		return vp8lClampAddSubtractFull(l, t, tl)
+	default:
+		return vp8lClampAddSubtractHalf(vp8lAverage2(l, t), tl)
+	}
+}
+
+// vp8lChannel 取ARGB中的第shift位起的8位通道
+func vp8lChannel(p uint32, shift uint) int {
+	return int(p >> shift & 0xff)
+}
+
+func vp8lAverage2(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= uint32((vp8lChannel(a, shift)+vp8lChannel(b, shift))/2) << shift
+	}
+	return out
+}
+
+func vp8lSelect(l, t, tl uint32) uint32 {
+	pl, pt := 0, 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		p := vp8lChannel(l, shift) + vp8lChannel(t, shift) - vp8lChannel(tl, shift)
+		pl += absInt(p - vp8lChannel(l, shift))
+		pt += absInt(p - vp8lChannel(t, shift))
+	}
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+func vp8lClampAddSubtractFull(a, b, c uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= uint32(clamp255(vp8lChannel(a, shift)+vp8lChannel(b, shift)-vp8lChannel(c, shift))) << shift
+	}
+	return out
+}
+
+func vp8lClampAddSubtractHalf(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		ca := vp8lChannel(a, shift)
+		out |= uint32(clamp255(ca+(ca-vp8lChannel(b, shift))/2)) << shift
+	}
+	return out
+}
+
+// vp8lSub 逐通道相减（模256）
+func vp8lSub(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= uint32(uint8(vp8lChannel(a, shift)-vp8lChannel(b, shift))) << shift
+	}
+	return out
+}
+
+// residualCost 残差代价：各通道按有符号值取绝对值求和
+func residualCost(p uint32) int {
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		cost += absInt(int(int8(p >> shift)))
+	}
+	return cost
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clamp255(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
+
+// riffWebP 用RIFF容器封装VP8L数据
+func riffWebP(vp8l []byte) []byte {
+	var buf bytes.Buffer
+	chunkSize := len(vp8l)
+	padded := chunkSize + chunkSize&1
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(4+8+padded))
+	buf.WriteString("WEBP")
+	buf.WriteString("VP8L")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(chunkSize))
+	buf.Write(vp8l)
+	if chunkSize&1 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
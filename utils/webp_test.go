@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"os/exec"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testImages 覆盖纯色、渐变、噪点、透明通道和极端尺寸
+func testImages() map[string]image.Image {
+	rng := rand.New(rand.NewSource(1))
+	images := map[string]image.Image{}
+
+	solid := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for i := 0; i < len(solid.Pix); i += 4 {
+		copy(solid.Pix[i:], []byte{200, 40, 90, 255})
+	}
+	images["solid"] = solid
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 97, 61))
+	for y := 0; y < 61; y++ {
+		for x := 0; x < 97; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{uint8(x * 255 / 96), uint8(y * 255 / 60), uint8((x + y) % 256), 255})
+		}
+	}
+	images["gradient"] = gradient
+
+	noise := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	rng.Read(noise.Pix)
+	for i := 3; i < len(noise.Pix); i += 4 {
+		noise.Pix[i] = 255
+	}
+	images["noise"] = noise
+
+	alpha := image.NewNRGBA(image.Rect(0, 0, 33, 17))
+	rng.Read(alpha.Pix)
+	for i := 3; i < len(alpha.Pix); i += 4 {
+		if alpha.Pix[i] == 0 {
+			alpha.Pix[i] = 1 // 完全透明像素的颜色值没有意义，避免比较
+		}
+	}
+	images["alpha"] = alpha
+
+	images["1x1"] = solid.SubImage(image.Rect(3, 3, 4, 4))
+	images["wide"] = gradient.SubImage(image.Rect(0, 10, 97, 11))
+	images["tall"] = gradient.SubImage(image.Rect(50, 0, 51, 61))
+
+	gray := image.NewGray(image.Rect(0, 0, 20, 20))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 7)
+	}
+	images["gray"] = gray
+	return images
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	for name, src := range testImages() {
+		t.Run(name, func(t *testing.T) {
+			data, err := EncodeWebP(src)
+			if err != nil {
+				t.Fatalf("EncodeWebP: %v", err)
+			}
+			decoded, err := webp.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+			sb, db := src.Bounds(), decoded.Bounds()
+			if sb.Dx() != db.Dx() || sb.Dy() != db.Dy() {
+				t.Fatalf("尺寸 %v, want %v", db.Size(), sb.Size())
+			}
+			// 无损编码，逐像素完全一致
+			for y := 0; y < sb.Dy(); y++ {
+				for x := 0; x < sb.Dx(); x++ {
+					want := color.NRGBAModel.Convert(src.At(sb.Min.X+x, sb.Min.Y+y)).(color.NRGBA)
+					got := color.NRGBAModel.Convert(decoded.At(db.Min.X+x, db.Min.Y+y)).(color.NRGBA)
+					if got != want {
+						t.Fatalf("像素 (%d,%d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPRejectsInvalidSize(t *testing.T) {
+	if _, err := EncodeWebP(image.NewNRGBA(image.Rect(0, 0, 0, 10))); err == nil {
+		t.Error("空图片应返回错误")
+	}
+	if _, err := EncodeWebP(image.NewNRGBA(image.Rect(0, 0, vp8lMaxDimension+1, 1))); err == nil {
+		t.Error("超出尺寸上限应返回错误")
+	}
+}
+
+func TestEncodeWebPLossy(t *testing.T) {
+	cwebp, err := exec.LookPath("cwebp")
+	if err != nil {
+		t.Skip("未安装 cwebp")
+	}
+	src := testImages()["gradient"]
+	data, err := EncodeWebPLossy(cwebp, src, 80)
+	if err != nil {
+		t.Fatalf("EncodeWebPLossy: %v", err)
+	}
+	decoded, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	if decoded.Bounds().Size() != src.Bounds().Size() {
+		t.Errorf("尺寸 %v, want %v", decoded.Bounds().Size(), src.Bounds().Size())
+	}
+}